@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"lychee-ai-organizer/internal/api"
 	"lychee-ai-organizer/internal/config"
 	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geocode"
 	"lychee-ai-organizer/internal/images"
 	"lychee-ai-organizer/internal/ollama"
+	"lychee-ai-organizer/internal/suggestions"
+	"lychee-ai-organizer/internal/titles"
 	"lychee-ai-organizer/internal/websocket"
 )
 
@@ -48,21 +53,61 @@ func (app *App) Run() error {
 	defer db.Close()
 	app.db = db
 
+	// Create the organizer's own bookkeeping tables
+	if err := db.EnsureSchema(); err != nil {
+		return err
+	}
+
 	// Initialize image fetcher
 	imageFetcher := images.NewFetcher(&cfg.Lychee)
 
+	// Initialize camera filename matcher for title suggestions
+	titleMatcher, err := titles.NewMatcher(cfg.Titles.CameraFilenamePatterns)
+	if err != nil {
+		return err
+	}
+
+	// Load the cities for offline reverse geocoding
+	geocoder, err := geocode.New(&cfg.Geocoding)
+	if err != nil {
+		return fmt.Errorf("failed to load geocoding data: %w", err)
+	}
+
+	// Load and validate prompt templates
+	prompts, err := ollama.LoadPrompts(&cfg.Prompts, cfg.Ollama.HasRemoteEndpoint())
+	if err != nil {
+		return fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+
 	// Initialize Ollama client
-	ollamaClient, err := ollama.NewClient(&cfg.Ollama, db, imageFetcher)
+	ollamaClient, err := ollama.NewClient(&cfg.Ollama, db, imageFetcher, titleMatcher, geocoder, prompts, &cfg.Validation, &cfg.Albums)
 	if err != nil {
 		return fmt.Errorf("failed to initialize Ollama client: %w", err)
 	}
 	app.ollama = ollamaClient
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Make sure the configured models exist before any job depends on them
+	missingModels, err := ollamaClient.VerifyModels(ctx, cfg.Ollama.AutoPull)
+	if err != nil {
+		return err
+	}
+
+	suggestionService, err := suggestions.NewService(db, ollamaClient, &cfg.Suggestions, &cfg.Clusters, geocoder)
+	if err != nil {
+		return err
+	}
+
 	// Initialize API server
-	app.apiServer = api.NewServer(db, ollamaClient, imageFetcher)
+	app.apiServer = api.NewServer(db, ollamaClient, imageFetcher, suggestionService, &cfg.Suggestions)
 
 	// Initialize WebSocket handler
-	app.wsHandler = websocket.NewHandler(db, ollamaClient)
+	app.wsHandler = websocket.NewHandler(db, ollamaClient, titleMatcher, suggestionService, cfg.Jobs.Concurrency)
+
+	// Let the UI know when jobs pause because Ollama is unreachable
+	ollamaClient.SetStatusHandler(app.wsHandler.BroadcastOllamaStatus)
 
 	// Set up HTTP routes
 	http.HandleFunc("/", app.handleIndex)
@@ -71,8 +116,76 @@ func (app *App) Run() error {
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	log.Printf("Starting server on %s", addr)
-	
-	return http.ListenAndServe(addr, nil)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- http.ListenAndServe(addr, nil)
+	}()
+
+	// Pull missing models with the server already up, so the UI can show download progress
+	if len(missingModels) > 0 {
+		if err := ollamaClient.PullModels(ctx, missingModels, app.wsHandler.BroadcastPullProgress); err != nil {
+			return err
+		}
+	}
+
+	// Start precomputing album suggestions in the background
+	suggestionService.Run(ctx)
+
+	return <-serverErr
+}
+
+// Check prints the Ollama endpoints and the state of the configured models, and returns an
+// error if any of them is missing
+func (app *App) Check() error {
+	cfg, err := config.LoadConfig(app.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ollamaClient, err := ollama.NewClient(&cfg.Ollama, nil, nil, nil, nil, nil, nil, &cfg.Albums)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Ollama client: %w", err)
+	}
+
+	ctx := context.Background()
+	for _, endpoint := range ollamaClient.Endpoints() {
+		serverVersion, err := ollamaClient.ServerVersion(ctx, endpoint)
+		if err != nil {
+			return fmt.Errorf("failed to reach Ollama at %s: %w", endpoint, err)
+		}
+		fmt.Printf("Ollama endpoint %s (version %s)\n", endpoint, serverVersion)
+	}
+
+	statuses, err := ollamaClient.CheckModels(ctx)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, status := range statuses {
+		switch {
+		case !status.Available:
+			fmt.Printf("  %s  %-30s %s: missing\n", status.Endpoint, status.Name, status.Role)
+			missing = append(missing, status.Name+" on "+status.Endpoint)
+		case status.Vision:
+			fmt.Printf("  %s  %-30s %s: ok (vision)\n", status.Endpoint, status.Name, status.Role)
+		default:
+			fmt.Printf("  %s  %-30s %s: ok\n", status.Endpoint, status.Name, status.Role)
+		}
+	}
+
+	for _, status := range statuses {
+		if status.Available && status.Image && !status.Vision {
+			fmt.Printf("Warning: image analysis model %s on %s does not appear to support images\n", status.Name, status.Endpoint)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing model(s): %s; run `ollama pull` for each or set ollama.auto_pull", strings.Join(missing, ", "))
+	}
+
+	return nil
 }
 
 func (app *App) handleIndex(w http.ResponseWriter, r *http.Request) {
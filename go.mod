@@ -1,6 +1,6 @@
 module lychee-ai-organizer
 
-go 1.22.0
+go 1.24.0
 
 toolchain go1.24.4
 
@@ -10,5 +10,12 @@ require (
 	github.com/gorilla/websocket v1.5.3
 	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.22
-	github.com/ollama/ollama v0.1.48
+	github.com/ollama/ollama v0.12.6
+	golang.org/x/image v0.22.0
+)
+
+require (
+	github.com/google/uuid v1.6.0 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
 )
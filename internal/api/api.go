@@ -7,16 +7,20 @@ import (
 	"net/http"
 	"strconv"
 
+	"lychee-ai-organizer/internal/config"
 	"lychee-ai-organizer/internal/database"
 	"lychee-ai-organizer/internal/images"
 	"lychee-ai-organizer/internal/ollama"
+	"lychee-ai-organizer/internal/suggestions"
 )
 
 type Server struct {
-	db           *database.DB
-	ollama       *ollama.Client
-	imageFetcher *images.Fetcher
-	mux          *http.ServeMux
+	db             *database.DB
+	ollama         *ollama.Client
+	imageFetcher   *images.Fetcher
+	suggestions    *suggestions.Service
+	suggestionsCfg *config.SuggestionsConfig
+	mux            *http.ServeMux
 }
 
 type PhotoResponse struct {
@@ -34,8 +38,25 @@ type AlbumResponse struct {
 	Description string `json:"description"`
 }
 
+type SuggestedAlbumResponse struct {
+	AlbumResponse
+	Confidence    float64                `json:"confidence"`
+	Reason        string                 `json:"reason"`
+	Contributions []ContributionResponse `json:"contributions,omitempty"` // only with ?debug=1
+}
+
+// ContributionResponse is one suggester's part in a suggestion's confidence
+type ContributionResponse struct {
+	Suggester    string  `json:"suggester"`
+	Confidence   float64 `json:"confidence"`   // the suggester's own score for the album
+	Weight       float64 `json:"weight"`       // the suggester's share of the ensemble for this photo
+	Contribution float64 `json:"contribution"` // confidence * weight
+}
+
 type SuggestionResponse struct {
-	Albums []AlbumResponse `json:"albums"`
+	Albums        []SuggestedAlbumResponse `json:"albums"`
+	LowConfidence bool                     `json:"low_confidence"`
+	NewAlbum      *AlbumProposalResponse   `json:"new_album,omitempty"`
 }
 
 type MovePhotoRequest struct {
@@ -43,12 +64,14 @@ type MovePhotoRequest struct {
 	AlbumID string `json:"album_id"`
 }
 
-func NewServer(db *database.DB, ollamaClient *ollama.Client, imageFetcher *images.Fetcher) *Server {
+func NewServer(db *database.DB, ollamaClient *ollama.Client, imageFetcher *images.Fetcher, suggestionService *suggestions.Service, suggestionsCfg *config.SuggestionsConfig) *Server {
 	s := &Server{
-		db:           db,
-		ollama:       ollamaClient,
-		imageFetcher: imageFetcher,
-		mux:          http.NewServeMux(),
+		db:             db,
+		ollama:         ollamaClient,
+		imageFetcher:   imageFetcher,
+		suggestions:    suggestionService,
+		suggestionsCfg: suggestionsCfg,
+		mux:            http.NewServeMux(),
 	}
 
 	s.setupRoutes()
@@ -58,9 +81,20 @@ func NewServer(db *database.DB, ollamaClient *ollama.Client, imageFetcher *image
 func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/api/health", s.handleHealth)
 	s.mux.HandleFunc("/api/photos/unsorted", s.handleUnsortedPhotos)
+	s.mux.HandleFunc("/api/photos/unsorted/clusters", s.handleUnsortedClusters)
 	s.mux.HandleFunc("/api/photos/suggestions", s.handlePhotoSuggestions)
 	s.mux.HandleFunc("/api/photos/move", s.handleMovePhoto)
 	s.mux.HandleFunc("/api/rescan", s.handleRescan)
+	s.mux.HandleFunc("/api/prompts/preview", s.handlePromptPreview)
+	s.mux.HandleFunc("/api/albums/contact-sheet", s.handleContactSheet)
+	s.mux.HandleFunc("/api/titles/pending", s.handlePendingTitles)
+	s.mux.HandleFunc("/api/titles/review", s.handleReviewTitle)
+	s.mux.HandleFunc("/api/proposals/review", s.handleReviewProposal)
+	s.mux.HandleFunc("/api/clusters/{id}/move", s.handleMoveCluster)
+	s.mux.HandleFunc("/api/reasoning", s.handleReasoning)
+	s.mux.HandleFunc("/api/validation/failures", s.handleValidationFailures)
+	s.mux.HandleFunc("/api/provenance", s.handleProvenance)
+	s.mux.HandleFunc("/api/provenance/summary", s.handleProvenanceSummary)
 	s.mux.HandleFunc("/", s.handleStatic)
 }
 
@@ -99,6 +133,18 @@ func (s *Server) handleUnsortedPhotos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lang, err := s.requestLanguage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	descs, err := s.loadDescriptions(w, lang)
+	if err != nil {
+		log.Printf("Error getting %s descriptions: %v", lang, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	photoData, err := s.getUnsortedPhotosWithVariants()
 	if err != nil {
 		log.Printf("Error getting unsorted photos with variants: %v", err)
@@ -106,36 +152,53 @@ func (s *Server) handleUnsortedPhotos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var response []PhotoResponse
-	for _, data := range photoData {
-		desc := ""
-		if data.Photo.AIDescription.Valid {
-			desc = data.Photo.AIDescription.String
+	// The low-confidence queue holds photos whose best suggestion is only a guess
+	var lowConfidence map[string]bool
+	if r.URL.Query().Get("queue") == "low_confidence" {
+		version, err := s.suggestions.CurrentVersion()
+		if err == nil {
+			lowConfidence, err = s.db.GetLowConfidencePhotoIDs(s.suggestionsCfg.LowConfidenceThreshold, version)
 		}
-
-		takenAt := "Unknown"
-		if data.Photo.TakenAt.Valid {
-			takenAt = data.Photo.TakenAt.Time.Format("2006-01-02 15:04:05")
+		if err != nil {
+			log.Printf("Error getting low-confidence photos: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
+	}
 
-		// Get URLs from variants
-		thumbnailURL := s.selectBestVariantURL(data.Variants, true)
-		fullSizeURL := s.selectBestVariantURL(data.Variants, false)
+	var response []PhotoResponse
+	for _, data := range photoData {
+		if lowConfidence != nil && !lowConfidence[data.Photo.ID] {
+			continue
+		}
 
-		response = append(response, PhotoResponse{
-			ID:          data.Photo.ID,
-			Title:       data.Photo.Title,
-			TakenAt:     takenAt,
-			Thumbnail:   thumbnailURL,
-			FullSize:    fullSizeURL,
-			Description: desc,
-		})
+		response = append(response, s.newPhotoResponse(data, descs))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+func (s *Server) newPhotoResponse(data PhotoWithVariants, descs *descriptions) PhotoResponse {
+	takenAt := "Unknown"
+	if data.Photo.TakenAt.Valid {
+		takenAt = data.Photo.TakenAt.Time.Format("2006-01-02 15:04:05")
+	}
+
+	// Get URLs from variants
+	thumbnailURL := s.selectBestVariantURL(data.Variants, true)
+	fullSizeURL := s.selectBestVariantURL(data.Variants, false)
+
+	return PhotoResponse{
+		ID:          data.Photo.ID,
+		Title:       data.Photo.Title,
+		TakenAt:     takenAt,
+		Thumbnail:   thumbnailURL,
+		FullSize:    fullSizeURL,
+		Description: descs.photo(&data.Photo),
+	}
+}
+
 func (s *Server) handlePhotoSuggestions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -147,85 +210,154 @@ func (s *Server) handlePhotoSuggestions(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "photo_id parameter required", http.StatusBadRequest)
 		return
 	}
+	debug := r.URL.Query().Get("debug") == "1"
 
-	// Generate suggestions
-	albums, err := s.db.GetTopLevelAlbums()
+	lang, err := s.requestLanguage(r)
 	if err != nil {
-		log.Printf("Error getting albums: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	log.Printf("Found %d top-level albums", len(albums))
-
-	photos, err := s.db.GetUnsortedPhotos()
+	descs, err := s.loadDescriptions(w, lang)
 	if err != nil {
-		log.Printf("Error getting photos: %v", err)
+		log.Printf("Error getting %s descriptions: %v", lang, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var targetPhoto *database.Photo
-	for _, photo := range photos {
-		if photo.ID == photoID {
-			targetPhoto = &photo
-			break
-		}
-	}
-
-	if targetPhoto == nil {
-		log.Printf("Photo not found in unsorted photos: %s", photoID)
+	result, err := s.suggestions.Get(photoID)
+	if err == sql.ErrNoRows {
 		http.Error(w, "Photo not found", http.StatusNotFound)
 		return
-	}
-
-	log.Printf("Generating suggestions for photo: %s", photoID)
-	suggestions, err := s.ollama.GenerateAlbumSuggestions(targetPhoto, albums)
-	if err != nil {
-		log.Printf("Error generating suggestions: %v", err)
+	} else if err == suggestions.ErrUnavailable {
+		http.Error(w, "Ollama is unavailable", http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		log.Printf("Error getting suggestions for photo %s: %v", photoID, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	log.Printf("Generated %d suggestions: %v", len(suggestions), suggestions)
 
-	albumMap := make(map[string]database.Album)
-	for _, album := range albums {
-		albumMap[album.ID] = album
-	}
+	// Get the next photos ready while the user looks at this one
+	go s.suggestions.PrefetchAfter(photoID)
+
+	suggestions := result.Suggestions
+	albumMap := result.Albums
+	log.Printf("Serving %d suggestions for photo %s (precomputed: %t)", len(suggestions), photoID, result.Cached)
+
+	response := SuggestionResponse{Albums: []SuggestedAlbumResponse{}}
 
-	var response SuggestionResponse
-	
 	if len(suggestions) == 0 {
 		log.Printf("No suggestions generated for photo %s", photoID)
-		// Return empty response but not null
-		response.Albums = []AlbumResponse{}
 	} else {
-		for _, albumID := range suggestions {
-			if album, exists := albumMap[albumID]; exists {
-				desc := ""
-				if album.AIDescription.Valid {
-					desc = album.AIDescription.String
+		for _, suggestion := range suggestions {
+			if album, exists := albumMap[suggestion.AlbumID]; exists {
+				suggested := SuggestedAlbumResponse{
+					AlbumResponse: AlbumResponse{
+						ID:          album.ID,
+						Name:        album.Title,
+						Description: descs.album(&album),
+					},
+					Confidence: suggestion.Confidence,
+					Reason:     suggestion.Reason,
 				}
-
-				response.Albums = append(response.Albums, AlbumResponse{
-					ID:          album.ID,
-					Name:        album.Title,
-					Description: desc,
-				})
-				log.Printf("Added album suggestion: %s", album.ID)
+				if debug {
+					for _, c := range suggestion.Contributions {
+						suggested.Contributions = append(suggested.Contributions, ContributionResponse{
+							Suggester:    c.Suggester,
+							Confidence:   c.Confidence,
+							Weight:       c.Weight,
+							Contribution: c.Confidence * c.Weight,
+						})
+					}
+				}
+				response.Albums = append(response.Albums, suggested)
+				log.Printf("Added album suggestion: %s (confidence %.2f)", album.ID, suggestion.Confidence)
 			} else {
-				log.Printf("Album not found in map: %s", albumID)
+				log.Printf("Album not found in map: %s", suggestion.AlbumID)
 			}
 			if len(response.Albums) >= 3 {
 				break
 			}
 		}
 	}
-	
+
+	response.LowConfidence = len(response.Albums) == 0 || response.Albums[0].Confidence < s.suggestionsCfg.LowConfidenceThreshold
+	if result.Proposal != nil {
+		response.NewAlbum = newAlbumProposalResponse(result.Proposal)
+	}
+
 	log.Printf("Returning %d album suggestions", len(response.Albums))
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+func (s *Server) handlePromptPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	photoID := r.URL.Query().Get("photo_id")
+	albumID := r.URL.Query().Get("album_id")
+
+	var previews []ollama.PromptPreview
+	switch {
+	case photoID != "":
+		photo, err := s.db.GetPhoto(photoID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Photo not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error getting photo %s: %v", photoID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		albums, err := s.db.GetTopLevelAlbums()
+		if err != nil {
+			log.Printf("Error getting albums: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		previews = s.ollama.PreviewPhotoPrompts(photo, albums)
+	case albumID != "":
+		albums, err := s.db.GetTopLevelAlbums()
+		if err != nil {
+			log.Printf("Error getting albums: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		var album *database.Album
+		for i := range albums {
+			if albums[i].ID == albumID {
+				album = &albums[i]
+				break
+			}
+		}
+		if album == nil {
+			http.Error(w, "Album not found", http.StatusNotFound)
+			return
+		}
+
+		content, err := s.ollama.AlbumContent(albumID)
+		if err != nil {
+			log.Printf("Error getting photos for album %s: %v", albumID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		previews = s.ollama.PreviewAlbumPrompts(album, content)
+	default:
+		http.Error(w, "photo_id or album_id parameter required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"prompts": previews})
+}
+
 func (s *Server) handleMovePhoto(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -249,6 +381,9 @@ func (s *Server) handleMovePhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The photo is sorted now, so its suggestions are no longer needed
+	s.suggestions.Moved([]string{req.PhotoID}, req.AlbumID)
+
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/suggestions"
+)
+
+// ClusterResponse is an event of unsorted photos that can be sorted as a whole
+type ClusterResponse struct {
+	ID             string                   `json:"id"`
+	Start          string                   `json:"start"`
+	End            string                   `json:"end"`
+	PhotoCount     int                      `json:"photo_count"`
+	Representative PhotoResponse            `json:"representative"`
+	Photos         []PhotoResponse          `json:"photos"`
+	Albums         []SuggestedAlbumResponse `json:"albums"`
+	Suggested      int                      `json:"suggested"` // photos the album suggestions are based on
+	NewAlbum       *AlbumProposalResponse   `json:"new_album,omitempty"`
+}
+
+type MoveClusterRequest struct {
+	AlbumID         string   `json:"album_id"`
+	ExcludePhotoIDs []string `json:"exclude_photo_ids,omitempty"`
+}
+
+func (s *Server) handleUnsortedClusters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	lang, err := s.requestLanguage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	descs, err := s.loadDescriptions(w, lang)
+	if err != nil {
+		log.Printf("Error getting %s descriptions: %v", lang, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	results, albumMap, err := s.suggestions.Clusters()
+	if err != nil {
+		log.Printf("Error clustering unsorted photos: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	photoData, err := s.getUnsortedPhotosWithVariants()
+	if err != nil {
+		log.Printf("Error getting unsorted photos with variants: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	variants := make(map[string][]database.SizeVariant, len(photoData))
+	for _, data := range photoData {
+		variants[data.Photo.ID] = data.Variants
+	}
+
+	response := []ClusterResponse{}
+	for _, result := range results {
+		c := result.Cluster
+		cluster := ClusterResponse{
+			ID:         c.ID,
+			Start:      c.Start.Format("2006-01-02 15:04:05"),
+			End:        c.End.Format("2006-01-02 15:04:05"),
+			PhotoCount: len(c.Photos),
+			Albums:     []SuggestedAlbumResponse{},
+			Suggested:  result.Suggested,
+		}
+		for _, photo := range c.Photos {
+			cluster.Photos = append(cluster.Photos, s.newPhotoResponse(PhotoWithVariants{Photo: photo, Variants: variants[photo.ID]}, descs))
+		}
+		representative := c.Representative()
+		cluster.Representative = s.newPhotoResponse(PhotoWithVariants{Photo: *representative, Variants: variants[representative.ID]}, descs)
+
+		for _, suggestion := range result.Suggestions {
+			album, exists := albumMap[suggestion.AlbumID]
+			if !exists {
+				continue
+			}
+			cluster.Albums = append(cluster.Albums, SuggestedAlbumResponse{
+				AlbumResponse: AlbumResponse{
+					ID:          album.ID,
+					Name:        album.Title,
+					Description: descs.album(&album),
+				},
+				Confidence: suggestion.Confidence,
+				Reason:     suggestion.Reason,
+			})
+		}
+		if result.Proposal != nil {
+			cluster.NewAlbum = newAlbumProposalResponse(result.Proposal)
+		}
+
+		response = append(response, cluster)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handleMoveCluster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clusterID := r.PathValue("id")
+
+	var req MoveClusterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.AlbumID == "" {
+		http.Error(w, "album_id is required", http.StatusBadRequest)
+		return
+	}
+
+	moved, err := s.suggestions.MoveCluster(clusterID, req.AlbumID, req.ExcludePhotoIDs)
+	if err == suggestions.ErrClusterNotFound {
+		http.Error(w, "Cluster not found", http.StatusNotFound)
+		return
+	} else if err == suggestions.ErrNothingToMove {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error moving cluster %s: %v", clusterID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "moved_photo_ids": moved})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// handleContactSheet returns the contact sheet the vision model would see for an album, as JPEG.
+// The photos on it are listed in the X-Photo-Ids header, row by row.
+func (s *Server) handleContactSheet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	albumID := r.URL.Query().Get("album_id")
+	if albumID == "" {
+		http.Error(w, "album_id parameter required", http.StatusBadRequest)
+		return
+	}
+
+	content, err := s.ollama.AlbumContent(albumID)
+	if err != nil {
+		log.Printf("Error getting photos for album %s: %v", albumID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(content.Photos) == 0 {
+		http.Error(w, "Album not found or empty", http.StatusNotFound)
+		return
+	}
+
+	sheet, err := s.ollama.BuildContactSheet(content.Photos)
+	if err != nil {
+		log.Printf("Error building contact sheet for album %s: %v", albumID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("X-Photo-Ids", strings.Join(sheet.PhotoIDs, ","))
+	_, _ = w.Write(sheet.Image)
+}
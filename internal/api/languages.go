@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+// descriptions holds the AI descriptions of photos and albums in the language of a response.
+// Items without a translation keep the description in the primary language.
+type descriptions struct {
+	language string
+	photos   map[string]string // translations by photo ID; nil for the primary language
+	albums   map[string]string // translations by album ID; nil for the primary language
+}
+
+// requestLanguage picks the language of the descriptions in a response: the lang query parameter,
+// else the best match for the Accept-Language header, else the primary language
+func (s *Server) requestLanguage(r *http.Request) (string, error) {
+	languages := s.ollama.Languages()
+
+	if lang := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("lang"))); lang != "" {
+		if !containsLanguage(languages, lang) {
+			return "", fmt.Errorf("unsupported language %q; use one of %s", lang, strings.Join(languages, ", "))
+		}
+		return lang, nil
+	}
+
+	if lang := matchAcceptLanguage(r.Header.Get("Accept-Language"), languages); lang != "" {
+		return lang, nil
+	}
+	return languages[0], nil
+}
+
+// loadDescriptions loads the translations into lang and declares the language of the response
+func (s *Server) loadDescriptions(w http.ResponseWriter, lang string) (*descriptions, error) {
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+
+	d := &descriptions{language: lang}
+	if lang == s.ollama.Languages()[0] {
+		return d, nil
+	}
+
+	var err error
+	if d.photos, err = s.db.GetTranslations(database.TranslationPhoto, lang); err != nil {
+		return nil, err
+	}
+	if d.albums, err = s.db.GetTranslations(database.TranslationAlbum, lang); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// photo returns a photo's description in the response language
+func (d *descriptions) photo(photo *database.Photo) string {
+	if translation, ok := d.photos[photo.ID]; ok {
+		return translation
+	}
+	if photo.AIDescription.Valid {
+		return photo.AIDescription.String
+	}
+	return ""
+}
+
+// album returns an album's description in the response language
+func (d *descriptions) album(album *database.Album) string {
+	if translation, ok := d.albums[album.ID]; ok {
+		return translation
+	}
+	if album.AIDescription.Valid {
+		return album.AIDescription.String
+	}
+	return ""
+}
+
+// matchAcceptLanguage returns the language in languages that an Accept-Language header prefers
+// most, or "" if it accepts none of them. A tag like de-AT matches de unless de-at is configured
+// itself; * matches the primary language.
+func matchAcceptLanguage(header string, languages []string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+
+		lang := ""
+		switch base, _, _ := strings.Cut(tag, "-"); {
+		case tag == "*":
+			lang = languages[0]
+		case containsLanguage(languages, tag):
+			lang = tag
+		case containsLanguage(languages, base):
+			lang = base
+		}
+		// Ties go to the earlier entry, as listed by the client
+		if lang != "" && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+func containsLanguage(languages []string, lang string) bool {
+	for _, l := range languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
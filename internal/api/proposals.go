@@ -0,0 +1,111 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/suggestions"
+)
+
+// AlbumProposalResponse is a proposed new album for the event a photo belongs to
+type AlbumProposalResponse struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	PhotoIDs    []string `json:"photo_ids"`
+}
+
+type ReviewProposalRequest struct {
+	ProposalID      string   `json:"proposal_id"`
+	Action          string   `json:"action"` // "approve" or "reject"
+	Title           string   `json:"title,omitempty"`
+	Description     *string  `json:"description,omitempty"`
+	ExcludePhotoIDs []string `json:"exclude_photo_ids,omitempty"`
+}
+
+func newAlbumProposalResponse(p *database.AlbumProposal) *AlbumProposalResponse {
+	return &AlbumProposalResponse{
+		ID:          p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		PhotoIDs:    p.PhotoIDs,
+	}
+}
+
+func (s *Server) handleReviewProposal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ReviewProposalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProposalID == "" {
+		http.Error(w, "proposal_id is required", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Action {
+	case "approve":
+		proposal, err := s.db.GetAlbumProposal(req.ProposalID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Proposal not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error getting album proposal %s: %v", req.ProposalID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		title := strings.TrimSpace(req.Title)
+		if title == "" {
+			title = proposal.Title
+		}
+		if len([]rune(title)) > 100 {
+			http.Error(w, "title must be between 1 and 100 characters", http.StatusBadRequest)
+			return
+		}
+		description := proposal.Description
+		if req.Description != nil {
+			description = strings.TrimSpace(*req.Description)
+		}
+
+		albumID, err := s.suggestions.ApproveProposal(req.ProposalID, title, description, req.ExcludePhotoIDs)
+		if err != nil {
+			log.Printf("Error approving album proposal %s: %v", req.ProposalID, err)
+			if err == suggestions.ErrProposalReviewed || err == suggestions.ErrNothingToMove {
+				http.Error(w, err.Error(), http.StatusConflict)
+			} else {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(AlbumResponse{ID: albumID, Name: title, Description: description})
+		return
+	case "reject":
+		if err := s.db.RejectAlbumProposal(req.ProposalID); err == suggestions.ErrProposalReviewed {
+			http.Error(w, "No pending proposal "+req.ProposalID, http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Printf("Error rejecting album proposal %s: %v", req.ProposalID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "action must be approve or reject", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
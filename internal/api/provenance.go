@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+// handleProvenance returns how the descriptions and translations of a photo or album were made
+func (s *Server) handleProvenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	itemID := r.URL.Query().Get("item_id")
+	if itemID == "" {
+		http.Error(w, "item_id parameter required", http.StatusBadRequest)
+		return
+	}
+
+	records, err := s.db.GetProvenance(itemID)
+	if err != nil {
+		log.Printf("Error getting provenance for %s: %v", itemID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []database.Provenance{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(records)
+}
+
+// handleProvenanceSummary counts the generated texts by kind, model and prompt, to find what a
+// regenerate_descriptions job would select
+func (s *Server) handleProvenanceSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	counts, err := s.db.GetProvenanceSummary()
+	if err != nil {
+		log.Printf("Error getting provenance summary: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if counts == nil {
+		counts = []database.ProvenanceCount{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(counts)
+}
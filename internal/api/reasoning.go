@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+type ReasoningResponse struct {
+	Kind      string `json:"kind"`
+	ItemID    string `json:"item_id"`
+	Model     string `json:"model"`
+	Reasoning string `json:"reasoning"`
+	CreatedAt string `json:"created_at"`
+}
+
+// handleReasoning returns the stored model reasoning about a photo, album or cluster
+func (s *Server) handleReasoning(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	itemID := r.URL.Query().Get("item_id")
+	if itemID == "" {
+		http.Error(w, "item_id parameter required", http.StatusBadRequest)
+		return
+	}
+
+	reasoning, err := s.db.GetReasoning(itemID, r.URL.Query().Get("kind"))
+	if err != nil {
+		log.Printf("Error getting reasoning for %s: %v", itemID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := []ReasoningResponse{}
+	for _, item := range reasoning {
+		response = append(response, ReasoningResponse{
+			Kind:      item.Kind,
+			ItemID:    item.ItemID,
+			Model:     item.Model,
+			Reasoning: item.Reasoning,
+			CreatedAt: item.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
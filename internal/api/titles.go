@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+type TitleSuggestionResponse struct {
+	PhotoID        string `json:"photo_id"`
+	OriginalTitle  string `json:"original_title"`
+	SuggestedTitle string `json:"suggested_title"`
+	Thumbnail      string `json:"thumbnail"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type ReviewTitleRequest struct {
+	PhotoID string `json:"photo_id"`
+	Action  string `json:"action"` // "approve" or "reject"
+	Title   string `json:"title,omitempty"`
+}
+
+func (s *Server) handlePendingTitles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	suggestions, err := s.db.GetPendingTitleSuggestions()
+	if err != nil {
+		log.Printf("Error getting pending title suggestions: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := []TitleSuggestionResponse{}
+	for _, suggestion := range suggestions {
+		variants, err := s.db.GetPhotoSizeVariants(suggestion.PhotoID)
+		if err != nil {
+			log.Printf("Error getting size variants for photo %s: %v", suggestion.PhotoID, err)
+		}
+
+		response = append(response, TitleSuggestionResponse{
+			PhotoID:        suggestion.PhotoID,
+			OriginalTitle:  suggestion.OriginalTitle,
+			SuggestedTitle: suggestion.SuggestedTitle,
+			Thumbnail:      s.selectBestVariantURL(variants, true),
+			CreatedAt:      suggestion.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handleReviewTitle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ReviewTitleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.PhotoID == "" {
+		http.Error(w, "photo_id is required", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Action {
+	case "approve":
+		title := strings.TrimSpace(req.Title)
+		if title == "" {
+			pending, err := s.db.GetPendingTitleSuggestions()
+			if err != nil {
+				log.Printf("Error getting pending title suggestions: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			title = findSuggestedTitle(pending, req.PhotoID)
+		}
+		if title == "" || len([]rune(title)) > 100 {
+			http.Error(w, "title must be between 1 and 100 characters", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.db.ApproveTitleSuggestion(req.PhotoID, title); err != nil {
+			log.Printf("Error approving title for photo %s: %v", req.PhotoID, err)
+			switch err {
+			case database.ErrNoTitleSuggestion:
+				http.Error(w, "Title suggestion not found", http.StatusNotFound)
+			case database.ErrTitleSuggestionReviewed, database.ErrPhotoRenamed:
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+		log.Printf("Renamed photo %s to %q", req.PhotoID, title)
+	case "reject":
+		if err := s.db.RejectTitleSuggestion(req.PhotoID); err != nil {
+			log.Printf("Error rejecting title for photo %s: %v", req.PhotoID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "action must be approve or reject", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+func findSuggestedTitle(suggestions []database.TitleSuggestion, photoID string) string {
+	for _, suggestion := range suggestions {
+		if suggestion.PhotoID == photoID {
+			return suggestion.SuggestedTitle
+		}
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+type ValidationFailureResponse struct {
+	Kind      string `json:"kind"`
+	ItemID    string `json:"item_id"`
+	Model     string `json:"model"`
+	Reason    string `json:"reason"`
+	Response  string `json:"response"`
+	CreatedAt string `json:"created_at"`
+}
+
+// handleValidationFailures lists the answers that failed validation and couldn't be repaired
+func (s *Server) handleValidationFailures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	failures, err := s.db.GetValidationFailures()
+	if err != nil {
+		log.Printf("Error getting validation failures: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := []ValidationFailureResponse{}
+	for _, f := range failures {
+		response = append(response, ValidationFailureResponse{
+			Kind:      f.Kind,
+			ItemID:    f.ItemID,
+			Model:     f.Model,
+			Reason:    f.Reason,
+			Response:  f.Response,
+			CreatedAt: f.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,193 @@
+package clusters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geo"
+	"lychee-ai-organizer/internal/words"
+)
+
+const (
+	// mergeGapFactor is how many times the gap two neighbouring clusters may be apart and still
+	// be merged if their photos look alike
+	mergeGapFactor = 4
+	// minSimilarity is the share of description words two clusters must have in common to be merged
+	minSimilarity = 0.2
+	// samePlaceKm is how close the centres of two neighbouring clusters must be for them to be
+	// merged as one stay at the same place
+	samePlaceKm = 1.0
+)
+
+// Options control where events are split
+type Options struct {
+	// Gap is the time without photos that separates two events
+	Gap time.Duration
+	// SplitDistanceKm is the distance between consecutive photos that separates two events;
+	// 0 ignores locations
+	SplitDistanceKm float64
+}
+
+// Cluster is a group of unsorted photos that appear to come from the same event
+type Cluster struct {
+	ID     string // derived from the member photos, so it changes when they do
+	Photos []database.Photo
+	Start  time.Time
+	End    time.Time
+}
+
+// PhotoIDs returns the IDs of the cluster's photos in capture order
+func (c *Cluster) PhotoIDs() []string {
+	ids := make([]string, len(c.Photos))
+	for i, photo := range c.Photos {
+		ids[i] = photo.ID
+	}
+	return ids
+}
+
+// Representative returns the photo in the middle of the event
+func (c *Cluster) Representative() *database.Photo {
+	return &c.Photos[len(c.Photos)/2]
+}
+
+// Build groups photos into events. Photos are ordered by capture time and split wherever more
+// than opts.Gap passes between two of them, or wherever a photo was taken more than
+// opts.SplitDistanceKm from the previous photo with coordinates. Neighbouring groups up to
+// mergeGapFactor gaps apart are joined again when their AI descriptions share enough words or
+// they were taken at the same place, unless their locations are too far apart. Photos without
+// a capture time are left out.
+func Build(photos []database.Photo, opts Options) []Cluster {
+	var dated []database.Photo
+	for _, photo := range photos {
+		if photo.TakenAt.Valid {
+			dated = append(dated, photo)
+		}
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dated[i].TakenAt.Time.Before(dated[j].TakenAt.Time)
+	})
+
+	var groups [][]database.Photo
+	var lastLocated *database.Photo // the previous photo with coordinates in the current group
+	for i := range dated {
+		photo := &dated[i]
+		split := i == 0 || photo.TakenAt.Time.Sub(dated[i-1].TakenAt.Time) > opts.Gap
+		if !split && lastLocated != nil && hasLocation(photo) && opts.SplitDistanceKm > 0 {
+			split = distance(lastLocated, photo) > opts.SplitDistanceKm
+		}
+		if split {
+			groups = append(groups, nil)
+			lastLocated = nil
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], *photo)
+		if hasLocation(photo) {
+			lastLocated = photo
+		}
+	}
+
+	var merged [][]database.Photo
+	for _, group := range groups {
+		if n := len(merged); n > 0 {
+			prev := merged[n-1]
+			apart := group[0].TakenAt.Time.Sub(prev[len(prev)-1].TakenAt.Time)
+			if apart <= opts.Gap*mergeGapFactor && belongTogether(prev, group, opts) {
+				merged[n-1] = append(prev, group...)
+				continue
+			}
+		}
+		merged = append(merged, group)
+	}
+
+	clusters := make([]Cluster, len(merged))
+	for i, group := range merged {
+		clusters[i] = Cluster{
+			ID:     clusterID(group),
+			Photos: group,
+			Start:  group[0].TakenAt.Time,
+			End:    group[len(group)-1].TakenAt.Time,
+		}
+	}
+	return clusters
+}
+
+// Find returns the cluster containing a photo
+func Find(clusters []Cluster, photoID string) (*Cluster, bool) {
+	for i := range clusters {
+		for _, photo := range clusters[i].Photos {
+			if photo.ID == photoID {
+				return &clusters[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// belongTogether decides whether two neighbouring groups are one event
+func belongTogether(a, b []database.Photo, opts Options) bool {
+	latA, lonA, okA := centre(a)
+	latB, lonB, okB := centre(b)
+	if okA && okB && opts.SplitDistanceKm > 0 {
+		d := geo.DistanceKm(latA, lonA, latB, lonB)
+		if d > opts.SplitDistanceKm {
+			return false
+		}
+		if d <= samePlaceKm {
+			return true
+		}
+	}
+	return words.Similarity(descriptionWords(a), descriptionWords(b)) >= minSimilarity
+}
+
+// centre is the mean location of the photos with coordinates; ok is false if there are none
+func centre(photos []database.Photo) (lat, lon float64, ok bool) {
+	n := 0
+	for i := range photos {
+		if hasLocation(&photos[i]) {
+			lat += photos[i].Latitude.Float64
+			lon += photos[i].Longitude.Float64
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0, false
+	}
+	return lat / float64(n), lon / float64(n), true
+}
+
+func hasLocation(photo *database.Photo) bool {
+	return photo.Latitude.Valid && photo.Longitude.Valid
+}
+
+func distance(a, b *database.Photo) float64 {
+	return geo.DistanceKm(a.Latitude.Float64, a.Longitude.Float64, b.Latitude.Float64, b.Longitude.Float64)
+}
+
+// clusterID hashes the member photo IDs
+func clusterID(photos []database.Photo) string {
+	ids := make([]string, len(photos))
+	for i, photo := range photos {
+		ids[i] = photo.ID
+	}
+	sort.Strings(ids)
+
+	h := sha256.New()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// descriptionWords returns the set of longer words in the photos' AI descriptions
+func descriptionWords(photos []database.Photo) words.Set {
+	set := make(words.Set)
+	for _, photo := range photos {
+		if photo.AIDescription.Valid {
+			set.Add(photo.AIDescription.String)
+		}
+	}
+	return set
+}
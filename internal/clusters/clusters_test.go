@@ -0,0 +1,196 @@
+package clusters
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+var day = time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)
+
+// photo builds a photo taken hours after the start of day, optionally at a place and described
+func photo(id string, hours float64, place []float64, description string) database.Photo {
+	p := database.Photo{
+		ID:      id,
+		TakenAt: sql.NullTime{Time: day.Add(time.Duration(hours * float64(time.Hour))), Valid: true},
+	}
+	if place != nil {
+		p.Latitude = sql.NullFloat64{Float64: place[0], Valid: true}
+		p.Longitude = sql.NullFloat64{Float64: place[1], Valid: true}
+	}
+	if description != "" {
+		p.AIDescription = sql.NullString{String: description, Valid: true}
+	}
+	return p
+}
+
+var (
+	lisbon = []float64{38.7223, -9.1393}
+	sintra = []float64{38.8029, -9.3817} // 23 km from Lisbon
+	porto  = []float64{41.1579, -8.6291} // 275 km from Lisbon
+)
+
+// events returns the photo IDs of each cluster
+func events(clusters []Cluster) [][]string {
+	ids := make([][]string, len(clusters))
+	for i := range clusters {
+		ids[i] = clusters[i].PhotoIDs()
+	}
+	return ids
+}
+
+func TestBuild(t *testing.T) {
+	opts := Options{Gap: 6 * time.Hour, SplitDistanceKm: 50}
+
+	tests := []struct {
+		name   string
+		photos []database.Photo
+		opts   Options
+		want   [][]string
+	}{
+		{
+			name: "split at a time gap",
+			photos: []database.Photo{
+				photo("a", 10, nil, "Children playing football in a park"),
+				photo("b", 11, nil, "A birthday cake with candles"),
+				photo("c", 20, nil, "Snowy mountain peaks at sunset"),
+			},
+			want: [][]string{{"a", "b"}, {"c"}},
+		},
+		{
+			name: "ordered by capture time, undated photos left out",
+			photos: []database.Photo{
+				photo("b", 11, nil, ""),
+				{ID: "undated"},
+				photo("a", 10, nil, ""),
+			},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "split at a distance",
+			photos: []database.Photo{
+				photo("a", 10, lisbon, "Tram on a steep street"),
+				photo("b", 11, porto, "Bridge over a river"),
+				photo("c", 12, nil, "Glass of port wine"), // no coordinates: stays with the previous photo
+				photo("d", 13, porto, "Boats on a river"),
+			},
+			want: [][]string{{"a"}, {"b", "c", "d"}},
+		},
+		{
+			name: "distances ignored when turned off",
+			photos: []database.Photo{
+				photo("a", 10, lisbon, ""),
+				photo("b", 11, porto, ""),
+			},
+			opts: Options{Gap: 6 * time.Hour},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "merged across a night when the photos look alike",
+			photos: []database.Photo{
+				photo("a", 10, nil, "Sandy beach with turquoise water and palm trees"),
+				photo("b", 11, nil, "Palm trees along a sandy beach"),
+				photo("c", 26, nil, "Turquoise water at a sandy beach with palm trees"),
+			},
+			want: [][]string{{"a", "b", "c"}},
+		},
+		{
+			name: "not merged when the photos differ",
+			photos: []database.Photo{
+				photo("a", 10, nil, "Sandy beach with turquoise water and palm trees"),
+				photo("c", 26, nil, "Office party with colleagues around a table"),
+			},
+			want: [][]string{{"a"}, {"c"}},
+		},
+		{
+			name: "not merged beyond four gaps",
+			photos: []database.Photo{
+				photo("a", 10, nil, "Sandy beach with turquoise water and palm trees"),
+				photo("c", 35, nil, "Sandy beach with turquoise water and palm trees"),
+			},
+			want: [][]string{{"a"}, {"c"}},
+		},
+		{
+			name: "merged at the same place",
+			photos: []database.Photo{
+				photo("a", 10, lisbon, "Breakfast table"),
+				photo("b", 20, lisbon, "Cat asleep on a sofa"),
+			},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "not merged when far apart, however alike",
+			photos: []database.Photo{
+				photo("a", 10, lisbon, "Sandy beach with turquoise water and palm trees"),
+				photo("b", 20, porto, "Sandy beach with turquoise water and palm trees"),
+			},
+			want: [][]string{{"a"}, {"b"}},
+		},
+		{
+			name: "merged nearby when alike",
+			photos: []database.Photo{
+				photo("a", 10, lisbon, "Palace with colourful towers on a hill"),
+				photo("b", 20, sintra, "Colourful palace towers above the hill forest"),
+			},
+			want: [][]string{{"a", "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.opts
+			if o.Gap == 0 {
+				o = opts
+			}
+			if got := events(Build(tt.photos, o)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterIDs(t *testing.T) {
+	opts := Options{Gap: time.Hour}
+	photos := []database.Photo{photo("a", 10, nil, ""), photo("b", 10.5, nil, ""), photo("c", 20, nil, "")}
+
+	first := Build(photos, opts)
+	reversed := Build([]database.Photo{photos[2], photos[1], photos[0]}, opts)
+	if first[0].ID != reversed[0].ID || first[1].ID != reversed[1].ID {
+		t.Error("cluster IDs depend on the order photos are given in")
+	}
+	if first[0].ID == first[1].ID {
+		t.Error("two clusters share an ID")
+	}
+
+	grown := Build(append(photos, photo("d", 11, nil, "")), opts)
+	if grown[0].ID == first[0].ID {
+		t.Error("a cluster kept its ID after a photo joined it")
+	}
+	if grown[1].ID != first[1].ID {
+		t.Error("an unchanged cluster got a new ID")
+	}
+
+	if c, ok := Find(first, "b"); !ok || c.ID != first[0].ID {
+		t.Errorf("Find(b) = %v, %v", c, ok)
+	}
+	if _, ok := Find(first, "x"); ok {
+		t.Error("Find found a photo that isn't in any cluster")
+	}
+	if got := first[0].Representative().ID; got != "b" {
+		t.Errorf("representative of a, b = %s, want the middle photo b", got)
+	}
+	if first[0].Start != photos[0].TakenAt.Time || first[0].End != photos[1].TakenAt.Time {
+		t.Errorf("cluster spans %v to %v", first[0].Start, first[0].End)
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	if got := Build(nil, Options{Gap: time.Hour}); len(got) != 0 {
+		t.Errorf("Build(nil) = %v", got)
+	}
+	if got := Build([]database.Photo{{ID: "undated"}}, Options{Gap: time.Hour}); len(got) != 0 {
+		t.Errorf("undated photos formed %d clusters", len(got))
+	}
+}
@@ -3,17 +3,28 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	Database DatabaseConfig `json:"database"`
-	Ollama   OllamaConfig   `json:"ollama"`
-	Server   ServerConfig   `json:"server"`
-	Lychee   LycheeConfig   `json:"lychee"`
-	Albums   AlbumsConfig   `json:"albums,omitempty"`
+	Database    DatabaseConfig    `json:"database"`
+	Ollama      OllamaConfig      `json:"ollama"`
+	Server      ServerConfig      `json:"server"`
+	Lychee      LycheeConfig      `json:"lychee"`
+	Albums      AlbumsConfig      `json:"albums,omitempty"`
+	Titles      TitlesConfig      `json:"titles,omitempty"`
+	Prompts     PromptsConfig     `json:"prompts,omitempty"`
+	Suggestions SuggestionsConfig `json:"suggestions,omitempty"`
+	Jobs        JobsConfig        `json:"jobs,omitempty"`
+	Clusters    ClustersConfig    `json:"clusters,omitempty"`
+	Geocoding   GeocodingConfig   `json:"geocoding,omitempty"`
+	Validation  ValidationConfig  `json:"validation,omitempty"`
 }
 
 const (
@@ -39,6 +50,130 @@ type OllamaConfig struct {
 	Temperature               float64           `json:"temperature,omitempty"`
 	TopP                      float64           `json:"top_p,omitempty"`
 	Options                   map[string]interface{} `json:"options,omitempty"`
+	// AutoPull downloads configured models missing from the Ollama server at startup
+	// instead of refusing to start
+	AutoPull bool `json:"auto_pull,omitempty"`
+	// Endpoints lists Ollama servers to spread requests across. If empty, Endpoint is used alone.
+	Endpoints []EndpointConfig `json:"endpoints,omitempty"`
+	Retry     RetryConfig      `json:"retry,omitempty"`
+	// Profiles are named model settings that tasks can use instead of the models above
+	Profiles map[string]ModelProfile `json:"profiles,omitempty"`
+	// Tasks assigns each task a chain of profiles
+	Tasks TasksConfig `json:"tasks,omitempty"`
+	// Think controls reasoning for models that support it, unless a profile sets its own
+	Think Think `json:"think,omitempty"`
+	// KeepReasoning stores the reasoning of every task so it can be reviewed through the API
+	KeepReasoning bool `json:"keep_reasoning,omitempty"`
+}
+
+// ModelProfile is a model together with the settings to request it with
+type ModelProfile struct {
+	Model string `json:"model"`
+	// Options are Ollama model options, overriding the global ones for this profile
+	Options map[string]interface{} `json:"options,omitempty"`
+	// KeepAlive is how long the model stays loaded after a request, as a duration like "10m" or
+	// in seconds; "-1" keeps it loaded and "0" unloads it at once. Empty leaves it to Ollama.
+	KeepAlive string `json:"keep_alive,omitempty"`
+	// TimeoutSeconds bounds a request including its retries; 0 means no limit
+	TimeoutSeconds float64 `json:"timeout_seconds,omitempty"`
+	// Think overrides the global think setting for this profile
+	Think Think `json:"think,omitempty"`
+	// KeepReasoning stores the reasoning of requests made with this profile
+	KeepReasoning bool `json:"keep_reasoning,omitempty"`
+}
+
+// Think asks a reasoning model to think before answering: "true", "false", or an effort level
+// ("low", "medium", "high") for models that support levels. Empty leaves it to the model.
+// JSON booleans are accepted too.
+type Think string
+
+func (t *Think) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*t = Think(strconv.FormatBool(b))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("think must be true, false, \"low\", \"medium\" or \"high\"")
+	}
+	*t = Think(strings.ToLower(strings.TrimSpace(s)))
+	return nil
+}
+
+// Valid reports whether the setting is one Ollama understands
+func (t Think) Valid() bool {
+	switch t {
+	case "", "true", "false", "low", "medium", "high":
+		return true
+	}
+	return false
+}
+
+// TasksConfig assigns each task an ordered chain of profile names. The first profile is used,
+// and the next one is tried whenever a profile fails or returns unusable output. An empty chain
+// uses the image analysis or description synthesis model with the global options.
+type TasksConfig struct {
+	PhotoDescription []string `json:"photo_description,omitempty"` // also used for photo titles
+	AlbumSynthesis   []string `json:"album_synthesis,omitempty"`   // also used for album proposals and facets
+	Compaction       []string `json:"compaction,omitempty"`
+	Suggestion       []string `json:"suggestion,omitempty"`
+	Translation      []string `json:"translation,omitempty"`
+	ContactSheet     []string `json:"contact_sheet,omitempty"`
+}
+
+// ParseKeepAlive reads a keep_alive setting: a Go duration, or a whole number of seconds
+func ParseKeepAlive(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// RetryConfig controls how failed requests are retried. Only transient failures are retried;
+// while Ollama is unreachable, requests wait for it instead of using up attempts.
+type RetryConfig struct {
+	// Attempts is the total number of tries per request (default 3)
+	Attempts int `json:"attempts,omitempty"`
+	// DelaySeconds is the initial delay between tries, doubled after each one (default 1)
+	DelaySeconds float64 `json:"delay_seconds,omitempty"`
+	// MaxJitterSeconds is the upper bound of the random delay added to each wait (default 1)
+	MaxJitterSeconds float64 `json:"max_jitter_seconds,omitempty"`
+}
+
+// HasRemoteEndpoint reports whether any endpoint lies outside this machine and the local
+// network. Host names other than localhost and *.local count as remote, since they aren't resolved.
+func (c *OllamaConfig) HasRemoteEndpoint() bool {
+	for _, endpoint := range c.Endpoints {
+		u, err := url.Parse(endpoint.URL)
+		if err != nil || !isLocalHost(u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLocalHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// EndpointConfig describes one Ollama server in a pool
+type EndpointConfig struct {
+	URL string `json:"url"`
+	// Weight is the endpoint's share of requests relative to the others (default 1)
+	Weight int `json:"weight,omitempty"`
+	// MaxInFlight caps concurrent requests to the endpoint; 0 means no limit
+	MaxInFlight int `json:"max_in_flight,omitempty"`
+}
+
+type JobsConfig struct {
+	// Concurrency is the number of photos or albums described in parallel by a job
+	Concurrency int `json:"concurrency,omitempty"`
 }
 
 type ServerConfig struct {
@@ -53,6 +188,240 @@ type LycheeConfig struct {
 type AlbumsConfig struct {
 	Blocklist  []string `json:"blocklist,omitempty"`
 	PinnedOnly bool     `json:"pinned_only,omitempty"`
+	// ContactSheet controls the collage of representative photos shown to the vision model when
+	// an album is described
+	ContactSheet ContactSheetConfig `json:"contact_sheet,omitempty"`
+	// Sampling bounds the photo descriptions a large album's description is written from
+	Sampling SamplingConfig `json:"sampling,omitempty"`
+	// Descendants controls how sub-albums contribute to the description of their parent
+	Descendants DescendantsConfig `json:"descendants,omitempty"`
+}
+
+// Sub-album aggregation strategies
+const (
+	DescendantsPhotos       = "photos"
+	DescendantsDescriptions = "descriptions"
+)
+
+// DescendantsConfig controls how an album's description takes in the content of its sub-albums
+type DescendantsConfig struct {
+	// Depth is how many levels of sub-albums below an album are included (default 3); -1
+	// includes none
+	Depth int `json:"depth,omitempty"`
+	// Strategy is DescendantsPhotos (default) to describe an album from the photos of its
+	// sub-albums too, or DescendantsDescriptions to use the descriptions of sub-albums that have
+	// one instead of their photos' descriptions
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// Album sampling strategies
+const (
+	SamplingExhaustive = "exhaustive"
+	SamplingSampled    = "sampled"
+)
+
+// SamplingConfig chooses between writing an album description from every photo description, or
+// from a sample stratified by time, place and content
+type SamplingConfig struct {
+	// Strategy is SamplingSampled (default) to sample albums with more than Threshold described
+	// photos, or SamplingExhaustive to always use every photo
+	Strategy string `json:"strategy,omitempty"`
+	// Threshold is the number of described photos above which an album is sampled (default 500)
+	Threshold int `json:"threshold,omitempty"`
+	// SampleSize is the number of photos in a sample (default 200)
+	SampleSize int `json:"sample_size,omitempty"`
+}
+
+// ContactSheetConfig controls the contact sheet of an album: a grid of photos chosen to spread
+// over the album's time span and to look different from each other
+type ContactSheetConfig struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// Photos is the most photos on a sheet (default 9)
+	Photos int `json:"photos,omitempty"`
+	// Candidates is how many photos spread over time are fetched to choose the most varied from
+	// (default 3 times Photos)
+	Candidates int `json:"candidates,omitempty"`
+	// CellSize is the width and height of each photo's cell in pixels (default 384)
+	CellSize int `json:"cell_size,omitempty"`
+}
+
+type TitlesConfig struct {
+	// CameraFilenamePatterns are regular expressions matched against photo titles
+	// (without file extension); only matching photos get AI title suggestions.
+	CameraFilenamePatterns []string `json:"camera_filename_patterns,omitempty"`
+}
+
+type SuggestionsConfig struct {
+	// LowConfidenceThreshold is the confidence below which a photo's best suggestion is considered
+	// a guess; such photos are listed in the low-confidence review queue (default 0.5; 0 lists none)
+	LowConfidenceThreshold float64 `json:"low_confidence_threshold,omitempty"`
+	// PrefetchCount is how many photos after the one being viewed get suggestions computed in the background
+	PrefetchCount int `json:"prefetch_count,omitempty"`
+	// Workers is the number of background workers computing suggestions
+	Workers int `json:"workers,omitempty"`
+	// PrecomputeAll queues every unsorted photo for suggestions at startup and whenever albums change
+	PrecomputeAll bool `json:"precompute_all,omitempty"`
+	// Suggesters weights each suggester's vote in the ensemble by name; a weight of 0 or a
+	// missing name disables the suggester
+	Suggesters map[string]float64 `json:"suggesters,omitempty"`
+	// NeighbourWindowMinutes is how far before and after a photo the neighbours suggester
+	// looks for sorted photos
+	NeighbourWindowMinutes int             `json:"neighbour_window_minutes,omitempty"`
+	NewAlbums              NewAlbumsConfig `json:"new_albums,omitempty"`
+}
+
+// NewAlbumsConfig controls proposals of new albums for groups of unsorted photos that fit no
+// existing album
+type NewAlbumsConfig struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// MinPhotos is the smallest group of photos worth proposing an album for (default 5)
+	MinPhotos int `json:"min_photos,omitempty"`
+}
+
+// ClustersConfig controls how unsorted photos are grouped into events
+type ClustersConfig struct {
+	// GapHours is the time without photos that separates two events (default 6)
+	GapHours float64 `json:"gap_hours,omitempty"`
+	// SplitDistanceKm is the distance between consecutive photos that separates two events (default 50)
+	SplitDistanceKm float64 `json:"split_distance_km,omitempty"`
+}
+
+// GeocodingConfig controls the offline reverse geocoder that names the places of photo coordinates
+type GeocodingConfig struct {
+	// Disabled turns reverse geocoding off; only Lychee's own location field is used then
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxDistanceKm is how far from the nearest known city a photo may be and still be placed there (default 50)
+	MaxDistanceKm float64 `json:"max_distance_km,omitempty"`
+	// GeoNamesFile is an optional GeoNames cities dump (e.g. cities1000.txt) used instead of the built-in cities
+	GeoNamesFile string `json:"geonames_file,omitempty"`
+	// Admin1File is an optional GeoNames admin1CodesASCII.txt naming the regions of GeoNamesFile
+	Admin1File string `json:"admin1_file,omitempty"`
+	// CountryInfoFile is an optional GeoNames countryInfo.txt naming the countries of GeoNamesFile
+	CountryInfoFile string `json:"country_info_file,omitempty"`
+}
+
+// PromptsConfig holds optional paths to text/template files overriding the built-in prompts
+type PromptsConfig struct {
+	PhotoDescription string `json:"photo_description,omitempty"`
+	PhotoTitle       string `json:"photo_title,omitempty"`
+	AlbumDescription string `json:"album_description,omitempty"`
+	Compaction       string `json:"compaction,omitempty"`
+	Suggestions      string `json:"suggestions,omitempty"`
+	AlbumProposal    string `json:"album_proposal,omitempty"`
+	// System is the system message sent with every prompt, holding the style rules
+	System string `json:"system,omitempty"`
+	// Repair asks the model to correct an answer that failed validation
+	Repair string `json:"repair,omitempty"`
+	// Translation translates a description into one of the other output languages
+	Translation string `json:"translation,omitempty"`
+	// ContactSheet asks the vision model to describe an album from its contact sheet
+	ContactSheet string `json:"contact_sheet,omitempty"`
+	// AlbumFacets breaks an album description down into its theme and typical subjects
+	AlbumFacets string `json:"album_facets,omitempty"`
+	// CompactAlbums lists albums in the suggestions prompt by their facets instead of their full
+	// description, where they have them
+	CompactAlbums bool `json:"compact_albums,omitempty"`
+	// Languages are the ISO 639-1 codes of the languages descriptions are written in (default
+	// ["en"]). Everything is generated in the first; descriptions are translated into the others.
+	Languages []string `json:"languages,omitempty"`
+	// Examples are worked examples shown to the model before it suggests albums
+	Examples ExamplesConfig `json:"examples,omitempty"`
+	// PhotoFields lists the photo details given to photo prompts, in order (default DefaultPhotoPromptFields)
+	PhotoFields []string `json:"photo_fields,omitempty"`
+	// Redaction strips sensitive photo data from every prompt
+	Redaction RedactionConfig `json:"redaction,omitempty"`
+}
+
+// ExamplesConfig controls the worked examples, each a photo description and the album it
+// belongs in, that precede the suggestions prompt
+type ExamplesConfig struct {
+	// Suggestions are fixed examples
+	Suggestions []SuggestionExample `json:"suggestions,omitempty"`
+	// Accepted is how many recently accepted suggestions are added as examples (default 3); -1 adds none
+	Accepted int `json:"accepted,omitempty"`
+}
+
+// SuggestionExample is a photo description and the album it belongs in
+type SuggestionExample struct {
+	Description string `json:"description"`
+	// Album is the album's ID or title; examples of albums that aren't offered are left out
+	Album  string `json:"album"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ValidationConfig controls the checks generated text must pass. An answer that fails them is
+// sent back to the model with what is wrong, and counts as failed if it can't be repaired.
+type ValidationConfig struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// RepairAttempts is how often a model is asked to correct an answer before the next profile
+	// is tried (default 1); -1 asks for no corrections
+	RepairAttempts int `json:"repair_attempts,omitempty"`
+	// BannedPhrases make an answer invalid wherever they appear, ignoring case, in addition to
+	// built-in preambles like "Sure! Here is"
+	BannedPhrases []string `json:"banned_phrases,omitempty"`
+	// MinLength and MaxLength bound descriptions in characters (default 20 and 1000)
+	MinLength int `json:"min_length,omitempty"`
+	MaxLength int `json:"max_length,omitempty"`
+	// MaxSentences caps the sentences of each kind of description, by prompt name
+	// (default DefaultMaxSentences)
+	MaxSentences map[string]int `json:"max_sentences,omitempty"`
+}
+
+// DefaultMaxSentences leaves one sentence of slack over what the built-in prompts ask for
+var DefaultMaxSentences = map[string]int{
+	"photo_description": 3,
+	"album_description": 3,
+	"compaction":        5,
+	"contact_sheet":     4,
+}
+
+// Redaction categories
+const (
+	RedactGPS    = "gps"    // coordinates, altitude, direction and locations
+	RedactTitles = "titles" // photo and album titles
+	RedactOwner  = "owner"  // camera make, model and lens, which tie photos to their owner
+)
+
+// RedactionConfig is the privacy policy for prompts
+type RedactionConfig struct {
+	// Strip lists the categories of data to leave out of prompts
+	Strip []string `json:"strip,omitempty"`
+	// Always applies the policy to local endpoints too; by default it only applies when an
+	// Ollama endpoint is remote
+	Always bool `json:"always,omitempty"`
+}
+
+// DefaultPhotoPromptFields are all photo details except the raw coordinates
+var DefaultPhotoPromptFields = []string{
+	"title", "taken_at", "camera", "lens", "focal", "aperture", "shutter", "iso", "location", "altitude", "direction",
+}
+
+// DefaultLowConfidenceThreshold is the confidence below which a suggestion counts as a guess
+const DefaultLowConfidenceThreshold = 0.5
+
+// DefaultSuggesterWeights lets the model's judgement dominate, with metadata heuristics breaking ties
+var DefaultSuggesterWeights = map[string]float64{
+	"llm":        1.0,
+	"neighbours": 1.0,
+	"date_range": 0.5,
+	"location":   0.5,
+	"camera":     0.1,
+}
+
+// DefaultCameraFilenamePatterns match the filenames commonly assigned by cameras and phones
+var DefaultCameraFilenamePatterns = []string{
+	`(?i)^IMG[_-]?\d+`,
+	`(?i)^_?DSC[NF_]?\d+`,
+	`(?i)^P\d{7}$`,
+	`(?i)^PXL_\d{8}_\d+`,
+	`(?i)^MVIMG_\d{8}_\d+`,
+	`(?i)^GOPR\d+`,
+	`(?i)^G[HX]\d{6}`,
+	`(?i)^DJI_\d+`,
+	`(?i)^(IMG|VID)?[_-]?\d{8}[_-]\d{6}`,
+	`(?i)^\d{4}-\d{2}-\d{2}[ _]\d{2}[.:-]?\d{2}[.:-]?\d{2}`,
+	`(?i)^photo[_ -]?\d+$`,
+	`(?i)^image[_ -]?\d+$`,
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -61,7 +430,11 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, err
 	}
 
-	var config Config
+	// Defaults that 0 is a valid setting for are filled in before decoding, so that only a missing
+	// key gets the default
+	config := Config{
+		Suggestions: SuggestionsConfig{LowConfidenceThreshold: DefaultLowConfidenceThreshold},
+	}
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
@@ -73,6 +446,98 @@ func LoadConfig(configPath string) (*Config, error) {
 	if config.Server.Port == 0 {
 		config.Server.Port = 8080
 	}
+	if config.Suggestions.PrefetchCount == 0 {
+		config.Suggestions.PrefetchCount = 5
+	}
+	if config.Suggestions.Workers == 0 {
+		config.Suggestions.Workers = 1
+	}
+	if config.Suggestions.Suggesters == nil {
+		config.Suggestions.Suggesters = DefaultSuggesterWeights
+	}
+	if config.Suggestions.NeighbourWindowMinutes == 0 {
+		config.Suggestions.NeighbourWindowMinutes = 60
+	}
+	if config.Suggestions.NewAlbums.MinPhotos == 0 {
+		config.Suggestions.NewAlbums.MinPhotos = 5
+	}
+	if config.Albums.ContactSheet.Photos == 0 {
+		config.Albums.ContactSheet.Photos = 9
+	}
+	if config.Albums.ContactSheet.Candidates == 0 {
+		config.Albums.ContactSheet.Candidates = 3 * config.Albums.ContactSheet.Photos
+	}
+	if config.Albums.ContactSheet.CellSize == 0 {
+		config.Albums.ContactSheet.CellSize = 384
+	}
+	if config.Albums.Sampling.Strategy == "" {
+		config.Albums.Sampling.Strategy = SamplingSampled
+	}
+	if config.Albums.Sampling.Threshold == 0 {
+		config.Albums.Sampling.Threshold = 500
+	}
+	if config.Albums.Sampling.SampleSize == 0 {
+		config.Albums.Sampling.SampleSize = 200
+	}
+	if config.Albums.Descendants.Depth == 0 {
+		config.Albums.Descendants.Depth = 3
+	}
+	if config.Albums.Descendants.Strategy == "" {
+		config.Albums.Descendants.Strategy = DescendantsPhotos
+	}
+	if config.Clusters.GapHours == 0 {
+		config.Clusters.GapHours = 6
+	}
+	if config.Clusters.SplitDistanceKm == 0 {
+		config.Clusters.SplitDistanceKm = 50
+	}
+	if config.Geocoding.MaxDistanceKm == 0 {
+		config.Geocoding.MaxDistanceKm = 50
+	}
+	if config.Prompts.Examples.Accepted == 0 {
+		config.Prompts.Examples.Accepted = 3
+	}
+	if config.Validation.RepairAttempts == 0 {
+		config.Validation.RepairAttempts = 1
+	}
+	if config.Validation.MinLength == 0 {
+		config.Validation.MinLength = 20
+	}
+	if config.Validation.MaxLength == 0 {
+		config.Validation.MaxLength = 1000
+	}
+	if config.Validation.MaxSentences == nil {
+		config.Validation.MaxSentences = DefaultMaxSentences
+	}
+	if len(config.Prompts.Languages) == 0 {
+		config.Prompts.Languages = []string{"en"}
+	}
+	if len(config.Prompts.PhotoFields) == 0 {
+		config.Prompts.PhotoFields = DefaultPhotoPromptFields
+	}
+	if len(config.Titles.CameraFilenamePatterns) == 0 {
+		config.Titles.CameraFilenamePatterns = DefaultCameraFilenamePatterns
+	}
+	if len(config.Ollama.Endpoints) == 0 && config.Ollama.Endpoint != "" {
+		config.Ollama.Endpoints = []EndpointConfig{{URL: config.Ollama.Endpoint}}
+	}
+	for i := range config.Ollama.Endpoints {
+		if config.Ollama.Endpoints[i].Weight == 0 {
+			config.Ollama.Endpoints[i].Weight = 1
+		}
+	}
+	if config.Jobs.Concurrency == 0 {
+		config.Jobs.Concurrency = 1
+	}
+	if config.Ollama.Retry.Attempts == 0 {
+		config.Ollama.Retry.Attempts = 3
+	}
+	if config.Ollama.Retry.DelaySeconds == 0 {
+		config.Ollama.Retry.DelaySeconds = 1
+	}
+	if config.Ollama.Retry.MaxJitterSeconds == 0 {
+		config.Ollama.Retry.MaxJitterSeconds = 1
+	}
 
 	// Validate configuration
 	if err := validateConfig(&config); err != nil {
@@ -114,16 +579,67 @@ func validateConfig(config *Config) error {
 	}
 
 	// Validate Ollama config
-	if config.Ollama.Endpoint == "" {
+	if len(config.Ollama.Endpoints) == 0 {
 		return fmt.Errorf("ollama endpoint is required")
 	}
-	if _, err := url.Parse(config.Ollama.Endpoint); err != nil {
-		return fmt.Errorf("invalid ollama endpoint URL: %w", err)
+	for _, endpoint := range config.Ollama.Endpoints {
+		if endpoint.URL == "" {
+			return fmt.Errorf("ollama endpoints need a url")
+		}
+		if _, err := url.Parse(endpoint.URL); err != nil {
+			return fmt.Errorf("invalid ollama endpoint URL: %w", err)
+		}
+		if endpoint.Weight < 0 {
+			return fmt.Errorf("ollama endpoint %s: weight must not be negative", endpoint.URL)
+		}
+		if endpoint.MaxInFlight < 0 {
+			return fmt.Errorf("ollama endpoint %s: max_in_flight must not be negative", endpoint.URL)
+		}
+	}
+	if config.Ollama.Retry.Attempts < 0 || config.Ollama.Retry.DelaySeconds < 0 || config.Ollama.Retry.MaxJitterSeconds < 0 {
+		return fmt.Errorf("ollama retry settings must not be negative")
 	}
-	if config.Ollama.ImageAnalysisModel == "" {
+	if !config.Ollama.Think.Valid() {
+		return fmt.Errorf("invalid ollama think setting %q", config.Ollama.Think)
+	}
+	for name, profile := range config.Ollama.Profiles {
+		if profile.Model == "" {
+			return fmt.Errorf("ollama profile %s: model is required", name)
+		}
+		if !profile.Think.Valid() {
+			return fmt.Errorf("ollama profile %s: invalid think setting %q", name, profile.Think)
+		}
+		if profile.KeepAlive != "" {
+			if _, err := ParseKeepAlive(profile.KeepAlive); err != nil {
+				return fmt.Errorf("ollama profile %s: invalid keep_alive %q", name, profile.KeepAlive)
+			}
+		}
+		if profile.TimeoutSeconds < 0 {
+			return fmt.Errorf("ollama profile %s: timeout_seconds must not be negative", name)
+		}
+	}
+	tasks := config.Ollama.Tasks
+	for task, chain := range map[string][]string{
+		"photo_description": tasks.PhotoDescription,
+		"album_synthesis":   tasks.AlbumSynthesis,
+		"compaction":        tasks.Compaction,
+		"suggestion":        tasks.Suggestion,
+		"translation":       tasks.Translation,
+		"contact_sheet":     tasks.ContactSheet,
+	} {
+		for _, name := range chain {
+			if _, ok := config.Ollama.Profiles[name]; !ok {
+				return fmt.Errorf("ollama task %s: unknown profile %q", task, name)
+			}
+		}
+	}
+	if config.Ollama.ImageAnalysisModel == "" &&
+		(len(tasks.PhotoDescription) == 0 || (len(tasks.ContactSheet) == 0 && !config.Albums.ContactSheet.Disabled)) {
 		return fmt.Errorf("ollama image analysis model is required")
 	}
-	if config.Ollama.DescriptionSynthesisModel == "" {
+	if config.Ollama.DescriptionSynthesisModel == "" &&
+		(len(tasks.AlbumSynthesis) == 0 || len(tasks.Compaction) == 0 || len(tasks.Suggestion) == 0 ||
+			(len(tasks.Translation) == 0 && len(config.Prompts.Languages) > 1)) {
 		return fmt.Errorf("ollama description synthesis model is required")
 	}
 
@@ -137,6 +653,120 @@ func validateConfig(config *Config) error {
 	// Remove trailing slash for consistency
 	config.Lychee.BaseURL = strings.TrimSuffix(config.Lychee.BaseURL, "/")
 
+	// Validate titles config
+	for _, pattern := range config.Titles.CameraFilenamePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid camera filename pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Validate suggestions config
+	if config.Suggestions.LowConfidenceThreshold < 0 || config.Suggestions.LowConfidenceThreshold > 1 {
+		return fmt.Errorf("suggestions low_confidence_threshold must be between 0 and 1")
+	}
+	if config.Suggestions.PrefetchCount < 0 {
+		return fmt.Errorf("suggestions prefetch_count must not be negative")
+	}
+	if config.Suggestions.Workers < 0 {
+		return fmt.Errorf("suggestions workers must not be negative")
+	}
+	if config.Suggestions.NeighbourWindowMinutes < 0 {
+		return fmt.Errorf("suggestions neighbour_window_minutes must not be negative")
+	}
+	if config.Suggestions.NewAlbums.MinPhotos < 0 {
+		return fmt.Errorf("suggestions new_albums min_photos must not be negative")
+	}
+	for name, weight := range config.Suggestions.Suggesters {
+		if weight < 0 {
+			return fmt.Errorf("suggestions suggester %s: weight must not be negative", name)
+		}
+	}
+
+	// Validate clusters config
+	if config.Clusters.GapHours < 0 || config.Clusters.SplitDistanceKm < 0 {
+		return fmt.Errorf("clusters settings must not be negative")
+	}
+
+	// Validate prompts config
+	for _, category := range config.Prompts.Redaction.Strip {
+		if category != RedactGPS && category != RedactTitles && category != RedactOwner {
+			return fmt.Errorf("prompts redaction: unknown category %q (use %s, %s or %s)", category, RedactGPS, RedactTitles, RedactOwner)
+		}
+	}
+	if config.Prompts.Examples.Accepted < -1 {
+		return fmt.Errorf("prompts examples: accepted must be -1 or more")
+	}
+	seenLanguages := make(map[string]bool)
+	for i, code := range config.Prompts.Languages {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" {
+			return fmt.Errorf("prompts languages: language %d is empty", i+1)
+		}
+		if seenLanguages[code] {
+			return fmt.Errorf("prompts languages: %q is listed twice", code)
+		}
+		seenLanguages[code] = true
+		config.Prompts.Languages[i] = code
+	}
+	for i, example := range config.Prompts.Examples.Suggestions {
+		if strings.TrimSpace(example.Description) == "" || strings.TrimSpace(example.Album) == "" {
+			return fmt.Errorf("prompts examples: suggestion example %d needs a description and an album", i+1)
+		}
+	}
+
+	// Validate validation config
+	if config.Validation.RepairAttempts < -1 {
+		return fmt.Errorf("validation repair_attempts must be -1 or more")
+	}
+	if config.Validation.MinLength < 0 || config.Validation.MaxLength < config.Validation.MinLength {
+		return fmt.Errorf("validation max_length must be at least min_length, and neither negative")
+	}
+	for name, n := range config.Validation.MaxSentences {
+		if n < 0 {
+			return fmt.Errorf("validation max_sentences for %s must not be negative", name)
+		}
+	}
+
+	// Validate contact sheet config
+	sheet := config.Albums.ContactSheet
+	if sheet.Photos < 1 || sheet.Candidates < sheet.Photos {
+		return fmt.Errorf("albums contact_sheet: photos must be at least 1 and candidates at least photos")
+	}
+	if sheet.CellSize < 64 || sheet.CellSize > 2048 {
+		return fmt.Errorf("albums contact_sheet: cell_size must be between 64 and 2048")
+	}
+
+	// Validate sampling config
+	sampling := config.Albums.Sampling
+	if sampling.Strategy != SamplingExhaustive && sampling.Strategy != SamplingSampled {
+		return fmt.Errorf("albums sampling: strategy must be %s or %s", SamplingExhaustive, SamplingSampled)
+	}
+	if sampling.SampleSize < 1 || sampling.Threshold < sampling.SampleSize {
+		return fmt.Errorf("albums sampling: sample_size must be at least 1 and threshold at least sample_size")
+	}
+
+	// Validate descendants config
+	descendants := config.Albums.Descendants
+	if descendants.Strategy != DescendantsPhotos && descendants.Strategy != DescendantsDescriptions {
+		return fmt.Errorf("albums descendants: strategy must be %s or %s", DescendantsPhotos, DescendantsDescriptions)
+	}
+	if descendants.Depth < -1 {
+		return fmt.Errorf("albums descendants: depth must be -1 or more")
+	}
+
+	// Validate geocoding config
+	if config.Geocoding.MaxDistanceKm < 0 {
+		return fmt.Errorf("geocoding max_distance_km must not be negative")
+	}
+	if (config.Geocoding.Admin1File != "" || config.Geocoding.CountryInfoFile != "") && config.Geocoding.GeoNamesFile == "" {
+		return fmt.Errorf("geocoding admin1_file and country_info_file require geonames_file")
+	}
+
+	// Validate jobs config
+	if config.Jobs.Concurrency < 0 {
+		return fmt.Errorf("jobs concurrency must not be negative")
+	}
+
 	// Validate server config
 	if config.Server.Port <= 0 || config.Server.Port > 65535 {
 		return fmt.Errorf("server port must be between 1 and 65535")
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The example configuration is what new users copy, so it must load, and where it spells out
+// defaults they must be the real ones
+func TestExampleConfig(t *testing.T) {
+	cfg, err := LoadConfig("../../config.example.json")
+	if err != nil {
+		t.Fatalf("config.example.json doesn't load: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Titles.CameraFilenamePatterns, DefaultCameraFilenamePatterns) {
+		t.Errorf("camera_filename_patterns = %q, want the defaults", cfg.Titles.CameraFilenamePatterns)
+	}
+	if !reflect.DeepEqual(cfg.Suggestions.Suggesters, DefaultSuggesterWeights) {
+		t.Errorf("suggesters = %v, want the defaults", cfg.Suggestions.Suggesters)
+	}
+	if !reflect.DeepEqual(cfg.Prompts.PhotoFields, DefaultPhotoPromptFields) {
+		t.Errorf("photo_fields = %q, want the defaults", cfg.Prompts.PhotoFields)
+	}
+	if !reflect.DeepEqual(cfg.Validation.MaxSentences, DefaultMaxSentences) {
+		t.Errorf("max_sentences = %v, want the defaults", cfg.Validation.MaxSentences)
+	}
+}
+
+func TestLowConfidenceThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		suggestions string
+		want        float64
+	}{
+		{"missing", `{}`, DefaultLowConfidenceThreshold},
+		{"set", `{"low_confidence_threshold": 0.3}`, 0.3},
+		{"zero", `{"low_confidence_threshold": 0}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTestConfig(t, `"suggestions": `+tt.suggestions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Suggestions.LowConfidenceThreshold != tt.want {
+				t.Errorf("low_confidence_threshold = %v, want %v", cfg.Suggestions.LowConfidenceThreshold, tt.want)
+			}
+		})
+	}
+}
+
+// loadTestConfig loads a minimal valid SQLite configuration with extra top-level sections
+func loadTestConfig(t *testing.T, sections string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"database": {"type": "sqlite", "database": "lychee.db"},
+		"ollama": {"endpoint": "http://localhost:11434", "image_analysis_model": "llava:7b", "description_synthesis_model": "llama3.1:8b"},
+		"lychee": {"base_url": "https://photos.example.com"}`
+	if sections != "" {
+		data += ",\n" + sections
+	}
+	data += "}"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(path)
+}
@@ -0,0 +1,114 @@
+package database
+
+// GetChildAlbums returns the direct sub-albums of an album, leaving out blocked ones
+func (db *DB) GetChildAlbums(albumID string) ([]Album, error) {
+	blocklistCondition, blocklistArgs := db.buildBlocklistCondition()
+	query := `
+		SELECT ba.id, ba.created_at, ba.updated_at, ba.published_at, ba.title, ba.description,
+		       ba.owner_id, ba.is_nsfw, ba.is_pinned, ba.sorting_col, ba.sorting_order,
+		       ba.copyright, ba.photo_layout, ba.photo_timeline, a.parent_id,
+		       ba._ai_description, ba._ai_description_ts
+		FROM base_albums ba
+		JOIN albums a ON ba.id = a.id
+		WHERE a.parent_id = ?` + blocklistCondition + `
+		ORDER BY ba.title`
+
+	rows, err := db.conn.Query(db.rebind(query), append([]interface{}{albumID}, blocklistArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var albums []Album
+	for rows.Next() {
+		var album Album
+		err := rows.Scan(
+			&album.ID, &album.CreatedAt, &album.UpdatedAt, &album.PublishedAt,
+			&album.Title, &album.Description, &album.OwnerID, &album.IsNsfw,
+			&album.IsPinned, &album.SortingCol, &album.SortingOrder,
+			&album.Copyright, &album.PhotoLayout, &album.PhotoTimeline,
+			&album.ParentID, &album.AIDescription, &album.AIDescriptionTimestamp,
+		)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
+
+// GetAlbumContent gathers the photos of an album and of its sub-albums down to depth levels
+// below it; 0 includes only the album's own photos. With useDescriptions, a sub-album with an AI
+// description is listed in SubAlbums and its photos, and those of its own sub-albums, are marked
+// as summarized by it.
+func (db *DB) GetAlbumContent(albumID string, depth int, useDescriptions bool) (*AlbumContent, error) {
+	content := &AlbumContent{Summarized: make(map[string]bool)}
+	seen := make(map[string]bool)
+
+	var collect func(albumID string, depth int, summarized bool) error
+	collect = func(albumID string, depth int, summarized bool) error {
+		photos, err := db.GetPhotosInAlbum(albumID)
+		if err != nil {
+			return err
+		}
+		for _, photo := range photos {
+			if seen[photo.ID] {
+				continue
+			}
+			seen[photo.ID] = true
+			content.Photos = append(content.Photos, photo)
+			if summarized {
+				content.Summarized[photo.ID] = true
+			}
+		}
+
+		if depth <= 0 {
+			return nil
+		}
+		children, err := db.GetChildAlbums(albumID)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			childSummarized := summarized
+			if !summarized && useDescriptions && child.AIDescription.Valid {
+				content.SubAlbums = append(content.SubAlbums, child)
+				childSummarized = true
+			}
+			if err := collect(child.ID, depth-1, childSummarized); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := collect(albumID, depth, false); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// GetAlbum returns an album by ID, whether or not it is a sub-album
+func (db *DB) GetAlbum(albumID string) (*Album, error) {
+	query := `
+		SELECT ba.id, ba.created_at, ba.updated_at, ba.published_at, ba.title, ba.description,
+		       ba.owner_id, ba.is_nsfw, ba.is_pinned, ba.sorting_col, ba.sorting_order,
+		       ba.copyright, ba.photo_layout, ba.photo_timeline, a.parent_id,
+		       ba._ai_description, ba._ai_description_ts
+		FROM base_albums ba
+		LEFT JOIN albums a ON ba.id = a.id
+		WHERE ba.id = ?`
+
+	var album Album
+	err := db.conn.QueryRow(db.rebind(query), albumID).Scan(
+		&album.ID, &album.CreatedAt, &album.UpdatedAt, &album.PublishedAt,
+		&album.Title, &album.Description, &album.OwnerID, &album.IsNsfw,
+		&album.IsPinned, &album.SortingCol, &album.SortingOrder,
+		&album.Copyright, &album.PhotoLayout, &album.PhotoTimeline,
+		&album.ParentID, &album.AIDescription, &album.AIDescriptionTimestamp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &album, nil
+}
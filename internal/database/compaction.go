@@ -0,0 +1,28 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetCompactionSummary returns the cached summary for a compaction request, identified by the
+// hash of its model and prompt. ok is false if the request has not been seen before.
+func (db *DB) GetCompactionSummary(inputHash string) (summary string, ok bool, err error) {
+	err = db.conn.QueryRow(db.rebind(`SELECT summary FROM _ai_compaction_cache WHERE input_hash = ?`), inputHash).Scan(&summary)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return summary, true, nil
+}
+
+// SaveCompactionSummary caches the summary produced for a compaction request
+func (db *DB) SaveCompactionSummary(inputHash, model, summary string) error {
+	query := db.upsertQuery("_ai_compaction_cache",
+		[]string{"input_hash", "model", "summary", "created_at"},
+		[]string{"input_hash"})
+
+	_, err := db.conn.Exec(query, inputHash, model, summary, time.Now())
+	return err
+}
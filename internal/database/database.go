@@ -117,6 +117,25 @@ func photoSelectColumns() string {
 	        live_photo_content_id, live_photo_checksum, _ai_description, _ai_description_ts`
 }
 
+func (db *DB) GetPhoto(photoID string) (*Photo, error) {
+	query := fmt.Sprintf(`SELECT %s FROM photos WHERE id = ?`, photoSelectColumns())
+
+	rows, err := db.conn.Query(db.rebind(query), photoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	return scanPhoto(rows)
+}
+
 func (db *DB) GetUnsortedPhotos() ([]Photo, error) {
 	query := fmt.Sprintf(`
 		SELECT %s
@@ -294,9 +313,9 @@ func (db *DB) GetPhotosInAlbum(albumID string) ([]Photo, error) {
 		INNER JOIN photo_album pa ON p.id = pa.photo_id
 		WHERE pa.album_id = ?
 		ORDER BY p.taken_at DESC, p.created_at DESC`, 
-		strings.ReplaceAll(photoSelectColumns(), "id,", "p.id,"))
+		strings.Replace(photoSelectColumns(), "id,", "p.id,", 1)) // only the leading id; owner_id must stay
 
-	rows, err := db.conn.Query(query, albumID)
+	rows, err := db.conn.Query(db.rebind(query), albumID)
 	if err != nil {
 		return nil, err
 	}
@@ -315,18 +334,44 @@ func (db *DB) GetPhotosInAlbum(albumID string) ([]Photo, error) {
 }
 
 func (db *DB) MovePhotoToAlbum(photoID, albumID string) error {
+	return db.movePhoto(db.conn, photoID, albumID)
+}
+
+// MovePhotosToAlbum moves several photos into an album in one transaction
+func (db *DB) MovePhotosToAlbum(photoIDs []string, albumID string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, photoID := range photoIDs {
+		if err := db.movePhoto(tx, photoID, albumID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (db *DB) movePhoto(ex execer, photoID, albumID string) error {
 	switch db.dbType {
 	case config.TypeMySQL:
 		query := `INSERT INTO photo_album (album_id, photo_id) VALUES (?, ?) ON DUPLICATE KEY UPDATE album_id = ?`
-		_, err := db.conn.Exec(query, albumID, photoID, albumID)
+		_, err := ex.Exec(query, albumID, photoID, albumID)
 		return err
 	case config.TypePostgreSQL:
 		query := `INSERT INTO photo_album (album_id, photo_id) VALUES ($1, $2) ON CONFLICT (album_id, photo_id) DO UPDATE SET album_id = $1`
-		_, err := db.conn.Exec(query, albumID, photoID)
+		_, err := ex.Exec(query, albumID, photoID)
 		return err
 	case config.TypeSQLite:
 		query := `INSERT OR REPLACE INTO photo_album (album_id, photo_id) VALUES (?, ?)`
-		_, err := db.conn.Exec(query, albumID, photoID)
+		_, err := ex.Exec(query, albumID, photoID)
 		return err
 	default:
 		return fmt.Errorf("unsupported database type: %s", db.dbType)
@@ -407,3 +452,57 @@ func (db *DB) GetPhotoSizeVariant(photoID string) (*SizeVariant, error) {
 	
 	return &variant, nil
 }
+
+// GetPhotoSizeVariants returns all size variants of a photo
+func (db *DB) GetPhotoSizeVariants(photoID string) ([]SizeVariant, error) {
+	query := `
+		SELECT id, photo_id, type, short_path, width, height, ratio, filesize, storage_disk
+		FROM size_variants
+		WHERE photo_id = ?
+		ORDER BY type ASC`
+
+	rows, err := db.conn.Query(db.rebind(query), photoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []SizeVariant
+	for rows.Next() {
+		var variant SizeVariant
+		err := rows.Scan(
+			&variant.ID, &variant.PhotoID, &variant.Type, &variant.ShortPath,
+			&variant.Width, &variant.Height, &variant.Ratio, &variant.Filesize,
+			&variant.StorageDisk,
+		)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	return variants, rows.Err()
+}
+
+// GetUnsortedPhotoIDs returns the IDs of all unsorted photos in filmstrip order
+func (db *DB) GetUnsortedPhotoIDs() ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT id FROM photos
+		WHERE id NOT IN (SELECT photo_id FROM photo_album)
+		ORDER BY taken_at DESC, created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var photoIDs []string
+	for rows.Next() {
+		var photoID string
+		if err := rows.Scan(&photoID); err != nil {
+			return nil, err
+		}
+		photoIDs = append(photoIDs, photoID)
+	}
+
+	return photoIDs, rows.Err()
+}
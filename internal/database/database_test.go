@@ -0,0 +1,59 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+// lycheeTables holds the parts of Lychee's schema the organizer reads and writes
+var lycheeTables = []string{
+	`CREATE TABLE base_albums (
+		id VARCHAR(24) PRIMARY KEY, created_at DATETIME, updated_at DATETIME, published_at DATETIME,
+		title TEXT, description TEXT, owner_id INTEGER, is_nsfw BOOLEAN DEFAULT 0,
+		is_pinned BOOLEAN DEFAULT 0, sorting_col TEXT, sorting_order TEXT, copyright TEXT,
+		photo_layout TEXT, photo_timeline TEXT, _ai_description TEXT, _ai_description_ts DATETIME)`,
+	`CREATE TABLE albums (id VARCHAR(24) PRIMARY KEY, parent_id VARCHAR(24), license TEXT, _lft INTEGER, _rgt INTEGER)`,
+	`CREATE TABLE photos (
+		id VARCHAR(24) PRIMARY KEY, created_at DATETIME, updated_at DATETIME, owner_id INTEGER DEFAULT 1,
+		old_album_id TEXT, title TEXT, description TEXT, tags TEXT, license TEXT DEFAULT 'none',
+		is_starred BOOLEAN DEFAULT 0, iso TEXT, make TEXT, model TEXT, lens TEXT, aperture TEXT,
+		shutter TEXT, focal TEXT, latitude REAL, longitude REAL, altitude REAL, img_direction REAL,
+		location TEXT, taken_at DATETIME, taken_at_orig_tz TEXT, initial_taken_at DATETIME,
+		initial_taken_at_orig_tz TEXT, type TEXT DEFAULT 'image/jpeg', filesize INTEGER DEFAULT 0,
+		checksum TEXT DEFAULT '', original_checksum TEXT DEFAULT '', live_photo_short_path TEXT,
+		live_photo_content_id TEXT, live_photo_checksum TEXT, _ai_description TEXT,
+		_ai_description_ts DATETIME)`,
+	`CREATE TABLE photo_album (album_id VARCHAR(24), photo_id VARCHAR(24), PRIMARY KEY (album_id, photo_id))`,
+}
+
+// newTestDB opens an empty SQLite database with Lychee's tables and the organizer's
+func newTestDB(t *testing.T, blocklist ...string) *DB {
+	t.Helper()
+	db, err := NewDB(&config.DatabaseConfig{Type: config.TypeSQLite, Database: filepath.Join(t.TempDir(), "lychee.db")}, blocklist, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.conn.Close() })
+
+	for _, stmt := range lycheeTables {
+		if _, err := db.conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.EnsureSchema(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// exec runs setup statements, failing the test on the first error
+func exec(t *testing.T, db *DB, statements ...string) {
+	t.Helper()
+	for _, stmt := range statements {
+		if _, err := db.conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
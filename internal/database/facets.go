@@ -0,0 +1,85 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// SaveAlbumFacets stores the structured parts of an album's description, replacing earlier ones
+func (db *DB) SaveAlbumFacets(f *AlbumFacets) error {
+	query := db.upsertQuery("_ai_album_facets",
+		[]string{"album_id", "theme", "subjects", "places", "date_start", "date_end", "created_at"},
+		[]string{"album_id"})
+
+	_, err := db.conn.Exec(query, f.AlbumID, f.Theme, joinLines(f.Subjects), joinLines(f.Places), f.DateStart, f.DateEnd, time.Now())
+	return err
+}
+
+// GetAlbumFacets returns the structured parts of every album description that has them, by album ID
+func (db *DB) GetAlbumFacets() (map[string]AlbumFacets, error) {
+	query := `SELECT album_id, theme, subjects, places, date_start, date_end FROM _ai_album_facets`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := make(map[string]AlbumFacets)
+	for rows.Next() {
+		var f AlbumFacets
+		var subjects, places string
+		if err := rows.Scan(&f.AlbumID, &f.Theme, &subjects, &places, &f.DateStart, &f.DateEnd); err != nil {
+			return nil, err
+		}
+		f.Subjects = splitLines(subjects)
+		f.Places = splitLines(places)
+		facets[f.AlbumID] = f
+	}
+	return facets, rows.Err()
+}
+
+// GetSubAlbumTitles returns the titles of an album's direct sub-albums, leaving out blocked ones
+func (db *DB) GetSubAlbumTitles(albumID string) ([]string, error) {
+	blocklistCondition, blocklistArgs := db.buildBlocklistCondition()
+	query := `
+		SELECT ba.title
+		FROM albums a
+		JOIN base_albums ba ON ba.id = a.id
+		WHERE a.parent_id = ?` + blocklistCondition + `
+		ORDER BY ba.title`
+
+	rows, err := db.conn.Query(db.rebind(query), append([]interface{}{albumID}, blocklistArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, rows.Err()
+}
+
+// joinLines stores a list in a text column, one item per line
+func joinLines(items []string) string {
+	cleaned := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.Join(strings.Fields(item), " "); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+	return strings.Join(cleaned, "\n")
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
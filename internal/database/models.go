@@ -63,6 +63,178 @@ type Album struct {
 	AIDescriptionTimestamp sql.NullTime   `db:"_ai_description_ts"`
 }
 
+// TitleSuggestion is an AI-proposed replacement for a camera filename title, awaiting review
+type TitleSuggestion struct {
+	PhotoID        string       `db:"photo_id"`
+	OriginalTitle  string       `db:"original_title"`
+	SuggestedTitle string       `db:"suggested_title"`
+	Status         string       `db:"status"`
+	CreatedAt      time.Time    `db:"created_at"`
+	ReviewedAt     sql.NullTime `db:"reviewed_at"`
+}
+
+const (
+	TitleSuggestionPending  = "pending"
+	TitleSuggestionApproved = "approved"
+	TitleSuggestionRejected = "rejected"
+)
+
+// AlbumSuggestion is a suggested album for an unsorted photo
+type AlbumSuggestion struct {
+	PhotoID    string    `db:"photo_id" json:"-"`
+	AlbumID    string    `db:"album_id" json:"album_id"`
+	Rank       int       `db:"suggestion_rank" json:"-"`
+	Confidence float64   `db:"confidence" json:"confidence"`
+	Reason     string    `db:"reason" json:"reason"`
+	CreatedAt  time.Time `db:"created_at" json:"-"`
+
+	// Contributions break the confidence down by the suggesters that produced it
+	Contributions []SuggestionContribution `json:"-"`
+}
+
+// SuggestionContribution is one suggester's part in an album suggestion. Weight is the
+// suggester's share of the ensemble, so Confidence * Weight summed over all contributions
+// gives the suggestion's confidence.
+type SuggestionContribution struct {
+	Suggester  string  `db:"suggester"`
+	Confidence float64 `db:"confidence"`
+	Weight     float64 `db:"weight"`
+}
+
+// AlbumPhotoMetadata is the metadata of a photo in an album that heuristic suggesters compare against
+type AlbumPhotoMetadata struct {
+	AlbumID       string          `db:"album_id"`
+	PhotoID       string          `db:"photo_id"`
+	TakenAt       sql.NullTime    `db:"taken_at"`
+	TakenAtOrigTz sql.NullString  `db:"taken_at_orig_tz"`
+	Latitude      sql.NullFloat64 `db:"latitude"`
+	Longitude     sql.NullFloat64 `db:"longitude"`
+	Make          sql.NullString  `db:"make"`
+	Model         sql.NullString  `db:"model"`
+}
+
+// AlbumProposal is an AI-proposed new album for a cluster of unsorted photos that fit no
+// existing album
+type AlbumProposal struct {
+	ID          string         `db:"id"` // the cluster ID
+	Title       string         `db:"title"`
+	Description string         `db:"description"`
+	Status      string         `db:"status"`
+	AlbumID     sql.NullString `db:"album_id"` // the created album, once approved
+	CreatedAt   time.Time      `db:"created_at"`
+	ReviewedAt  sql.NullTime   `db:"reviewed_at"`
+	PhotoIDs    []string       `db:"-"`
+}
+
+// AcceptedSuggestion is a suggested album that a photo was moved into, together with the photo's
+// AI description. Recent ones serve as examples for the model.
+type AcceptedSuggestion struct {
+	PhotoID     string    `db:"photo_id"`
+	AlbumID     string    `db:"album_id"`
+	Reason      string    `db:"reason"`
+	AcceptedAt  time.Time `db:"accepted_at"`
+	Description string    `db:"-"`
+}
+
+// ValidationFailure is the latest answer about an item that failed validation and couldn't be
+// repaired. It is removed once a valid answer is generated.
+type ValidationFailure struct {
+	Kind      string    `db:"kind"`    // the prompt that was answered
+	ItemID    string    `db:"item_id"` // the photo, album or cluster it was about
+	Model     string    `db:"model"`
+	Reason    string    `db:"reason"`
+	Response  string    `db:"response"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Reasoning is what a thinking model reasoned before producing one piece of output, kept
+// apart from the output itself
+type Reasoning struct {
+	Kind      string    `db:"kind"`    // the prompt that was answered, e.g. "photo_description"
+	ItemID    string    `db:"item_id"` // the photo, album or cluster it was about
+	Model     string    `db:"model"`
+	Reasoning string    `db:"reasoning"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Item types of translated descriptions
+const (
+	TranslationPhoto = "photo"
+	TranslationAlbum = "album"
+)
+
+// Untranslated is a photo or album whose description has no up-to-date translation into some
+// language
+type Untranslated struct {
+	ItemType    string `db:"item_type"` // TranslationPhoto or TranslationAlbum
+	ItemID      string `db:"item_id"`
+	Title       string `db:"title"`
+	Description string `db:"description"` // in the primary language
+}
+
+// AlbumContent is what an album's description is written from
+type AlbumContent struct {
+	// Photos are the album's photos and those of its sub-albums down to the requested depth, each once
+	Photos []Photo
+	// SubAlbums are the described sub-albums whose description stands in for their photos'
+	// descriptions
+	SubAlbums []Album
+	// Summarized holds the IDs of the photos in SubAlbums and their sub-albums
+	Summarized map[string]bool
+}
+
+// Provenance records how a generated text was made
+type Provenance struct {
+	Kind       string    `db:"kind" json:"kind"`       // the prompt name
+	ItemID     string    `db:"item_id" json:"item_id"` // the photo or album; "<id>/<language>" for translations
+	Model      string    `db:"model" json:"model"`
+	Digest     string    `db:"digest" json:"digest"`           // the model's digest on the endpoint that answered; empty if unknown
+	PromptHash string    `db:"prompt_hash" json:"prompt_hash"` // identifies the prompt and system templates
+	Options    string    `db:"options" json:"options"`         // the request options as JSON
+	DurationMs int64     `db:"duration_ms" json:"duration_ms"` // including corrections of invalid answers
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// ProvenanceCount is how many texts of one kind were made with one model and prompt
+type ProvenanceCount struct {
+	Kind       string `json:"kind"`
+	Model      string `json:"model"`
+	Digest     string `json:"digest"`
+	PromptHash string `json:"prompt_hash"`
+	Count      int    `json:"count"`
+}
+
+// ProvenanceFilter selects descriptions by how they were made. Set fields must all match;
+// Untracked also selects descriptions without provenance, made before it was recorded.
+type ProvenanceFilter struct {
+	Model      string `json:"model,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	PromptHash string `json:"prompt_hash,omitempty"`
+	Untracked  bool   `json:"untracked,omitempty"`
+}
+
+// Empty reports whether the filter selects nothing
+func (f ProvenanceFilter) Empty() bool {
+	return f.Model == "" && f.Digest == "" && f.PromptHash == "" && !f.Untracked
+}
+
+// AlbumFacets is an album's description in structured parts, compact enough to list many
+// albums in one prompt
+type AlbumFacets struct {
+	AlbumID   string   `db:"album_id"`
+	Theme     string   `db:"theme"`    // a short phrase naming what the album is about
+	Subjects  []string `db:"subjects"` // what most photos show, most common first
+	Places    []string `db:"places"`   // where the photos were taken, most photos first
+	DateStart string   `db:"date_start"`
+	DateEnd   string   `db:"date_end"`
+}
+
+const (
+	AlbumProposalPending  = "pending"
+	AlbumProposalApproved = "approved"
+	AlbumProposalRejected = "rejected"
+)
+
 type PhotoAlbum struct {
 	AlbumID string `db:"album_id"`
 	PhotoID string `db:"photo_id"`
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+// ErrProposalReviewed is returned when approving or rejecting a proposal that isn't pending
+var ErrProposalReviewed = errors.New("album proposal was already reviewed")
+
+// SaveAlbumProposal stores a new album proposal and the photos it covers
+func (db *DB) SaveAlbumProposal(p *AlbumProposal) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	proposalQuery := db.upsertQuery("_ai_album_proposals",
+		[]string{"id", "title", "description", "status", "created_at"},
+		[]string{"id"})
+	if _, err := tx.Exec(proposalQuery, p.ID, p.Title, p.Description, p.Status, p.CreatedAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.rebind(`DELETE FROM _ai_album_proposal_photos WHERE proposal_id = ?`), p.ID); err != nil {
+		return err
+	}
+	insert := db.rebind(`INSERT INTO _ai_album_proposal_photos (proposal_id, photo_id) VALUES (?, ?)`)
+	for _, photoID := range p.PhotoIDs {
+		if _, err := tx.Exec(insert, p.ID, photoID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAlbumProposal returns a stored album proposal with its photos, or sql.ErrNoRows
+func (db *DB) GetAlbumProposal(id string) (*AlbumProposal, error) {
+	var p AlbumProposal
+	err := db.conn.QueryRow(db.rebind(`
+		SELECT id, title, description, status, album_id, created_at, reviewed_at
+		FROM _ai_album_proposals
+		WHERE id = ?`), id).Scan(&p.ID, &p.Title, &p.Description, &p.Status, &p.AlbumID, &p.CreatedAt, &p.ReviewedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(db.rebind(`SELECT photo_id FROM _ai_album_proposal_photos WHERE proposal_id = ?`), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var photoID string
+		if err := rows.Scan(&photoID); err != nil {
+			return nil, err
+		}
+		p.PhotoIDs = append(p.PhotoIDs, photoID)
+	}
+
+	return &p, rows.Err()
+}
+
+// CreateAlbumFromProposal creates a top-level Lychee album, moves the given photos into it and
+// marks the proposal approved, all in one transaction. The album is appended as the last root
+// of the albums nested set. Returns the new album's ID, or ErrProposalReviewed if the proposal
+// isn't pending, e.g. because it was approved twice at once.
+func (db *DB) CreateAlbumFromProposal(proposalID, title, description string, ownerID int, photoIDs []string) (string, error) {
+	albumID, err := newAlbumID()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Claim the proposal first; a concurrent approval waits on its row and then finds it approved
+	now := time.Now()
+	result, err := tx.Exec(db.rebind(`
+		UPDATE _ai_album_proposals SET status = ?, album_id = ?, reviewed_at = ? WHERE id = ? AND status = ?`),
+		AlbumProposalApproved, albumID, now, proposalID, AlbumProposalPending)
+	if err != nil {
+		return "", err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return "", err
+	} else if n == 0 {
+		return "", ErrProposalReviewed
+	}
+
+	_, err = tx.Exec(db.rebind(`
+		INSERT INTO base_albums (id, created_at, updated_at, title, description, owner_id, is_nsfw, is_pinned,
+		                         _ai_description, _ai_description_ts)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		albumID, now, now, title, description, ownerID, false, false, description, now)
+	if err != nil {
+		return "", err
+	}
+
+	lastRgt, err := db.lockNestedSet(tx)
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(db.rebind(`
+		INSERT INTO albums (id, parent_id, license, _lft, _rgt)
+		VALUES (?, NULL, 'none', ?, ?)`),
+		albumID, lastRgt+1, lastRgt+2)
+	if err != nil {
+		return "", err
+	}
+
+	for _, photoID := range photoIDs {
+		if err := db.movePhoto(tx, photoID, albumID); err != nil {
+			return "", err
+		}
+	}
+
+	return albumID, tx.Commit()
+}
+
+// lockNestedSet keeps other transactions from changing the albums nested set until tx ends,
+// and returns its largest _rgt. SQLite needs no lock, as it runs one writing transaction at a
+// time and tx has already written.
+func (db *DB) lockNestedSet(tx *sql.Tx) (int64, error) {
+	query := `SELECT COALESCE(MAX(_rgt), 0) FROM albums`
+	switch db.dbType {
+	case config.TypeMySQL:
+		query += ` FOR UPDATE`
+	case config.TypePostgreSQL:
+		// FOR UPDATE can't be combined with MAX, and wouldn't stop new rows from being inserted
+		if _, err := tx.Exec(`LOCK TABLE albums IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return 0, err
+		}
+	}
+
+	var lastRgt int64
+	err := tx.QueryRow(query).Scan(&lastRgt)
+	return lastRgt, err
+}
+
+// RejectAlbumProposal marks a pending proposal rejected, so it isn't offered again
+func (db *DB) RejectAlbumProposal(id string) error {
+	result, err := db.conn.Exec(db.rebind(`
+		UPDATE _ai_album_proposals SET status = ?, reviewed_at = ? WHERE id = ? AND status = ?`),
+		AlbumProposalRejected, time.Now(), id, AlbumProposalPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrProposalReviewed
+	}
+	return nil
+}
+
+// newAlbumID generates a random 24 character ID in the style Lychee uses
+func newAlbumID() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
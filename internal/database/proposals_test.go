@@ -0,0 +1,77 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func saveTestProposal(t *testing.T, db *DB, id string, photoIDs ...string) {
+	t.Helper()
+	err := db.SaveAlbumProposal(&AlbumProposal{
+		ID: id, Title: "Proposal " + id, Description: "Photos of " + id,
+		Status: AlbumProposalPending, CreatedAt: time.Now(), PhotoIDs: photoIDs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateAlbumFromProposal(t *testing.T) {
+	db := newTestDB(t)
+	exec(t, db,
+		`INSERT INTO albums (id, parent_id, license, _lft, _rgt) VALUES ('root', NULL, 'none', 1, 4), ('child', 'root', 'none', 2, 3)`,
+		`INSERT INTO photos (id, title) VALUES ('p1', 'a'), ('p2', 'b'), ('p3', 'c')`,
+	)
+	saveTestProposal(t, db, "c1", "p1", "p2")
+	saveTestProposal(t, db, "c2", "p3")
+
+	first, err := db.CreateAlbumFromProposal("c1", "Lisbon", "A trip", 1, []string{"p1", "p2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Approving again, e.g. after a double click, must not create a second album
+	if _, err := db.CreateAlbumFromProposal("c1", "Lisbon", "A trip", 1, []string{"p1", "p2"}); !errors.Is(err, ErrProposalReviewed) {
+		t.Errorf("second approval: error = %v, want ErrProposalReviewed", err)
+	}
+	if err := db.RejectAlbumProposal("c2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RejectAlbumProposal("c2"); err != ErrProposalReviewed {
+		t.Errorf("rejecting twice: error = %v, want ErrProposalReviewed", err)
+	}
+	if err := db.RejectAlbumProposal("c1"); err != ErrProposalReviewed {
+		t.Errorf("rejecting an approved proposal: error = %v, want ErrProposalReviewed", err)
+	}
+	if _, err := db.CreateAlbumFromProposal("c2", "Porto", "", 1, []string{"p3"}); !errors.Is(err, ErrProposalReviewed) {
+		t.Errorf("approving a rejected proposal: error = %v, want ErrProposalReviewed", err)
+	}
+
+	var albums, moved int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM base_albums`).Scan(&albums); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM photo_album WHERE album_id = ?`, first).Scan(&moved); err != nil {
+		t.Fatal(err)
+	}
+	if albums != 1 || moved != 2 {
+		t.Errorf("got %d albums and %d photos moved, want 1 album with 2 photos", albums, moved)
+	}
+
+	var lft, rgt int
+	if err := db.conn.QueryRow(`SELECT _lft, _rgt FROM albums WHERE id = ?`, first).Scan(&lft, &rgt); err != nil {
+		t.Fatal(err)
+	}
+	if lft != 5 || rgt != 6 {
+		t.Errorf("new album at _lft %d, _rgt %d; want 5 and 6, after the existing tree", lft, rgt)
+	}
+
+	proposal, err := db.GetAlbumProposal("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proposal.Status != AlbumProposalApproved || proposal.AlbumID.String != first {
+		t.Errorf("proposal is %s with album %q, want approved with %q", proposal.Status, proposal.AlbumID.String, first)
+	}
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SaveProvenance records how a text was made, replacing the record of the text it replaces
+func (db *DB) SaveProvenance(p *Provenance) error {
+	query := db.upsertQuery("_ai_provenance",
+		[]string{"kind", "item_id", "model", "digest", "prompt_hash", "options", "duration_ms", "created_at"},
+		[]string{"kind", "item_id"})
+
+	_, err := db.conn.Exec(query, p.Kind, p.ItemID, p.Model, p.Digest, p.PromptHash, p.Options, p.DurationMs, p.CreatedAt)
+	return err
+}
+
+// GetProvenance returns the records of the texts made for a photo or album, including its
+// translations
+func (db *DB) GetProvenance(itemID string) ([]Provenance, error) {
+	query := `
+		SELECT kind, item_id, model, digest, prompt_hash, options, duration_ms, created_at
+		FROM _ai_provenance
+		WHERE item_id = ? OR item_id LIKE ?
+		ORDER BY kind, item_id`
+
+	rows, err := db.conn.Query(db.rebind(query), itemID, itemID+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Provenance
+	for rows.Next() {
+		var p Provenance
+		if err := rows.Scan(&p.Kind, &p.ItemID, &p.Model, &p.Digest, &p.PromptHash, &p.Options, &p.DurationMs, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, p)
+	}
+	return records, rows.Err()
+}
+
+// GetProvenanceSummary counts the recorded texts by kind, model, digest and prompt
+func (db *DB) GetProvenanceSummary() ([]ProvenanceCount, error) {
+	query := `
+		SELECT kind, model, digest, prompt_hash, COUNT(*)
+		FROM _ai_provenance
+		GROUP BY kind, model, digest, prompt_hash
+		ORDER BY kind, model, digest, prompt_hash`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []ProvenanceCount
+	for rows.Next() {
+		var c ProvenanceCount
+		if err := rows.Scan(&c.Kind, &c.Model, &c.Digest, &c.PromptHash, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// GetDescribedByProvenance returns the IDs of the described photos or albums (itemType is
+// TranslationPhoto or TranslationAlbum) whose description was made as the filter says. kinds
+// are the prompts whose records count for a description. Blocked albums and their photos are
+// left out.
+func (db *DB) GetDescribedByProvenance(itemType string, kinds []string, filter ProvenanceFilter) ([]string, error) {
+	if filter.Empty() || len(kinds) == 0 {
+		return nil, nil
+	}
+
+	table, alias := "photos", "p"
+	if itemType == TranslationAlbum {
+		table, alias = "base_albums", "ba"
+	}
+
+	kindPlaceholders := strings.TrimSuffix(strings.Repeat("?,", len(kinds)), ",")
+	records := fmt.Sprintf(`SELECT 1 FROM _ai_provenance pr WHERE pr.item_id = %s.id AND pr.kind IN (%s)`, alias, kindPlaceholders)
+	kindArgs := make([]interface{}, len(kinds))
+	for i, kind := range kinds {
+		kindArgs[i] = kind
+	}
+
+	var conditions []string
+	var args []interface{}
+	if filter.Model != "" || filter.Digest != "" || filter.PromptHash != "" {
+		matched := records
+		args = append(args, kindArgs...)
+		for _, field := range []struct {
+			column string
+			value  string
+		}{{"model", filter.Model}, {"digest", filter.Digest}, {"prompt_hash", filter.PromptHash}} {
+			if field.value != "" {
+				matched += " AND pr." + field.column + " = ?"
+				args = append(args, field.value)
+			}
+		}
+		conditions = append(conditions, "EXISTS ("+matched+")")
+	}
+	if filter.Untracked {
+		conditions = append(conditions, "NOT EXISTS ("+records+")")
+		args = append(args, kindArgs...)
+	}
+
+	blocklistCondition := ""
+	if len(db.blocklist) > 0 {
+		placeholders := make([]string, 0, len(db.blocklist))
+		for albumID := range db.blocklist {
+			placeholders = append(placeholders, "?")
+			args = append(args, albumID)
+		}
+		if itemType == TranslationAlbum {
+			blocklistCondition = fmt.Sprintf(" AND ba.id NOT IN (%s)", strings.Join(placeholders, ","))
+		} else {
+			blocklistCondition = fmt.Sprintf(" AND p.id NOT IN (SELECT photo_id FROM photo_album WHERE album_id IN (%s))", strings.Join(placeholders, ","))
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT %[1]s.id FROM %[2]s %[1]s WHERE %[1]s._ai_description IS NOT NULL AND (%[3]s)%[4]s`,
+		alias, table, strings.Join(conditions, " OR "), blocklistCondition)
+
+	rows, err := db.conn.Query(db.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,40 @@
+package database
+
+import "time"
+
+// SaveReasoning stores the reasoning behind one piece of output, replacing any earlier one
+func (db *DB) SaveReasoning(kind, itemID, model, reasoning string) error {
+	query := db.upsertQuery("_ai_reasoning",
+		[]string{"kind", "item_id", "model", "reasoning", "created_at"},
+		[]string{"kind", "item_id"})
+
+	_, err := db.conn.Exec(query, kind, itemID, model, reasoning, time.Now())
+	return err
+}
+
+// GetReasoning returns the stored reasoning about an item, optionally only of one kind
+func (db *DB) GetReasoning(itemID, kind string) ([]Reasoning, error) {
+	query := `SELECT kind, item_id, model, reasoning, created_at FROM _ai_reasoning WHERE item_id = ?`
+	args := []interface{}{itemID}
+	if kind != "" {
+		query += ` AND kind = ?`
+		args = append(args, kind)
+	}
+	query += ` ORDER BY kind`
+
+	rows, err := db.conn.Query(db.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reasoning []Reasoning
+	for rows.Next() {
+		var r Reasoning
+		if err := rows.Scan(&r.Kind, &r.ItemID, &r.Model, &r.Reasoning, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		reasoning = append(reasoning, r)
+	}
+	return reasoning, rows.Err()
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+// EnsureSchema creates the organizer's own bookkeeping tables if they don't exist yet.
+// These tables are all prefixed with _ai_ and are never read by Lychee itself; the
+// _ai_description columns on Lychee's tables must still be added by the user (see README).
+func (db *DB) EnsureSchema() error {
+	ts := db.timestampType()
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS _ai_title_suggestions (
+			photo_id VARCHAR(24) NOT NULL,
+			original_title VARCHAR(100) NOT NULL,
+			suggested_title VARCHAR(100) NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			reviewed_at ` + ts + ` NULL,
+			PRIMARY KEY (photo_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_album_suggestions (
+			photo_id VARCHAR(24) NOT NULL,
+			album_id VARCHAR(24) NOT NULL,
+			suggestion_rank INTEGER NOT NULL,
+			confidence DOUBLE PRECISION NOT NULL,
+			reason VARCHAR(255) NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (photo_id, album_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_suggestion_contributions (
+			photo_id VARCHAR(24) NOT NULL,
+			album_id VARCHAR(24) NOT NULL,
+			suggester VARCHAR(32) NOT NULL,
+			confidence DOUBLE PRECISION NOT NULL,
+			weight DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (photo_id, album_id, suggester)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_suggestion_sets (
+			photo_id VARCHAR(24) NOT NULL,
+			album_set_version VARCHAR(64) NOT NULL,
+			computed_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (photo_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_album_proposals (
+			id VARCHAR(32) NOT NULL,
+			title VARCHAR(100) NOT NULL,
+			description TEXT NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			album_id VARCHAR(24) NULL,
+			created_at ` + ts + ` NOT NULL,
+			reviewed_at ` + ts + ` NULL,
+			PRIMARY KEY (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_album_proposal_photos (
+			proposal_id VARCHAR(32) NOT NULL,
+			photo_id VARCHAR(24) NOT NULL,
+			PRIMARY KEY (proposal_id, photo_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_compaction_cache (
+			input_hash VARCHAR(64) NOT NULL,
+			model VARCHAR(191) NOT NULL,
+			summary TEXT NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (input_hash)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_reasoning (
+			kind VARCHAR(32) NOT NULL,
+			item_id VARCHAR(64) NOT NULL,
+			model VARCHAR(191) NOT NULL,
+			reasoning TEXT NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (kind, item_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_accepted_suggestions (
+			photo_id VARCHAR(24) NOT NULL,
+			album_id VARCHAR(24) NOT NULL,
+			reason VARCHAR(255) NOT NULL,
+			accepted_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (photo_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_validation_failures (
+			kind VARCHAR(32) NOT NULL,
+			item_id VARCHAR(64) NOT NULL,
+			model VARCHAR(191) NOT NULL,
+			reason VARCHAR(255) NOT NULL,
+			response TEXT NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (kind, item_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_translations (
+			item_type VARCHAR(16) NOT NULL,
+			item_id VARCHAR(24) NOT NULL,
+			language VARCHAR(16) NOT NULL,
+			description TEXT NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (item_type, item_id, language)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_album_facets (
+			album_id VARCHAR(24) NOT NULL,
+			theme TEXT NOT NULL,
+			subjects TEXT NOT NULL,
+			places TEXT NOT NULL,
+			date_start VARCHAR(10) NOT NULL,
+			date_end VARCHAR(10) NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (album_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS _ai_provenance (
+			kind VARCHAR(32) NOT NULL,
+			item_id VARCHAR(64) NOT NULL,
+			model VARCHAR(191) NOT NULL,
+			digest VARCHAR(80) NOT NULL,
+			prompt_hash VARCHAR(64) NOT NULL,
+			options TEXT NOT NULL,
+			duration_ms BIGINT NOT NULL,
+			created_at ` + ts + ` NOT NULL,
+			PRIMARY KEY (kind, item_id)
+		)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.conn.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create organizer tables: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// timestampType returns the column type used for timestamps in organizer tables
+func (db *DB) timestampType() string {
+	switch db.dbType {
+	case config.TypeMySQL:
+		return "DATETIME(6)"
+	case config.TypePostgreSQL:
+		return "TIMESTAMP"
+	default:
+		return "DATETIME"
+	}
+}
+
+// rebind rewrites ? placeholders to $1, $2, ... for PostgreSQL
+func (db *DB) rebind(query string) string {
+	if db.dbType != config.TypePostgreSQL {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// upsertQuery builds an INSERT that updates the non-key columns when a row with the same key exists
+func (db *DB) upsertQuery(table string, columns []string, keyColumns []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = "?"
+	}
+
+	isKey := make(map[string]bool)
+	for _, col := range keyColumns {
+		isKey[col] = true
+	}
+
+	var updates []string
+	for _, col := range columns {
+		if isKey[col] {
+			continue
+		}
+		switch db.dbType {
+		case config.TypeMySQL:
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", col, col))
+		default:
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", col, col))
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	switch {
+	case len(updates) == 0 && db.dbType == config.TypeMySQL:
+		query += fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", keyColumns[0], keyColumns[0])
+	case len(updates) == 0:
+		query += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(keyColumns, ", "))
+	case db.dbType == config.TypeMySQL:
+		query += " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	default:
+		query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keyColumns, ", "), strings.Join(updates, ", "))
+	}
+
+	return db.rebind(query)
+}
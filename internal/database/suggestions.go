@@ -0,0 +1,295 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SaveAlbumSuggestions replaces the stored suggestions for a photo. albumSetVersion identifies
+// the set of album descriptions the suggestions were computed against.
+func (db *DB) SaveAlbumSuggestions(photoID, albumSetVersion string, suggestions []AlbumSuggestion) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(db.rebind(`DELETE FROM _ai_album_suggestions WHERE photo_id = ?`), photoID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(db.rebind(`DELETE FROM _ai_suggestion_contributions WHERE photo_id = ?`), photoID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	insert := db.rebind(`
+		INSERT INTO _ai_album_suggestions (photo_id, album_id, suggestion_rank, confidence, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	insertContribution := db.rebind(`
+		INSERT INTO _ai_suggestion_contributions (photo_id, album_id, suggester, confidence, weight)
+		VALUES (?, ?, ?, ?, ?)`)
+	for _, s := range suggestions {
+		if _, err := tx.Exec(insert, photoID, s.AlbumID, s.Rank, s.Confidence, s.Reason, now); err != nil {
+			return err
+		}
+		for _, c := range s.Contributions {
+			if _, err := tx.Exec(insertContribution, photoID, s.AlbumID, c.Suggester, c.Confidence, c.Weight); err != nil {
+				return err
+			}
+		}
+	}
+
+	setQuery := db.upsertQuery("_ai_suggestion_sets", []string{"photo_id", "album_set_version", "computed_at"}, []string{"photo_id"})
+	if _, err := tx.Exec(setQuery, photoID, albumSetVersion, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAlbumSuggestions returns the stored suggestions for a photo, best first. ok is false if no
+// suggestions were computed against the given album set version.
+func (db *DB) GetAlbumSuggestions(photoID, albumSetVersion string) (suggestions []AlbumSuggestion, ok bool, err error) {
+	var version string
+	err = db.conn.QueryRow(db.rebind(`SELECT album_set_version FROM _ai_suggestion_sets WHERE photo_id = ?`), photoID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	if version != albumSetVersion {
+		return nil, false, nil
+	}
+
+	query := `
+		SELECT photo_id, album_id, suggestion_rank, confidence, reason, created_at
+		FROM _ai_album_suggestions
+		WHERE photo_id = ?
+		ORDER BY suggestion_rank ASC`
+
+	rows, err := db.conn.Query(db.rebind(query), photoID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s AlbumSuggestion
+		if err := rows.Scan(&s.PhotoID, &s.AlbumID, &s.Rank, &s.Confidence, &s.Reason, &s.CreatedAt); err != nil {
+			return nil, false, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	contributions, err := db.getSuggestionContributions(photoID)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range suggestions {
+		suggestions[i].Contributions = contributions[suggestions[i].AlbumID]
+	}
+
+	return suggestions, true, nil
+}
+
+// getSuggestionContributions returns the stored contributions for a photo's suggestions, by album ID
+func (db *DB) getSuggestionContributions(photoID string) (map[string][]SuggestionContribution, error) {
+	query := `
+		SELECT album_id, suggester, confidence, weight
+		FROM _ai_suggestion_contributions
+		WHERE photo_id = ?
+		ORDER BY album_id, weight * confidence DESC`
+
+	rows, err := db.conn.Query(db.rebind(query), photoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contributions := make(map[string][]SuggestionContribution)
+	for rows.Next() {
+		var albumID string
+		var c SuggestionContribution
+		if err := rows.Scan(&albumID, &c.Suggester, &c.Confidence, &c.Weight); err != nil {
+			return nil, err
+		}
+		contributions[albumID] = append(contributions[albumID], c)
+	}
+
+	return contributions, rows.Err()
+}
+
+// GetCurrentAlbumSuggestions returns all suggestions stored for the given album set version, by
+// photo ID and best first. Contributions are not loaded.
+func (db *DB) GetCurrentAlbumSuggestions(albumSetVersion string) (map[string][]AlbumSuggestion, error) {
+	query := `
+		SELECT s.photo_id, s.album_id, s.suggestion_rank, s.confidence, s.reason, s.created_at
+		FROM _ai_album_suggestions s
+		INNER JOIN _ai_suggestion_sets ss ON s.photo_id = ss.photo_id
+		WHERE ss.album_set_version = ?
+		ORDER BY s.photo_id, s.suggestion_rank ASC`
+
+	rows, err := db.conn.Query(db.rebind(query), albumSetVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make(map[string][]AlbumSuggestion)
+	for rows.Next() {
+		var s AlbumSuggestion
+		if err := rows.Scan(&s.PhotoID, &s.AlbumID, &s.Rank, &s.Confidence, &s.Reason, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		suggestions[s.PhotoID] = append(suggestions[s.PhotoID], s)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// DeleteAlbumSuggestions removes the stored suggestions for a photo, e.g. once it has been sorted
+func (db *DB) DeleteAlbumSuggestions(photoID string) error {
+	if _, err := db.conn.Exec(db.rebind(`DELETE FROM _ai_album_suggestions WHERE photo_id = ?`), photoID); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec(db.rebind(`DELETE FROM _ai_suggestion_contributions WHERE photo_id = ?`), photoID); err != nil {
+		return err
+	}
+	_, err := db.conn.Exec(db.rebind(`DELETE FROM _ai_suggestion_sets WHERE photo_id = ?`), photoID)
+	return err
+}
+
+// DeleteStaleAlbumSuggestions removes suggestions computed against any album set version other
+// than the current one, and returns how many photos were affected
+func (db *DB) DeleteStaleAlbumSuggestions(albumSetVersion string) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(db.rebind(`
+		DELETE FROM _ai_album_suggestions
+		WHERE photo_id NOT IN (SELECT photo_id FROM _ai_suggestion_sets WHERE album_set_version = ?)`), albumSetVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(db.rebind(`
+		DELETE FROM _ai_suggestion_contributions
+		WHERE photo_id NOT IN (SELECT photo_id FROM _ai_suggestion_sets WHERE album_set_version = ?)`), albumSetVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(db.rebind(`DELETE FROM _ai_suggestion_sets WHERE album_set_version <> ?`), albumSetVersion)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+
+	return n, tx.Commit()
+}
+
+// GetLowConfidencePhotoIDs returns the unsorted photos whose best current suggestion has a
+// confidence below the threshold. Photos without current suggestions are not included.
+func (db *DB) GetLowConfidencePhotoIDs(threshold float64, albumSetVersion string) (map[string]bool, error) {
+	query := `
+		SELECT s.photo_id
+		FROM _ai_album_suggestions s
+		INNER JOIN _ai_suggestion_sets ss ON s.photo_id = ss.photo_id
+		WHERE ss.album_set_version = ? AND s.photo_id NOT IN (SELECT photo_id FROM photo_album)
+		GROUP BY s.photo_id
+		HAVING MAX(s.confidence) < ?`
+
+	rows, err := db.conn.Query(db.rebind(query), albumSetVersion, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	photoIDs := make(map[string]bool)
+	for rows.Next() {
+		var photoID string
+		if err := rows.Scan(&photoID); err != nil {
+			return nil, err
+		}
+		photoIDs[photoID] = true
+	}
+
+	return photoIDs, rows.Err()
+}
+
+// GetAlbumPhotoMetadata returns the date, location and camera of every photo in an album
+func (db *DB) GetAlbumPhotoMetadata() ([]AlbumPhotoMetadata, error) {
+	query := `
+		SELECT pa.album_id, p.id, p.taken_at, p.taken_at_orig_tz, p.latitude, p.longitude, p.make, p.model
+		FROM photo_album pa
+		INNER JOIN photos p ON p.id = pa.photo_id`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metadata []AlbumPhotoMetadata
+	for rows.Next() {
+		var m AlbumPhotoMetadata
+		if err := rows.Scan(&m.AlbumID, &m.PhotoID, &m.TakenAt, &m.TakenAtOrigTz, &m.Latitude, &m.Longitude, &m.Make, &m.Model); err != nil {
+			return nil, err
+		}
+		metadata = append(metadata, m)
+	}
+
+	return metadata, rows.Err()
+}
+
+// RecordAcceptedSuggestion remembers that a photo was moved into an album that was suggested
+// for it. Moves into albums that weren't suggested are ignored.
+func (db *DB) RecordAcceptedSuggestion(photoID, albumID string) error {
+	var reason string
+	err := db.conn.QueryRow(db.rebind(`
+		SELECT reason FROM _ai_album_suggestions WHERE photo_id = ? AND album_id = ?`), photoID, albumID).Scan(&reason)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	query := db.upsertQuery("_ai_accepted_suggestions",
+		[]string{"photo_id", "album_id", "reason", "accepted_at"},
+		[]string{"photo_id"})
+	_, err = db.conn.Exec(query, photoID, albumID, reason, time.Now())
+	return err
+}
+
+// GetAcceptedSuggestions returns up to limit of the most recently accepted suggestions whose
+// photo has an AI description and is still in the album, newest first
+func (db *DB) GetAcceptedSuggestions(limit int) ([]AcceptedSuggestion, error) {
+	rows, err := db.conn.Query(db.rebind(`
+		SELECT a.photo_id, a.album_id, a.reason, a.accepted_at, p._ai_description
+		FROM _ai_accepted_suggestions a
+		INNER JOIN photos p ON p.id = a.photo_id
+		INNER JOIN photo_album pa ON pa.photo_id = a.photo_id AND pa.album_id = a.album_id
+		WHERE p._ai_description IS NOT NULL AND p._ai_description != ''
+		ORDER BY a.accepted_at DESC
+		LIMIT ?`), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accepted []AcceptedSuggestion
+	for rows.Next() {
+		var a AcceptedSuggestion
+		if err := rows.Scan(&a.PhotoID, &a.AlbumID, &a.Reason, &a.AcceptedAt, &a.Description); err != nil {
+			return nil, err
+		}
+		accepted = append(accepted, a)
+	}
+	return accepted, rows.Err()
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoTitleSuggestion is returned when approving a title for a photo without a suggestion
+	ErrNoTitleSuggestion = errors.New("no title suggestion for this photo")
+	// ErrTitleSuggestionReviewed is returned when approving a suggestion that isn't pending anymore
+	ErrTitleSuggestionReviewed = errors.New("title suggestion was already reviewed")
+	// ErrPhotoRenamed is returned when approving a suggestion for a photo renamed since it was made
+	ErrPhotoRenamed = errors.New("photo was renamed since the suggestion was made")
+)
+
+// GetPhotosWithoutTitleSuggestion returns photos that have never had a title suggestion generated.
+// Callers filter the result by camera filename pattern, since those are regular expressions.
+func (db *DB) GetPhotosWithoutTitleSuggestion() ([]Photo, error) {
+	blocklistCondition := ""
+	var blocklistArgs []interface{}
+
+	if len(db.blocklist) > 0 {
+		placeholders := make([]string, 0, len(db.blocklist))
+		for albumID := range db.blocklist {
+			placeholders = append(placeholders, "?")
+			blocklistArgs = append(blocklistArgs, albumID)
+		}
+		blocklistCondition = fmt.Sprintf(" AND id NOT IN (SELECT photo_id FROM photo_album WHERE album_id IN (%s))", strings.Join(placeholders, ","))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM photos
+		WHERE id NOT IN (SELECT photo_id FROM _ai_title_suggestions)%s
+		ORDER BY taken_at DESC, created_at DESC`, photoSelectColumns(), blocklistCondition)
+
+	rows, err := db.conn.Query(db.rebind(query), blocklistArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var photos []Photo
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		photos = append(photos, *photo)
+	}
+
+	return photos, rows.Err()
+}
+
+// SaveTitleSuggestion stores a pending title suggestion, replacing any earlier one for the photo
+func (db *DB) SaveTitleSuggestion(photoID, originalTitle, suggestedTitle string) error {
+	query := db.upsertQuery("_ai_title_suggestions",
+		[]string{"photo_id", "original_title", "suggested_title", "status", "created_at", "reviewed_at"},
+		[]string{"photo_id"})
+
+	_, err := db.conn.Exec(query, photoID, originalTitle, suggestedTitle, TitleSuggestionPending, time.Now(), nil)
+	return err
+}
+
+// GetPendingTitleSuggestions returns the title review queue, oldest first
+func (db *DB) GetPendingTitleSuggestions() ([]TitleSuggestion, error) {
+	query := `
+		SELECT photo_id, original_title, suggested_title, status, created_at, reviewed_at
+		FROM _ai_title_suggestions
+		WHERE status = ?
+		ORDER BY created_at ASC`
+
+	rows, err := db.conn.Query(db.rebind(query), TitleSuggestionPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []TitleSuggestion
+	for rows.Next() {
+		var s TitleSuggestion
+		if err := rows.Scan(&s.PhotoID, &s.OriginalTitle, &s.SuggestedTitle, &s.Status, &s.CreatedAt, &s.ReviewedAt); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// ApproveTitleSuggestion writes the approved title to photos.title and closes the suggestion.
+// The photo is only renamed if its title still matches the one the suggestion was made for.
+func (db *DB) ApproveTitleSuggestion(photoID, title string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var originalTitle, status string
+	row := tx.QueryRow(db.rebind(`SELECT original_title, status FROM _ai_title_suggestions WHERE photo_id = ?`), photoID)
+	if err := row.Scan(&originalTitle, &status); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoTitleSuggestion
+		}
+		return err
+	}
+	if status != TitleSuggestionPending {
+		return ErrTitleSuggestionReviewed
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(db.rebind(`UPDATE photos SET title = ?, updated_at = ? WHERE id = ? AND title = ?`), title, now, photoID, originalTitle)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPhotoRenamed
+	}
+
+	_, err = tx.Exec(db.rebind(`UPDATE _ai_title_suggestions SET suggested_title = ?, status = ?, reviewed_at = ? WHERE photo_id = ?`),
+		title, TitleSuggestionApproved, now, photoID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RejectTitleSuggestion closes a suggestion without touching the photo
+func (db *DB) RejectTitleSuggestion(photoID string) error {
+	_, err := db.conn.Exec(db.rebind(`UPDATE _ai_title_suggestions SET status = ?, reviewed_at = ? WHERE photo_id = ? AND status = ?`),
+		TitleSuggestionRejected, time.Now(), photoID, TitleSuggestionPending)
+	return err
+}
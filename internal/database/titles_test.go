@@ -0,0 +1,47 @@
+package database
+
+import "testing"
+
+func TestApproveTitleSuggestion(t *testing.T) {
+	db := newTestDB(t)
+	exec(t, db, `INSERT INTO photos (id, title) VALUES ('p1', 'IMG_0001'), ('p2', 'IMG_0002'), ('p3', 'IMG_0003')`)
+	for _, id := range []string{"p1", "p2", "p3"} {
+		var title string
+		if err := db.conn.QueryRow(`SELECT title FROM photos WHERE id = ?`, id).Scan(&title); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SaveTitleSuggestion(id, title, "Harbour at Dawn"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec(t, db, `UPDATE photos SET title = 'Renamed' WHERE id = 'p2'`)
+	if err := db.RejectTitleSuggestion("p3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.ApproveTitleSuggestion("p1", "Harbour at Dawn"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		photoID string
+		want    error
+	}{
+		{"p1", ErrTitleSuggestionReviewed},
+		{"p2", ErrPhotoRenamed},
+		{"p3", ErrTitleSuggestionReviewed},
+		{"missing", ErrNoTitleSuggestion},
+	}
+	for _, tt := range tests {
+		if err := db.ApproveTitleSuggestion(tt.photoID, "Harbour"); err != tt.want {
+			t.Errorf("approving %s: error = %v, want %v", tt.photoID, err, tt.want)
+		}
+	}
+
+	var title string
+	if err := db.conn.QueryRow(`SELECT title FROM photos WHERE id = 'p1'`).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if title != "Harbour at Dawn" {
+		t.Errorf("title = %q, want the approved one", title)
+	}
+}
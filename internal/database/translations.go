@@ -0,0 +1,103 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SaveTranslation stores the description of a photo or album in another language, replacing any
+// earlier translation into that language
+func (db *DB) SaveTranslation(itemType, itemID, language, description string) error {
+	query := db.upsertQuery("_ai_translations",
+		[]string{"item_type", "item_id", "language", "description", "created_at"},
+		[]string{"item_type", "item_id", "language"})
+
+	_, err := db.conn.Exec(query, itemType, itemID, language, description, time.Now())
+	return err
+}
+
+// GetTranslations returns the descriptions of one type of item in a language, by item ID
+func (db *DB) GetTranslations(itemType, language string) (map[string]string, error) {
+	query := `SELECT item_id, description FROM _ai_translations WHERE item_type = ? AND language = ?`
+
+	rows, err := db.conn.Query(db.rebind(query), itemType, language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := make(map[string]string)
+	for rows.Next() {
+		var itemID, description string
+		if err := rows.Scan(&itemID, &description); err != nil {
+			return nil, err
+		}
+		translations[itemID] = description
+	}
+	return translations, rows.Err()
+}
+
+// GetUntranslated returns the described photos and albums that have no translation into a
+// language, or only one older than their description. Blocked albums and their photos are left out.
+func (db *DB) GetUntranslated(language string) ([]Untranslated, error) {
+	// A translation is up to date if it was made after the description
+	upToDate := `SELECT 1 FROM _ai_translations t
+		WHERE t.item_type = ? AND t.item_id = %[1]s AND t.language = ?
+		AND (%[2]s IS NULL OR t.created_at >= %[2]s)`
+
+	photoArgs := []interface{}{TranslationPhoto, language}
+	photoBlocklist := ""
+	if len(db.blocklist) > 0 {
+		placeholders := make([]string, 0, len(db.blocklist))
+		for albumID := range db.blocklist {
+			placeholders = append(placeholders, "?")
+			photoArgs = append(photoArgs, albumID)
+		}
+		photoBlocklist = fmt.Sprintf(" AND p.id NOT IN (SELECT photo_id FROM photo_album WHERE album_id IN (%s))", strings.Join(placeholders, ","))
+	}
+	photoQuery := `
+		SELECT p.id, p.title, p._ai_description
+		FROM photos p
+		WHERE p._ai_description IS NOT NULL
+		AND NOT EXISTS (` + fmt.Sprintf(upToDate, "p.id", "p._ai_description_ts") + `)` + photoBlocklist + `
+		ORDER BY p.taken_at DESC, p.created_at DESC`
+
+	albumBlocklist, blocklistArgs := db.buildBlocklistCondition()
+	albumArgs := append([]interface{}{TranslationAlbum, language}, blocklistArgs...)
+	albumQuery := `
+		SELECT ba.id, ba.title, ba._ai_description
+		FROM base_albums ba
+		WHERE ba._ai_description IS NOT NULL
+		AND NOT EXISTS (` + fmt.Sprintf(upToDate, "ba.id", "ba._ai_description_ts") + `)` + albumBlocklist + `
+		ORDER BY ba.title`
+
+	var items []Untranslated
+	for _, q := range []struct {
+		itemType string
+		query    string
+		args     []interface{}
+	}{
+		{TranslationPhoto, photoQuery, photoArgs},
+		{TranslationAlbum, albumQuery, albumArgs},
+	} {
+		rows, err := db.conn.Query(db.rebind(q.query), q.args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			item := Untranslated{ItemType: q.itemType}
+			if err := rows.Scan(&item.ItemID, &item.Title, &item.Description); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
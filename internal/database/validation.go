@@ -0,0 +1,49 @@
+package database
+
+import "time"
+
+// maxFailureReasonLength fits _ai_validation_failures.reason
+const maxFailureReasonLength = 255
+
+// SaveValidationFailure records why the answer about an item failed validation, replacing any
+// earlier failure
+func (db *DB) SaveValidationFailure(kind, itemID, model, reason, response string) error {
+	if runes := []rune(reason); len(runes) > maxFailureReasonLength {
+		reason = string(runes[:maxFailureReasonLength])
+	}
+
+	query := db.upsertQuery("_ai_validation_failures",
+		[]string{"kind", "item_id", "model", "reason", "response", "created_at"},
+		[]string{"kind", "item_id"})
+
+	_, err := db.conn.Exec(query, kind, itemID, model, reason, response, time.Now())
+	return err
+}
+
+// DeleteValidationFailure removes the recorded failure of an item, if any
+func (db *DB) DeleteValidationFailure(kind, itemID string) error {
+	_, err := db.conn.Exec(db.rebind(`DELETE FROM _ai_validation_failures WHERE kind = ? AND item_id = ?`), kind, itemID)
+	return err
+}
+
+// GetValidationFailures returns the recorded validation failures, newest first
+func (db *DB) GetValidationFailures() ([]ValidationFailure, error) {
+	rows, err := db.conn.Query(`
+		SELECT kind, item_id, model, reason, response, created_at
+		FROM _ai_validation_failures
+		ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []ValidationFailure
+	for rows.Next() {
+		var f ValidationFailure
+		if err := rows.Scan(&f.Kind, &f.ItemID, &f.Model, &f.Reason, &f.Response, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
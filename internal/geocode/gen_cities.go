@@ -0,0 +1,78 @@
+//go:build ignore
+
+// gen_cities replaces the built-in cities with an extract of the GeoNames dumps in -dir:
+// cities15000.txt, admin1CodesASCII.txt and countryInfo.txt from
+// https://download.geonames.org/export/dump/. It writes data/cities.tsv.gz and the
+// attribution the GeoNames licence asks for to data/LICENSE-cities.
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"lychee-ai-organizer/internal/geocode"
+)
+
+const attribution = `The built-in cities are an extract of the GeoNames geographical database
+(https://www.geonames.org/): %s, with region names from admin1CodesASCII.txt
+and country names from countryInfo.txt, taken from
+https://download.geonames.org/export/dump/.
+
+GeoNames data is licensed under the Creative Commons Attribution 4.0 License
+(https://creativecommons.org/licenses/by/4.0/). The extract keeps only the
+name, country, coordinates and region of each city.
+`
+
+func main() {
+	dir := flag.String("dir", "geonames", "directory holding the GeoNames dumps")
+	citiesFile := flag.String("cities", "cities15000.txt", "GeoNames cities dump in -dir")
+	flag.Parse()
+
+	cities, err := geocode.LoadGeoNames(
+		filepath.Join(*dir, *citiesFile),
+		filepath.Join(*dir, "admin1CodesASCII.txt"),
+		filepath.Join(*dir, "countryInfo.txt"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := writeCities("data/cities.tsv.gz", cities); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("data/LICENSE-cities", []byte(fmt.Sprintf(attribution, *citiesFile)), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %d cities", len(cities))
+}
+
+// writeCities writes name, country, latitude, longitude and region per line, as read by
+// loadBuiltinCities
+func writeCities(path string, cities []geocode.City) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewWriterLevel(f, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(gz)
+	for _, city := range cities {
+		fmt.Fprintf(w, "%s\t%s\t%.4f\t%.4f\t%s\n", city.Name, city.Country, city.Latitude, city.Longitude, city.Region)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,269 @@
+package geocode
+
+import (
+	"bufio"
+	"compress/gzip"
+	"embed"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geo"
+)
+
+//go:generate go run gen_cities.go -dir geonames
+
+//go:embed data/cities.tsv.gz
+var builtinCities embed.FS
+
+const (
+	// cellDegrees is the size of the grid cells cities are indexed by
+	cellDegrees = 1.0
+	// nearbyKm is the distance from a city beyond which a photo is described as near it rather than in it
+	nearbyKm = 15.0
+	// kmPerDegree is the length of a degree of latitude
+	kmPerDegree = 111.0
+)
+
+// City is a named place with coordinates
+type City struct {
+	Name      string
+	Region    string // empty if unknown
+	Country   string
+	Latitude  float64
+	Longitude float64
+}
+
+// Place is the city nearest to a coordinate
+type Place struct {
+	City       string
+	Region     string
+	Country    string
+	DistanceKm float64
+}
+
+// String names the place as "City, Region, Country", leaving out unknown parts
+func (p Place) String() string {
+	var parts []string
+	for _, part := range []string{p.City, p.Region, p.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Describe names the place for a prompt, saying "near" when the photo is outside the city
+func (p Place) Describe() string {
+	if p.DistanceKm > nearbyKm {
+		return "near " + p.String()
+	}
+	return p.String()
+}
+
+// Geocoder resolves coordinates to the nearest city without any network access. A nil
+// Geocoder is valid and never finds a place.
+type Geocoder struct {
+	cities        []City
+	cells         map[[2]int][]int32 // city indexes by grid cell
+	maxDistanceKm float64
+}
+
+// New loads the built-in cities, or the GeoNames dump from config if one is set. Returns nil
+// if geocoding is disabled.
+func New(cfg *config.GeocodingConfig) (*Geocoder, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+
+	var cities []City
+	var err error
+	if cfg.GeoNamesFile != "" {
+		cities, err = LoadGeoNames(cfg.GeoNamesFile, cfg.Admin1File, cfg.CountryInfoFile)
+	} else {
+		cities, err = loadBuiltinCities()
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Loaded %d cities for reverse geocoding", len(cities))
+
+	return newGeocoder(cities, cfg.MaxDistanceKm), nil
+}
+
+func newGeocoder(cities []City, maxDistanceKm float64) *Geocoder {
+	g := &Geocoder{
+		cities:        cities,
+		cells:         make(map[[2]int][]int32),
+		maxDistanceKm: maxDistanceKm,
+	}
+	for i, city := range cities {
+		key := cell(city.Latitude, city.Longitude)
+		g.cells[key] = append(g.cells[key], int32(i))
+	}
+	return g
+}
+
+// Lookup returns the city nearest to a coordinate, if one lies within the configured distance
+func (g *Geocoder) Lookup(lat, lon float64) (Place, bool) {
+	if g == nil {
+		return Place{}, false
+	}
+
+	// Scan every cell that can hold a city within range; cells narrow towards the poles
+	latCells := int(math.Ceil(g.maxDistanceKm / kmPerDegree / cellDegrees))
+	lonCells := 360
+	if cos := math.Cos(lat * math.Pi / 180); cos > 0.01 {
+		lonCells = int(math.Ceil(g.maxDistanceKm / (kmPerDegree * cos) / cellDegrees))
+	}
+	lonCells = min(lonCells, int(180/cellDegrees))
+
+	centre := cell(lat, lon)
+	best, bestDistance := -1, g.maxDistanceKm
+	for dLat := -latCells; dLat <= latCells; dLat++ {
+		for dLon := -lonCells; dLon <= lonCells; dLon++ {
+			key := [2]int{centre[0] + dLat, wrapLon(centre[1] + dLon)}
+			for _, i := range g.cells[key] {
+				city := &g.cities[i]
+				if d := geo.DistanceKm(lat, lon, city.Latitude, city.Longitude); d <= bestDistance {
+					best, bestDistance = int(i), d
+				}
+			}
+		}
+	}
+	if best < 0 {
+		return Place{}, false
+	}
+
+	city := g.cities[best]
+	return Place{City: city.Name, Region: city.Region, Country: city.Country, DistanceKm: bestDistance}, true
+}
+
+// PhotoLocation names where a photo was taken: Lychee's location if set, otherwise the place
+// of its coordinates. Returns "" if neither is known.
+func (g *Geocoder) PhotoLocation(photo *database.Photo) string {
+	if photo.Location.Valid && strings.TrimSpace(photo.Location.String) != "" {
+		return photo.Location.String
+	}
+	if !photo.Latitude.Valid || !photo.Longitude.Valid {
+		return ""
+	}
+	if place, ok := g.Lookup(photo.Latitude.Float64, photo.Longitude.Float64); ok {
+		return place.Describe()
+	}
+	return ""
+}
+
+// Summarize lists the places photos were taken at, most photos first, at most limit of them
+func (g *Geocoder) Summarize(photos []database.Photo, limit int) []string {
+	counts := make(map[string]int)
+	for i := range photos {
+		photo := &photos[i]
+		name := ""
+		if photo.Location.Valid && strings.TrimSpace(photo.Location.String) != "" {
+			name = photo.Location.String
+		} else if photo.Latitude.Valid && photo.Longitude.Valid {
+			if place, ok := g.Lookup(photo.Latitude.Float64, photo.Longitude.Float64); ok {
+				name = place.String()
+			}
+		}
+		if name != "" {
+			counts[name]++
+		}
+	}
+
+	places := make([]string, 0, len(counts))
+	for name := range counts {
+		places = append(places, name)
+	}
+	sort.Slice(places, func(i, j int) bool {
+		if counts[places[i]] != counts[places[j]] {
+			return counts[places[i]] > counts[places[j]]
+		}
+		return places[i] < places[j]
+	})
+	if len(places) > limit {
+		places = places[:limit]
+	}
+	return places
+}
+
+func cell(lat, lon float64) [2]int {
+	return [2]int{int(math.Floor(lat / cellDegrees)), wrapLon(int(math.Floor(lon / cellDegrees)))}
+}
+
+// wrapLon keeps longitude cells in range across the antimeridian
+func wrapLon(c int) int {
+	n := int(360 / cellDegrees)
+	return ((c+n/2)%n+n)%n - n/2
+}
+
+// loadBuiltinCities reads the embedded cities: name, country, latitude, longitude and
+// optionally region per line
+func loadBuiltinCities() ([]City, error) {
+	f, err := builtinCities.Open("data/cities.tsv.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var cities []City
+	err = readTSV(gz, func(fields []string) error {
+		if len(fields) < 4 {
+			return fmt.Errorf("expected 4 fields, got %d", len(fields))
+		}
+		city := City{Name: fields[0], Country: fields[1]}
+		if len(fields) > 4 {
+			city.Region = fields[4]
+		}
+		if err := parseCoordinates(fields[2], fields[3], &city); err != nil {
+			return err
+		}
+		cities = append(cities, city)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid built-in cities: %w", err)
+	}
+	return cities, nil
+}
+
+// readTSV calls fn with the tab separated fields of every line that isn't empty or a # comment
+func readTSV(r io.Reader, fn func(fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := fn(strings.Split(text, "\t")); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseCoordinates(lat, lon string, city *City) error {
+	var err error
+	if city.Latitude, err = strconv.ParseFloat(lat, 64); err != nil {
+		return fmt.Errorf("invalid latitude %q", lat)
+	}
+	if city.Longitude, err = strconv.ParseFloat(lon, 64); err != nil {
+		return fmt.Errorf("invalid longitude %q", lon)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package geocode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+func TestWrapLon(t *testing.T) {
+	tests := []struct {
+		cell int
+		want int
+	}{
+		{0, 0},
+		{179, 179},
+		{180, -180},
+		{181, -179},
+		{-180, -180},
+		{-181, 179},
+		{359, -1},
+		{-360, 0},
+		{540, -180},
+	}
+	for _, tt := range tests {
+		if got := wrapLon(tt.cell); got != tt.want {
+			t.Errorf("wrapLon(%d) = %d, want %d", tt.cell, got, tt.want)
+		}
+	}
+}
+
+func TestCell(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		want     [2]int
+	}{
+		{38.72, -9.14, [2]int{38, -10}},
+		{-0.5, 0.5, [2]int{-1, 0}},
+		{10, 179.9, [2]int{10, 179}},
+		{10, 180, [2]int{10, -180}},
+		{10, -180, [2]int{10, -180}},
+	}
+	for _, tt := range tests {
+		if got := cell(tt.lat, tt.lon); got != tt.want {
+			t.Errorf("cell(%v, %v) = %v, want %v", tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	g := newGeocoder([]City{
+		{Name: "Lisbon", Region: "Lisbon", Country: "Portugal", Latitude: 38.7251, Longitude: -9.1498},
+		{Name: "Sintra", Country: "Portugal", Latitude: 38.8029, Longitude: -9.3817},
+		{Name: "Taveuni", Country: "Fiji", Latitude: -16.8500, Longitude: 179.9500},
+		{Name: "Cape Wrangel", Country: "Russia", Latitude: 71.0000, Longitude: -179.9000},
+		{Name: "Longyearbyen", Country: "Svalbard and Jan Mayen", Latitude: 78.2232, Longitude: 15.6267},
+		{Name: "North Pole Camp", Latitude: 89.9000, Longitude: 170.0000},
+		{Name: "Amundsen-Scott", Latitude: -89.9900, Longitude: 139.2700},
+	}, 50)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string // "" if no place is found
+	}{
+		{"in city", 38.7223, -9.1393, "Lisbon"},
+		{"nearest of two", 38.79, -9.36, "Sintra"},
+		{"west of antimeridian", -16.85, -179.95, "Taveuni"},
+		{"east of antimeridian", 71.0, 179.9, "Cape Wrangel"},
+		{"exactly on antimeridian", -16.85, 180, "Taveuni"},
+		// at 78°N a degree of longitude is about 23 km, so this needs the neighbouring cells
+		{"high latitude", 78.2, 16.8, "Longyearbyen"},
+		// across the pole: 170°E and 10°W are 180° apart in longitude but 22 km apart
+		{"across north pole", 89.9, -10, "North Pole Camp"},
+		{"south pole", -89.99, -40.73, "Amundsen-Scott"},
+		{"out of range", 38.7, -7.5, ""},
+		{"open ocean", 0, -140, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			place, ok := g.Lookup(tt.lat, tt.lon)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("Lookup(%v, %v) = %+v, want no place", tt.lat, tt.lon, place)
+				}
+				return
+			}
+			if !ok || place.City != tt.want {
+				t.Fatalf("Lookup(%v, %v) = %+v, %v, want %s", tt.lat, tt.lon, place, ok, tt.want)
+			}
+			if place.DistanceKm > 50 {
+				t.Errorf("distance %.1f km is beyond the maximum", place.DistanceKm)
+			}
+		})
+	}
+}
+
+func TestLookupNil(t *testing.T) {
+	var g *Geocoder
+	if place, ok := g.Lookup(38.72, -9.14); ok {
+		t.Fatalf("nil geocoder found %+v", place)
+	}
+}
+
+func TestPlaceDescribe(t *testing.T) {
+	tests := []struct {
+		place Place
+		want  string
+	}{
+		{Place{City: "Lisbon", Region: "Lisbon", Country: "Portugal", DistanceKm: 2}, "Lisbon, Lisbon, Portugal"},
+		{Place{City: "Sintra", Country: "Portugal", DistanceKm: 20}, "near Sintra, Portugal"},
+		{Place{City: "Camp", DistanceKm: 15}, "Camp"},
+	}
+	for _, tt := range tests {
+		if got := tt.place.Describe(); got != tt.want {
+			t.Errorf("Describe() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestBuiltinCities(t *testing.T) {
+	g, err := New(&config.GeocodingConfig{MaxDistanceKm: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.cities) < 1000 {
+		t.Fatalf("loaded %d built-in cities", len(g.cities))
+	}
+	place, ok := g.Lookup(38.7223, -9.1393)
+	if !ok || place.City != "Lisbon" || place.Country != "Portugal" {
+		t.Errorf("Lookup(Lisbon) = %+v, %v", place, ok)
+	}
+
+	g, err = New(&config.GeocodingConfig{Disabled: true})
+	if err != nil || g != nil {
+		t.Errorf("disabled geocoder = %v, %v", g, err)
+	}
+}
+
+func TestLoadGeoNames(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	cities := write("cities15000.txt",
+		"2267057\tLisbon\tLisbon\tLisboa\t38.72509\t-9.1498\tP\tPPLC\tPT\t\t14\t\t\t\t517802\t\t45\tEurope/Lisbon\t2022-03-01\n"+
+			"2950159\tBerlin\tBerlin\t\t52.52437\t13.41053\tP\tPPLC\tDE\t\t16\n")
+	admin1 := write("admin1CodesASCII.txt", "PT.14\tLisbon\tLisbon\t2267056\n")
+	countries := write("countryInfo.txt", "# ISO\tISO3\nPT\tPRT\t620\tPO\tPortugal\n")
+
+	got, err := LoadGeoNames(cities, admin1, countries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []City{
+		{Name: "Lisbon", Region: "Lisbon", Country: "Portugal", Latitude: 38.72509, Longitude: -9.1498},
+		{Name: "Berlin", Country: "DE", Latitude: 52.52437, Longitude: 13.41053},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d cities, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("city %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if _, err := LoadGeoNames(write("short.txt", "1\tNowhere\t0\t0\n"), "", ""); err == nil {
+		t.Error("expected an error for a line with too few fields")
+	}
+}
@@ -0,0 +1,80 @@
+package geocode
+
+import (
+	"fmt"
+	"os"
+)
+
+// LoadGeoNames reads a GeoNames cities dump (cities500.txt, cities1000.txt, ...). Regions and
+// countries are named from admin1CodesASCII.txt and countryInfo.txt if given, and by their
+// codes otherwise.
+func LoadGeoNames(citiesPath, admin1Path, countryInfoPath string) ([]City, error) {
+	regions := map[string]string{}
+	if admin1Path != "" {
+		// code ("PT.14"), name, ascii name, geonameid
+		err := readTSVFile(admin1Path, func(fields []string) error {
+			if len(fields) >= 2 {
+				regions[fields[0]] = fields[1]
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GeoNames admin1 codes: %w", err)
+		}
+	}
+
+	countries := map[string]string{}
+	if countryInfoPath != "" {
+		// ISO code, ISO3, numeric, fips, name, ...
+		err := readTSVFile(countryInfoPath, func(fields []string) error {
+			if len(fields) >= 5 {
+				countries[fields[0]] = fields[4]
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GeoNames country info: %w", err)
+		}
+	}
+
+	var cities []City
+	// geonameid, name, asciiname, alternatenames, latitude, longitude, feature class, feature
+	// code, country code, cc2, admin1 code, ...
+	err := readTSVFile(citiesPath, func(fields []string) error {
+		if len(fields) < 11 {
+			return fmt.Errorf("expected at least 11 fields, got %d", len(fields))
+		}
+		countryCode, admin1 := fields[8], fields[10]
+
+		city := City{Name: fields[1], Country: countryCode}
+		if name, ok := countries[countryCode]; ok {
+			city.Country = name
+		}
+		if admin1Path != "" {
+			city.Region = regions[countryCode+"."+admin1]
+		}
+		if err := parseCoordinates(fields[4], fields[5], &city); err != nil {
+			return err
+		}
+		cities = append(cities, city)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoNames cities: %w", err)
+	}
+	if len(cities) == 0 {
+		return nil, fmt.Errorf("no cities in %s", citiesPath)
+	}
+
+	return cities, nil
+}
+
+func readTSVFile(path string, fn func(fields []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return readTSV(f, fn)
+}
@@ -0,0 +1,218 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+)
+
+const (
+	// sheetGap is the space between cells, in pixels
+	sheetGap = 8
+	// sheetQuality is the JPEG quality of a contact sheet
+	sheetQuality = 85
+	// signatureSize is the width and height a photo is shrunk to when comparing how it looks
+	signatureSize = 8
+)
+
+// sheetBackground fills the gaps between photos and the margins of photos that aren't square
+var sheetBackground = color.RGBA{R: 32, G: 32, B: 32, A: 255}
+
+// SheetPhoto is a photo that may appear on a contact sheet
+type SheetPhoto struct {
+	ID      string
+	TakenAt time.Time
+}
+
+// VariantFunc returns the size variant of a photo to show on a contact sheet
+type VariantFunc func(photoID string) (*database.SizeVariant, error)
+
+// ContactSheet is a collage of representative photos of an album, in the order they were taken
+type ContactSheet struct {
+	Image    []byte   // JPEG
+	PhotoIDs []string // row by row
+	Columns  int
+	Rows     int
+}
+
+// candidate is a photo fetched to choose the contact sheet's photos from
+type candidate struct {
+	photo     SheetPhoto
+	image     image.Image
+	signature []float64
+}
+
+// BuildContactSheet lays out up to cfg.Photos photos of an album in a grid. Photos are chosen to
+// spread over the album's time span and to look different from each other: cfg.Candidates photos
+// spread over time are fetched, and of these the ones least like those already chosen are picked.
+// Photos whose image can't be fetched or decoded are skipped.
+func (f *Fetcher) BuildContactSheet(photos []SheetPhoto, variant VariantFunc, cfg *config.ContactSheetConfig) (*ContactSheet, error) {
+	sorted := append([]SheetPhoto(nil), photos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].TakenAt.Before(sorted[j].TakenAt)
+	})
+
+	var candidates []candidate
+	for _, photo := range spreadOverTime(sorted, cfg.Candidates) {
+		img, err := f.fetchImage(photo.ID, variant)
+		if err != nil {
+			log.Printf("Leaving photo %s off the contact sheet: %v", photo.ID, err)
+			continue
+		}
+		candidates = append(candidates, candidate{photo: photo, image: img, signature: signature(img)})
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("none of the album's photos could be fetched")
+	}
+
+	chosen := chooseDiverse(candidates, cfg.Photos)
+	return renderSheet(chosen, cfg.CellSize)
+}
+
+func (f *Fetcher) fetchImage(photoID string, variant VariantFunc) (image.Image, error) {
+	v, err := variant(photoID)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := f.GetImageBytes(v)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// spreadOverTime picks n photos from photos sorted by time, one from the middle of each of n
+// equally sized runs, so that busy days are represented like they are in the album
+func spreadOverTime(photos []SheetPhoto, n int) []SheetPhoto {
+	if len(photos) <= n {
+		return photos
+	}
+	picked := make([]SheetPhoto, n)
+	for i := range picked {
+		picked[i] = photos[(2*i+1)*len(photos)/(2*n)]
+	}
+	return picked
+}
+
+// chooseDiverse picks up to n candidates, each time the one farthest from those already picked.
+// Distance is the mean of the distance in time, as a share of the album's time span, and the
+// difference in looks. The result is in time order.
+func chooseDiverse(candidates []candidate, n int) []candidate {
+	if len(candidates) <= n {
+		return candidates
+	}
+
+	span := candidates[len(candidates)-1].photo.TakenAt.Sub(candidates[0].photo.TakenAt)
+	distance := func(a, b candidate) float64 {
+		d := visualDistance(a.signature, b.signature)
+		if span > 0 {
+			d += math.Abs(float64(a.photo.TakenAt.Sub(b.photo.TakenAt))) / float64(span)
+		}
+		return d / 2
+	}
+
+	// Start from the middle of the album; nearest[i] is candidate i's distance to the closest pick
+	picked := []int{len(candidates) / 2}
+	nearest := make([]float64, len(candidates))
+	for i := range candidates {
+		nearest[i] = distance(candidates[i], candidates[picked[0]])
+	}
+	for len(picked) < n {
+		best := -1
+		for i := range candidates {
+			if nearest[i] > 0 && (best < 0 || nearest[i] > nearest[best]) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break // the rest are duplicates of picked photos
+		}
+		picked = append(picked, best)
+		for i := range candidates {
+			nearest[i] = math.Min(nearest[i], distance(candidates[i], candidates[best]))
+		}
+	}
+
+	sort.Ints(picked)
+	chosen := make([]candidate, len(picked))
+	for i, index := range picked {
+		chosen[i] = candidates[index]
+	}
+	return chosen
+}
+
+// signature shrinks an image to a few pixels, which is enough to tell a beach from a forest
+func signature(img image.Image) []float64 {
+	small := image.NewRGBA(image.Rect(0, 0, signatureSize, signatureSize))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	sig := make([]float64, 0, signatureSize*signatureSize*3)
+	for i := 0; i < len(small.Pix); i += 4 {
+		sig = append(sig, float64(small.Pix[i]), float64(small.Pix[i+1]), float64(small.Pix[i+2]))
+	}
+	return sig
+}
+
+// visualDistance is the mean difference between two signatures, from 0 for the same to 1
+func visualDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += math.Abs(a[i] - b[i])
+	}
+	return sum / float64(len(a)) / 255
+}
+
+// renderSheet draws the photos into a grid of square cells, as close to square as possible,
+// and encodes it as JPEG
+func renderSheet(photos []candidate, cellSize int) (*ContactSheet, error) {
+	columns := int(math.Ceil(math.Sqrt(float64(len(photos)))))
+	rows := (len(photos) + columns - 1) / columns
+
+	sheet := image.NewRGBA(image.Rect(0, 0, columns*cellSize+(columns+1)*sheetGap, rows*cellSize+(rows+1)*sheetGap))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(sheetBackground), image.Point{}, draw.Src)
+
+	ids := make([]string, len(photos))
+	for i, photo := range photos {
+		ids[i] = photo.photo.ID
+		cell := image.Rect(0, 0, cellSize, cellSize).Add(image.Pt(
+			sheetGap+(i%columns)*(cellSize+sheetGap),
+			sheetGap+(i/columns)*(cellSize+sheetGap)))
+		draw.CatmullRom.Scale(sheet, fit(photo.image.Bounds(), cell), photo.image, photo.image.Bounds(), draw.Src, nil)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sheet, &jpeg.Options{Quality: sheetQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode contact sheet: %w", err)
+	}
+	return &ContactSheet{Image: buf.Bytes(), PhotoIDs: ids, Columns: columns, Rows: rows}, nil
+}
+
+// fit returns the largest rectangle with the proportions of src centered in cell
+func fit(src, cell image.Rectangle) image.Rectangle {
+	w, h := cell.Dx(), cell.Dy()
+	if src.Dx()*h > src.Dy()*w {
+		h = max(1, src.Dy()*w/src.Dx())
+	} else {
+		w = max(1, src.Dx()*h/src.Dy())
+	}
+	offset := image.Pt((cell.Dx()-w)/2, (cell.Dy()-h)/2)
+	return image.Rect(0, 0, w, h).Add(cell.Min).Add(offset)
+}
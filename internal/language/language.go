@@ -0,0 +1,169 @@
+package language
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minWords is the shortest text whose language is guessed from its words
+	minWords = 6
+	// minHits is the number of common words a language needs before it counts as detected
+	minHits = 3
+	// foreignScriptShare is the share of letters in another script that marks a text as written
+	// in a language of that script
+	foreignScriptShare = 0.3
+)
+
+// names are the languages this package knows, by ISO 639-1 code
+var names = map[string]string{
+	"en": "English",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"it": "Italian",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"el": "Greek",
+	"ar": "Arabic",
+	"zh": "Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+}
+
+// commonWords are frequent short words of languages written in Latin script. They tell these
+// languages apart well enough in the sentences of a photo description.
+var commonWords = map[string][]string{
+	"en": {"the", "and", "of", "a", "in", "is", "with", "on", "to", "are", "an", "at", "its", "this", "from", "by", "as", "under", "their", "that", "while", "it"},
+	"de": {"der", "die", "das", "und", "ein", "eine", "mit", "auf", "im", "ist", "den", "dem", "von", "zu", "sich", "unter", "des", "bei", "einem", "einer", "sind"},
+	"fr": {"le", "la", "les", "et", "un", "une", "des", "du", "dans", "est", "avec", "sur", "au", "aux", "en", "sous", "ce", "qui", "par", "se"},
+	"es": {"el", "la", "los", "las", "y", "un", "una", "de", "del", "en", "con", "es", "por", "sobre", "al", "bajo", "que", "se", "su", "sus"},
+	"it": {"il", "lo", "la", "gli", "le", "e", "un", "una", "di", "del", "della", "in", "con", "è", "su", "sotto", "che", "nel", "nella", "sono"},
+	"nl": {"de", "het", "een", "en", "van", "in", "met", "op", "is", "zijn", "aan", "onder", "die", "dat", "bij", "door", "voor", "naar"},
+	"pt": {"o", "a", "os", "as", "e", "um", "uma", "de", "do", "da", "em", "com", "no", "na", "sob", "que", "por", "são", "seu", "sua"},
+}
+
+// scripts maps the languages written in a script other than Latin to that script
+var scripts = map[string]*unicode.RangeTable{
+	"ru": unicode.Cyrillic,
+	"el": unicode.Greek,
+	"ar": unicode.Arabic,
+	"zh": unicode.Han,
+	"ja": unicode.Hiragana,
+	"ko": unicode.Hangul,
+}
+
+// Name returns the English name of a language, or the code itself if it isn't known
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// Known reports whether a language code is one this package can name
+func Known(code string) bool {
+	_, ok := names[code]
+	return ok
+}
+
+// Latin reports whether a language is written in Latin script. Unknown languages are assumed to be.
+func Latin(code string) bool {
+	_, other := scripts[code]
+	return !other
+}
+
+// Detect guesses the language of a text. ok is false if the text is too short or too mixed to
+// tell; the guess is limited to the languages in names.
+func Detect(text string) (code string, ok bool) {
+	if code, ok := detectScript(text); ok {
+		return code, true
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) < minWords {
+		return "", false
+	}
+
+	hits := make(map[string]int)
+	for code, common := range commonWords {
+		set := make(map[string]bool, len(common))
+		for _, w := range common {
+			set[w] = true
+		}
+		for _, w := range words {
+			if set[w] {
+				hits[code]++
+			}
+		}
+	}
+
+	best, second := "", 0
+	for _, code := range sortedCodes(hits) {
+		switch n := hits[code]; {
+		case best == "" || n > hits[best]:
+			if best != "" {
+				second = hits[best]
+			}
+			best = code
+		case n > second:
+			second = n
+		}
+	}
+	// Many short words are shared between languages, so the winner must stand out
+	if best == "" || hits[best] < minHits || hits[best] < second*3/2 {
+		return "", false
+	}
+	return best, true
+}
+
+// detectScript recognizes text written mostly in a script other than Latin
+func detectScript(text string) (string, bool) {
+	letters := 0
+	counts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for code, table := range scripts {
+			if unicode.Is(table, r) {
+				counts[code]++
+			}
+		}
+		// Japanese mixes kana with Han characters
+		if unicode.Is(unicode.Katakana, r) {
+			counts["ja"]++
+		}
+	}
+	if letters == 0 {
+		return "", false
+	}
+	if counts["ja"] > 0 && float64(counts["ja"]+counts["zh"])/float64(letters) >= foreignScriptShare {
+		return "ja", true
+	}
+
+	best := ""
+	for _, code := range sortedCodes(counts) {
+		if best == "" || counts[code] > counts[best] {
+			best = code
+		}
+	}
+	if best != "" && float64(counts[best])/float64(letters) >= foreignScriptShare {
+		return best, true
+	}
+	return "", false
+}
+
+func sortedCodes(counts map[string]int) []string {
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,51 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string // "" if the language can't be told
+	}{
+		{"english", "A small harbour at dawn, with fishing boats moored along the stone quay and gulls circling above the water.", "en"},
+		{"german", "Ein kleiner Hafen im Morgengrauen, mit Fischerbooten an der steinernen Kaimauer und Möwen über dem Wasser.", "de"},
+		{"french", "Un petit port à l'aube, avec des bateaux de pêche amarrés le long du quai en pierre et des mouettes au-dessus de l'eau.", "fr"},
+		{"spanish", "Un pequeño puerto al amanecer, con barcos de pesca amarrados en el muelle de piedra y gaviotas sobre el agua.", "es"},
+		{"dutch", "Een kleine haven bij zonsopgang, met vissersboten aan de stenen kade en meeuwen boven het water.", "nl"},
+		{"russian", "Небольшая гавань на рассвете с рыбацкими лодками у каменной пристани.", "ru"},
+		{"japanese", "夜明けの小さな港。石の岸壁に漁船が係留されている。", "ja"},
+		{"chinese", "黎明时分的小港口，渔船停泊在石头码头旁。", "zh"},
+		{"korean", "새벽의 작은 항구, 돌 부두에 어선들이 정박해 있다.", "ko"},
+		{"short", "Harbour at dawn.", ""},
+		{"numbers only", "Canon EOS R5 at f/2.8, 1/250 s, ISO 100.", ""},
+		{"empty", "", ""},
+		{"no common words", "Sunset harbour boats lights town quay gulls.", ""},
+		// a few foreign words don't make the text foreign
+		{"english with japanese name", "A photo of the Tokyo tower 東京タワー at night with the city lights in the background.", "en"},
+		{"english with cyrillic sign", "A street sign reading Москва in the snow, with a bus and people waiting at the stop.", "en"},
+		{"mixed english and german", "The harbour and the boats, der Hafen und die Boote, in the morning mit dem Licht.", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ok := Detect(tt.text)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Detect() = %s, want no guess", code)
+				}
+				return
+			}
+			if !ok || code != tt.want {
+				t.Errorf("Detect() = %q, %v, want %s", code, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatin(t *testing.T) {
+	for code, want := range map[string]bool{"en": true, "de": true, "xx": true, "ru": false, "ja": false, "ar": false} {
+		if got := Latin(code); got != want {
+			t.Errorf("Latin(%s) = %v, want %v", code, got, want)
+		}
+	}
+}
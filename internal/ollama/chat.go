@@ -0,0 +1,194 @@
+package ollama
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"strings"
+
+	"lychee-ai-organizer/internal/database"
+
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// exampleDistractors is the number of other albums listed in a suggestion example, so that
+	// examples show a choice between albums like the real prompt does
+	exampleDistractors = 2
+	// exampleConfidence and distractorConfidence are the confidences example answers give the
+	// right album and the others
+	exampleConfidence    = 0.9
+	distractorConfidence = 0.1
+	// defaultExampleReason is used for configured examples without a reason
+	defaultExampleReason = "The photo matches the album's subject and setting"
+	distractorReason     = "Little in common with the photo"
+)
+
+// jsonFormat asks for a response in JSON
+var jsonFormat = json.RawMessage(`"json"`)
+
+// Example is a worked example, shown to the model as an earlier turn of the conversation
+type Example struct {
+	Prompt   string `json:"prompt"`
+	Response string `json:"response"`
+}
+
+// newChat builds the conversation for a prompt: the system message with the style rules, the
+// examples as earlier turns, and the prompt itself with any images. The answer is asked for in
+// the primary language.
+func (c *Client) newChat(name, prompt string, images []api.ImageData, examples []Example) *api.ChatRequest {
+	return c.newChatIn("", name, prompt, images, examples)
+}
+
+// newChatIn builds the conversation for a prompt whose answer is written in language
+func (c *Client) newChatIn(language, name, prompt string, images []api.ImageData, examples []Example) *api.ChatRequest {
+	var messages []api.Message
+	if system := c.prompts.System(name, language); system != "" {
+		messages = append(messages, api.Message{Role: "system", Content: system})
+	}
+	for _, example := range examples {
+		messages = append(messages,
+			api.Message{Role: "user", Content: example.Prompt},
+			api.Message{Role: "assistant", Content: example.Response})
+	}
+	messages = append(messages, api.Message{Role: "user", Content: prompt, Images: images})
+
+	return &api.ChatRequest{Messages: messages}
+}
+
+// suggestionExamples returns worked examples for the suggestions prompt: the configured ones,
+// followed by recently accepted suggestions. Only examples whose album is among albums are
+// used, and photo's own accepted suggestion is left out.
+func (c *Client) suggestionExamples(photo *database.Photo, albums []AlbumPromptItem) []Example {
+	type example struct {
+		description string
+		album       AlbumPromptItem
+		reason      string
+	}
+	var selected []example
+	used := make(map[string]bool) // one example per album, so they show a range of albums
+
+	for _, configured := range c.prompts.examples.Suggestions {
+		album, ok := findAlbum(albums, configured.Album)
+		if !ok || used[album.ID] {
+			continue
+		}
+		reason := configured.Reason
+		if reason == "" {
+			reason = defaultExampleReason
+		}
+		selected = append(selected, example{configured.Description, album, reason})
+		used[album.ID] = true
+	}
+
+	if n := c.prompts.examples.Accepted; n > 0 && c.db != nil {
+		// Ask for more than needed, as some are skipped
+		accepted, err := c.db.GetAcceptedSuggestions(n * 4)
+		if err != nil {
+			log.Printf("Error getting accepted suggestions: %v", err)
+		}
+		added := 0
+		for _, a := range accepted {
+			if added == n {
+				break
+			}
+			album, ok := findAlbum(albums, a.AlbumID)
+			if !ok || used[album.ID] || a.PhotoID == photo.ID {
+				continue
+			}
+			selected = append(selected, example{a.Description, album, a.Reason})
+			used[album.ID] = true
+			added++
+		}
+	}
+
+	var examples []Example
+	for _, e := range selected {
+		prompt, response, err := c.renderSuggestionExample(e.description, e.album, e.reason, albums)
+		if err != nil {
+			log.Printf("Error rendering suggestion example for album %s: %v", e.album.ID, err)
+			continue
+		}
+		examples = append(examples, Example{Prompt: prompt, Response: response})
+	}
+	return examples
+}
+
+// renderSuggestionExample renders the suggestions prompt for an example photo, offering its
+// album among a few others, and the answer the model should give
+func (c *Client) renderSuggestionExample(description string, album AlbumPromptItem, reason string, albums []AlbumPromptItem) (prompt, response string, err error) {
+	offered := []AlbumPromptItem{album}
+	answer := []suggestionResponseItem{{AlbumID: album.ID, Confidence: exampleConfidence, Reason: reason}}
+
+	// Pick the other albums from a place that depends on the description, so that examples
+	// don't all show the same ones
+	h := fnv.New32a()
+	h.Write([]byte(description))
+	sum := h.Sum32()
+	start := int(sum % uint32(len(albums)))
+	for i := 0; i < len(albums) && len(offered) <= exampleDistractors; i++ {
+		other := albums[(start+i)%len(albums)]
+		if other.ID == album.ID {
+			continue
+		}
+		offered = append(offered, other)
+		answer = append(answer, suggestionResponseItem{AlbumID: other.ID, Confidence: distractorConfidence, Reason: distractorReason})
+	}
+	// Move the right album away from the top of the list, so that examples don't teach the
+	// model to pick the first album. The answer stays in confidence order.
+	right := int((sum >> 16) % uint32(len(offered)))
+	offered[0], offered[right] = offered[right], offered[0]
+
+	prompt, err = c.prompts.Render(PromptSuggestions, SuggestionPromptContext{
+		Photo: PhotoPromptData{
+			ID:          "example",
+			Title:       "Unknown",
+			TakenAt:     "Unknown",
+			Date:        "Unknown",
+			Make:        "Unknown",
+			Model:       "Unknown",
+			Location:    "Unknown",
+			Description: description,
+			EXIF:        unknownEXIF(),
+		},
+		Albums: offered,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{"suggestions": answer})
+	if err != nil {
+		return "", "", err
+	}
+	return prompt, string(encoded), nil
+}
+
+// suggestionResponseItem is one suggestion in the JSON the suggestions prompt asks for
+type suggestionResponseItem struct {
+	AlbumID    string  `json:"album_id"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
+}
+
+// findAlbum finds an album by ID, or else by title ignoring case
+func findAlbum(albums []AlbumPromptItem, idOrTitle string) (AlbumPromptItem, bool) {
+	for _, album := range albums {
+		if album.ID == idOrTitle {
+			return album, true
+		}
+	}
+	for _, album := range albums {
+		if strings.EqualFold(strings.TrimSpace(album.Title), strings.TrimSpace(idOrTitle)) {
+			return album, true
+		}
+	}
+	return AlbumPromptItem{}, false
+}
+
+func unknownEXIF() EXIFPromptData {
+	return EXIFPromptData{
+		Lens: "Unknown", Focal: "Unknown", Aperture: "Unknown", Shutter: "Unknown", ISO: "Unknown",
+		Altitude: "Unknown", Direction: "Unknown", Latitude: "Unknown", Longitude: "Unknown",
+	}
+}
@@ -0,0 +1,61 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+func TestRenderSuggestionExample(t *testing.T) {
+	prompts, err := LoadPrompts(&config.PromptsConfig{Languages: []string{"en"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{prompts: prompts}
+	albums := []AlbumPromptItem{
+		{ID: "a1", Title: "Harbours"},
+		{ID: "a2", Title: "Mountains"},
+		{ID: "a3", Title: "Markets"},
+		{ID: "a4", Title: "Festivals"},
+		{ID: "a5", Title: "Gardens"},
+	}
+	album := albums[2]
+
+	positions := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		prompt, response, err := c.renderSuggestionExample(fmt.Sprintf("Example photo %d", i), album, "Stalls of fruit", albums)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var answer struct {
+			Suggestions []suggestionResponseItem `json:"suggestions"`
+		}
+		if err := json.Unmarshal([]byte(response), &answer); err != nil {
+			t.Fatal(err)
+		}
+		if len(answer.Suggestions) != exampleDistractors+1 {
+			t.Fatalf("answer has %d suggestions, want %d", len(answer.Suggestions), exampleDistractors+1)
+		}
+		if first := answer.Suggestions[0]; first.AlbumID != album.ID || first.Confidence != exampleConfidence {
+			t.Errorf("answer starts with %+v, want the example's album", first)
+		}
+
+		// The position of the album among those offered, by where its title is in the prompt
+		position := 0
+		at := strings.Index(prompt, album.Title)
+		for _, s := range answer.Suggestions[1:] {
+			other, _ := findAlbum(albums, s.AlbumID)
+			if strings.Index(prompt, other.Title) < at {
+				position++
+			}
+		}
+		positions[position] = true
+	}
+	if len(positions) < 2 {
+		t.Errorf("the example's album is always offered at position %v", positions)
+	}
+}
@@ -3,60 +3,71 @@ package ollama
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
-	"net/url"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"lychee-ai-organizer/internal/config"
 	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geocode"
 	"lychee-ai-organizer/internal/images"
+	"lychee-ai-organizer/internal/titles"
 
 	"github.com/avast/retry-go"
 	"github.com/ollama/ollama/api"
 )
 
-const (
-	// maxDescriptionsBeforeCompaction is the threshold for applying hierarchical compaction
-	maxDescriptionsBeforeCompaction = 30
-	// retryAttempts is the number of times to retry failed API calls
-	retryAttempts = 3
-)
+// PartialFunc receives the text generated so far while a response is streamed. When a failed
+// request is retried, the text starts over from the beginning.
+type PartialFunc func(text string)
 
 type Client struct {
-	client       *api.Client
-	imageModel   string
-	synthModel   string
+	pool         *pool
+	tasks        map[string][]modelProfile // profile chain by task
 	db           *database.DB
 	imageFetcher *images.Fetcher
+	titleMatcher *titles.Matcher
+	geocoder     *geocode.Geocoder
+	prompts      *Prompts
+	validator    *validator
+	albums       *config.AlbumsConfig // how albums are described; nil where no album is
 	config       *config.OllamaConfig
+
+	mu         sync.Mutex
+	noThinking map[string]bool         // models that refused to think when asked to
+	digests    map[string]cachedDigest // model digests by endpoint URL and model name
 }
 
-func NewClient(cfg *config.OllamaConfig, db *database.DB, imageFetcher *images.Fetcher) (*Client, error) {
-	baseURL, err := url.Parse(cfg.Endpoint)
+func NewClient(cfg *config.OllamaConfig, db *database.DB, imageFetcher *images.Fetcher, titleMatcher *titles.Matcher, geocoder *geocode.Geocoder, prompts *Prompts, validation *config.ValidationConfig, albums *config.AlbumsConfig) (*Client, error) {
+	endpoints, err := newPool(cfg.Endpoints)
 	if err != nil {
-		return nil, fmt.Errorf("invalid Ollama endpoint URL: %w", err)
+		return nil, err
 	}
 
-	httpClient := &http.Client{}
-	client := api.NewClient(baseURL, httpClient)
-
-	return &Client{
-		client:       client,
-		imageModel:   cfg.ImageAnalysisModel,
-		synthModel:   cfg.DescriptionSynthesisModel,
+	c := &Client{
+		pool:         endpoints,
 		db:           db,
 		imageFetcher: imageFetcher,
+		titleMatcher: titleMatcher,
+		geocoder:     geocoder,
+		prompts:      prompts,
+		validator:    newValidator(validation),
+		albums:       albums,
 		config:       cfg,
-	}, nil
+		noThinking:   make(map[string]bool),
+		digests:      make(map[string]cachedDigest),
+	}
+	c.tasks = c.newTaskProfiles()
+	return c, nil
 }
 
-func (c *Client) GeneratePhotoDescription(photo *database.Photo) (string, error) {
+// GeneratePhotoDescription describes a photo with the vision model. onPartial, if not nil, is
+// called as the description streams in.
+func (c *Client) GeneratePhotoDescription(photo *database.Photo, onPartial PartialFunc) (string, error) {
 	// Get the image variant for this photo first to check filename
 	variant, err := c.db.GetPhotoSizeVariant(photo.ID)
 	if err != nil {
@@ -74,43 +85,69 @@ func (c *Client) GeneratePhotoDescription(photo *database.Photo) (string, error)
 		return "", fmt.Errorf("failed to fetch image: %w", err)
 	}
 
-	prompt := fmt.Sprintf(`Analyze this photo and provide a concise description in 2 sentences. Focus on:
-- Subject matter and composition
-- Photographic style and unique characteristics  
-- Overall mood and atmosphere
+	prompt, err := c.prompts.Render(PromptPhotoDescription, PhotoPromptContext{Photo: c.photoPromptData(photo)})
+	if err != nil {
+		return "", fmt.Errorf("failed to render photo description prompt: %w", err)
+	}
+
+	req := c.newChat(PromptPhotoDescription, prompt, []api.ImageData{imageBytes}, nil)
+
+	description, err := c.generate(taskPhotoDescription, subject{kind: PromptPhotoDescription, id: photo.ID}, req, onPartial, requireText)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate photo description after retries: %w", err)
+	}
+
+	return description, nil
+}
+
+// GeneratePhotoTitle proposes a short human-readable title for a photo whose title is a camera filename.
+// onPartial, if not nil, is called as the title streams in.
+func (c *Client) GeneratePhotoTitle(photo *database.Photo, onPartial PartialFunc) (string, error) {
+	variant, err := c.db.GetPhotoSizeVariant(photo.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get image variant: %w", err)
+	}
 
-Photo details:
-- Title: %s
-- Taken at: %s
-- Camera: %s %s
-- Location: %s
+	if isMovieFile(photo, variant) {
+		return "", fmt.Errorf("skipping movie file (type: %s, path: %s)", photo.Type, variant.ShortPath)
+	}
 
-Provide only the description, no additional text.`,
-		photo.Title,
-		formatTakenAt(photo.TakenAt),
-		getStringValue(photo.Make),
-		getStringValue(photo.Model),
-		getStringValue(photo.Location))
+	imageBytes, _, err := c.imageFetcher.GetImageBytes(variant)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch image: %w", err)
+	}
 
-	req := &api.GenerateRequest{
-		Model:  c.imageModel,
-		Prompt: prompt,
-		Stream: &[]bool{false}[0],
-		Images: []api.ImageData{
-			imageBytes,
-		},
+	prompt, err := c.prompts.Render(PromptPhotoTitle, PhotoPromptContext{Photo: c.photoPromptData(photo)})
+	if err != nil {
+		return "", fmt.Errorf("failed to render photo title prompt: %w", err)
 	}
 
-	ctx := context.Background()
-	description, err := c.generateWithRetry(ctx, req)
+	req := c.newChat(PromptPhotoTitle, prompt, []api.ImageData{imageBytes}, nil)
+
+	var title string
+	_, err = c.generate(taskPhotoDescription, subject{kind: PromptPhotoTitle, id: photo.ID}, req, onPartial, func(response string) error {
+		title = cleanTitle(response)
+		if title == "" {
+			return invalidOutput("is not a title")
+		}
+		return nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to generate photo description after retries: %w", err)
+		return "", fmt.Errorf("failed to generate photo title after retries: %w", err)
 	}
 
-	// Remove <think> tags and their contents
-	description = removeThinkTags(description)
+	return title, nil
+}
 
-	return description, nil
+// promptTitle returns the photo title for use in prompts, hiding camera filenames that carry no meaning
+func (c *Client) promptTitle(photo *database.Photo) string {
+	if c.titleMatcher != nil && c.titleMatcher.IsCameraFilename(photo.Title) {
+		return "Unknown"
+	}
+	if strings.TrimSpace(photo.Title) == "" {
+		return "Unknown"
+	}
+	return photo.Title
 }
 
 // buildOllamaOptions creates options map for Ollama API requests
@@ -146,75 +183,164 @@ func (c *Client) buildOllamaOptions() map[string]interface{} {
 	return options
 }
 
-// generateWithRetry performs an Ollama API call with retry logic
-func (c *Client) generateWithRetry(ctx context.Context, req *api.GenerateRequest) (string, error) {
-	var response strings.Builder
+// generation is a model's response together with the reasoning it reported apart from it
+type generation struct {
+	response string
+	thinking string
+	endpoint *endpoint // the endpoint that answered
+}
 
-	err := retry.Do(
-		func() error {
-			response.Reset() // Clear previous attempts
-			return c.client.Generate(ctx, req, func(resp api.GenerateResponse) error {
-				response.WriteString(resp.Response)
-				return nil
-			})
-		},
-		retry.Attempts(retryAttempts),
-		retry.Delay(time.Second),
-		retry.DelayType(retry.BackOffDelay),
-	)
-	if err != nil {
-		return "", err
+// generateWithRetry performs an Ollama chat API call, retrying transient failures with jittered
+// backoff. Permanent failures are returned at once. If the endpoint turns out to be unreachable
+// the request waits in the pool until an endpoint is available again, without using up attempts.
+// The response is streamed when onPartial is set, and returned in one piece otherwise; only the
+// response, not the reasoning, is passed to onPartial.
+func (c *Client) generateWithRetry(ctx context.Context, req *api.ChatRequest, onPartial PartialFunc) (generation, error) {
+	var response, thinking strings.Builder
+	var served *endpoint
+
+	stream := onPartial != nil
+	req.Stream = &stream
+
+	attempt := func() error {
+		// Clear previous attempts
+		response.Reset()
+		thinking.Reset()
+
+		// Each attempt may go to a different endpoint
+		e, err := c.pool.acquire(ctx)
+		if err != nil {
+			return retry.Unrecoverable(err)
+		}
+		err = e.client.Chat(ctx, req, func(resp api.ChatResponse) error {
+			response.WriteString(resp.Message.Content)
+			thinking.WriteString(resp.Message.Thinking)
+			if onPartial != nil && resp.Message.Content != "" {
+				onPartial(response.String())
+			}
+			return nil
+		})
+		if err != nil && ctx.Err() != nil {
+			// A profile's timeout or the caller gave up; the endpoint may just be slow
+			c.pool.abandon(e)
+		} else {
+			c.pool.release(e, err)
+		}
+		served = e
+		return err
+	}
+
+	for {
+		err := retry.Do(attempt,
+			retry.Attempts(uint(c.config.Retry.Attempts)),
+			retry.Delay(time.Duration(c.config.Retry.DelaySeconds*float64(time.Second))),
+			retry.MaxJitter(time.Duration(c.config.Retry.MaxJitterSeconds*float64(time.Second))),
+			retry.DelayType(retry.CombineDelay(retry.BackOffDelay, retry.RandomDelay)),
+			retry.RetryIf(func(err error) bool {
+				return retry.IsRecoverable(err) && classifyError(err) == errorTransient
+			}),
+			retry.LastErrorOnly(true),
+			retry.Context(ctx),
+		)
+		if err == nil {
+			break
+		}
+
+		class := classifyError(err)
+		if class != errorUnreachable {
+			return generation{}, fmt.Errorf("%s error: %w", class, err)
+		}
+		log.Printf("Ollama unreachable (%v); waiting for an endpoint", err)
 	}
 
-	return strings.TrimSpace(response.String()), nil
+	return generation{
+		response: strings.TrimSpace(response.String()),
+		thinking: strings.TrimSpace(thinking.String()),
+		endpoint: served,
+	}, nil
+}
+
+// Available reports whether any Ollama endpoint is currently reachable
+func (c *Client) Available() bool {
+	return c.pool.isAvailable()
+}
+
+// SetStatusHandler registers a function to be told when Ollama becomes unreachable or comes back
+func (c *Client) SetStatusHandler(fn StatusFunc) {
+	c.pool.mu.Lock()
+	defer c.pool.mu.Unlock()
+	c.pool.onStatus = fn
 }
 
-func (c *Client) GenerateAlbumDescription(album *database.Album, photos []database.Photo) (string, error) {
-	log.Printf("Generating description for album %s (%s) with %d photos", album.ID, album.Title, len(photos))
+// GenerateAlbumDescription synthesizes an album description from its photos' and sub-albums'
+// descriptions, as gathered by AlbumContent, then has the vision model rewrite it from a contact
+// sheet of the album's photos unless contact sheets are disabled. onPartial, if not nil, is
+// called as each compaction summary and the descriptions stream in.
+func (c *Client) GenerateAlbumDescription(album *database.Album, content *database.AlbumContent, onPartial PartialFunc) (string, error) {
+	photos := content.Photos
+	log.Printf("Generating description for album %s (%s) with %d photos and %d described sub-albums", album.ID, album.Title, len(photos), len(content.SubAlbums))
 
-	photoDescriptions, dates, err := c.extractPhotoData(photos)
+	_, dates, err := c.extractPhotoData(photos)
 	if err != nil {
 		return "", err
 	}
 
+	// Very large albums are described from a representative sample; dates and places still
+	// cover every photo
+	photoDescriptions, sample := c.albumDescriptions(content)
 	if len(photoDescriptions) == 0 {
 		return "", fmt.Errorf("no photo descriptions available for album synthesis")
 	}
+	places := c.geocoder.Summarize(photos, maxPromptPlaces)
+	albumData := c.albumPromptData(album)
 
-	// Apply hierarchical compaction if needed
-	compactedDescriptions := photoDescriptions
-	if len(photoDescriptions) > maxDescriptionsBeforeCompaction {
-		log.Printf("Album %s has %d descriptions, applying compaction", album.ID, len(photoDescriptions))
-		compactedDescriptions, err = c.compactDescriptionsHierarchically(album.ID, photoDescriptions)
-		if err != nil {
-			return "", fmt.Errorf("failed to compact descriptions: %w", err)
-		}
+	// Apply hierarchical compaction if the descriptions don't fit the context window
+	budget, err := c.albumDescriptionBudget(albumData, dates, places, sample)
+	if err != nil {
+		return "", fmt.Errorf("failed to render album description prompt: %w", err)
+	}
+	compactedDescriptions, err := c.compactDescriptionsHierarchically(album.ID, photoDescriptions, budget, onPartial)
+	if err != nil {
+		return "", fmt.Errorf("failed to compact descriptions: %w", err)
+	}
+	if len(compactedDescriptions) != len(photoDescriptions) {
 		log.Printf("Compacted %d descriptions to %d for album %s", len(photoDescriptions), len(compactedDescriptions), album.ID)
 	}
 
-	prompt := c.buildAlbumDescriptionPrompt(compactedDescriptions, dates)
-	req := &api.GenerateRequest{
-		Model:   c.synthModel,
-		Prompt:  prompt,
-		Stream:  &[]bool{false}[0],
-		Options: c.buildOllamaOptions(),
+	prompt, err := c.buildAlbumDescriptionPrompt(albumData, compactedDescriptions, dates, places, sample)
+	if err != nil {
+		return "", fmt.Errorf("failed to render album description prompt: %w", err)
 	}
+	req := c.newChat(PromptAlbumDescription, prompt, nil, nil)
 
-	ctx := context.Background()
-	generatedDescription, err := c.generateWithRetry(ctx, req)
+	generatedDescription, err := c.generate(taskAlbumSynthesis, subject{kind: PromptAlbumDescription, id: album.ID}, req, onPartial, requireText)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate album description after retries: %w", err)
 	}
 
-	// Remove <think> tags and their contents
-	generatedDescription = removeThinkTags(generatedDescription)
+	// Let the vision model add what the text leaves out; the text alone is still a description
+	if c.contactSheetEnabled() {
+		visual, err := c.describeContactSheet(album, photos, generatedDescription, dates, places, onPartial)
+		if err != nil {
+			log.Printf("Keeping the description of album %s written from text only: %v", album.ID, err)
+		} else {
+			generatedDescription = visual
+		}
+	}
 
-	// Append date range information
+	// Append date range and place information
 	if len(dates) > 0 {
 		minDate := getMinDate(dates)
 		maxDate := getMaxDate(dates)
-		dateRangeText := fmt.Sprintf(" The album contains photos from dates %s to %s.", minDate, maxDate)
-		generatedDescription += dateRangeText
+		dateRangeText := fmt.Sprintf(" The album contains photos from dates %s to %s", minDate, maxDate)
+		// The description ends up in suggestion prompts, so it follows the redaction policy too
+		if places := c.prompts.RedactPlaces(places); len(places) > 0 {
+			dateRangeText += " taken in " + strings.Join(places, "; ")
+		}
+		generatedDescription += dateRangeText + "."
+	}
+	if sample.size > 0 {
+		generatedDescription += fmt.Sprintf(" It is based on a sample of %d of its %d described photos.", sample.size, sample.of)
 	}
 
 	log.Printf("Generated description for album %s (length: %d chars)", album.ID, len(generatedDescription))
@@ -243,145 +369,144 @@ func (c *Client) extractPhotoData(photos []database.Photo) ([]string, []string,
 }
 
 // buildAlbumDescriptionPrompt creates the prompt for album description generation
-func (c *Client) buildAlbumDescriptionPrompt(descriptions []string, dates []string) string {
-	minDate := getMinDate(dates)
-	maxDate := getMaxDate(dates)
+func (c *Client) buildAlbumDescriptionPrompt(album AlbumPromptData, descriptions []string, dates []string, places []string, sample albumSample) (string, error) {
+	return c.prompts.Render(PromptAlbumDescription, AlbumPromptContext{
+		Album:        album,
+		Descriptions: descriptions,
+		DateRange:    DateRange{Start: getMinDate(dates), End: getMaxDate(dates)},
+		Places:       places,
+		SampleSize:   sample.size,
+		PhotoCount:   sample.of,
+	})
+}
 
-	return fmt.Sprintf(`Based on the following photo descriptions from an album, create a concise summary that captures the essence of this photo collection:
+// GenerateAlbumSuggestions asks the model for the top 3 albums for a photo, each with a
+// normalized confidence (0-1) and a one-line reason. Worked examples of photos and their
+// albums precede the question.
+func (c *Client) GenerateAlbumSuggestions(photo *database.Photo, albums []database.Album) ([]database.AlbumSuggestion, error) {
+	albumItems := c.suggestionAlbums(albums)
+	prompt, err := c.buildSuggestionPrompt(photo, albumItems)
+	if err != nil {
+		return nil, err
+	}
+	examples := c.suggestionExamples(photo, albumItems)
 
-Photo descriptions:
-%s
+	log.Printf("Generating album suggestions for photo %s with %d examples", photo.ID, len(examples))
 
-Date range: %s to %s
+	req := c.newChat(PromptSuggestions, prompt, nil, examples)
+	req.Format = jsonFormat
 
-Provide a cohesive summary that synthesizes the common themes, subjects, and mood across these photos.
+	// Unparseable JSON counts as unusable output, so the next profile gets a chance
+	var suggestions []database.AlbumSuggestion
+	_, err = c.generate(taskSuggestion, subject{kind: PromptSuggestions, id: photo.ID}, req, nil, func(response string) (err error) {
+		suggestions, err = parseAlbumSuggestions(response, albums)
+		if err != nil {
+			return c.validator.invalidJSON(err)
+		}
+		if len(suggestions) == 0 && c.validator.enabled() {
+			return invalidOutput("uses none of the album IDs from the list")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate album suggestions: %w", err)
+	}
 
-IMPORTANT: Keep your response to a maximum of 2 sentences. Be concise and focus on the most important aspects.
+	for i := range suggestions {
+		suggestions[i].PhotoID = photo.ID
+	}
 
-Provide only the summary, no additional text.`,
-		strings.Join(descriptions, "\n- "),
-		minDate,
-		maxDate)
+	log.Printf("Generated %d album suggestions for photo %s", len(suggestions), photo.ID)
+	return suggestions, nil
 }
 
-func (c *Client) GenerateAlbumSuggestions(photo *database.Photo, albums []database.Album) ([]string, error) {
-	var albumDescs []string
+// suggestionAlbums lists the albums that can be suggested: those with an AI description
+func (c *Client) suggestionAlbums(albums []database.Album) []AlbumPromptItem {
+	var albumItems []AlbumPromptItem
 	for _, album := range albums {
 		if album.AIDescription.Valid {
-			albumDescs = append(albumDescs, fmt.Sprintf("Album ID %s: \"%s\": %s", album.ID, album.Title, album.AIDescription.String))
+			albumItems = append(albumItems, AlbumPromptItem{
+				ID:          album.ID,
+				Title:       album.Title,
+				Description: album.AIDescription.String,
+			})
 		}
 	}
-
-	if len(albumDescs) == 0 {
-		return nil, fmt.Errorf("no album descriptions available for suggestions")
-	}
-
-	photoDesc := ""
-	if photo.AIDescription.Valid {
-		photoDesc = photo.AIDescription.String
-	} else {
-		return nil, fmt.Errorf("photo has no AI description")
-	}
-
-	// Get photo date (use taken_at if available, otherwise fall back to created_at)
-	var photoDate string
-	if photo.TakenAt.Valid {
-		photoDate = photo.TakenAt.Time.Format("2006-01-02")
-	} else {
-		photoDate = photo.CreatedAt.Format("2006-01-02")
-	}
-
-	prompt := fmt.Sprintf(`Given this photo description:
-%s
-
-Photo date: %s
-
-And these available albums:
-%s
-
-Analyze this photo and suggest the top 3 most appropriate albums for it. Consider:
-- Thematic similarity (subject matter, content type)
-- Contextual relevance (setting, event type, activity)
-- Other clues (album title vs. photo subject, album date vs. photo date)
-
-You must respond with valid JSON in exactly this format:
-{
-  "album_ids": ["AlbumID1", "AlbumID2", "AlbumID3"]
+	c.addAlbumFacets(albumItems)
+	return albumItems
 }
 
-Rules:
-- Use only Album IDs that appear in the available albums list above
-- Return exactly 3 Album IDs in order of best match first
-- Respond with only the JSON object, no other text
-- The "album_ids" field must contain an array of strings`,
-		photoDesc,
-		photoDate,
-		strings.Join(albumDescs, "\n"))
-
-	log.Printf("Generating album suggestions for photo %s", photo.ID)
-
-	// Build options for the request
-	options := c.buildOllamaOptions()
-
-	req := &api.GenerateRequest{
-		Model:   c.synthModel,
-		Prompt:  prompt,
-		Stream:  &[]bool{false}[0],
-		Format:  "json",
-		Options: options,
+// buildSuggestionPrompt creates the prompt asking for the best albums for a photo
+func (c *Client) buildSuggestionPrompt(photo *database.Photo, albumItems []AlbumPromptItem) (string, error) {
+	if len(albumItems) == 0 {
+		return "", fmt.Errorf("no album descriptions available for suggestions")
 	}
 
-	ctx := context.Background()
-	responseText, err := c.generateWithRetry(ctx, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate album suggestions after retries: %w", err)
+	if !photo.AIDescription.Valid {
+		return "", fmt.Errorf("photo has no AI description")
 	}
 
-	// Parse JSON response
-	var jsonResponse struct {
-		AlbumIDs []string `json:"album_ids"`
-	}
-
-	if err := json.Unmarshal([]byte(responseText), &jsonResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w, response was: %s", err, responseText)
+	prompt, err := c.prompts.Render(PromptSuggestions, SuggestionPromptContext{
+		Photo:  c.photoPromptData(photo),
+		Albums: albumItems,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render suggestions prompt: %w", err)
 	}
+	return prompt, nil
+}
 
-	log.Printf("Generated %d album suggestions for photo %s", len(jsonResponse.AlbumIDs), photo.ID)
-
-	// Create a set of valid album IDs for validation
-	validAlbumIDs := make(map[string]bool)
-	for _, album := range albums {
-		validAlbumIDs[album.ID] = true
-	}
+var (
+	thinkBlockPattern = regexp.MustCompile(`(?s)<think>(.*?)</think>`)
+	// An unclosed <think> tag runs to the end of the text
+	openThinkPattern = regexp.MustCompile(`(?s)<think>(.*)`)
+)
 
-	// Filter and validate album IDs
-	var suggestions []string
-	for _, albumID := range jsonResponse.AlbumIDs {
-		if validAlbumIDs[albumID] {
-			suggestions = append(suggestions, albumID)
-			if len(suggestions) >= 3 {
-				break
-			}
+// splitThinkTags separates the reasoning that models without native thinking support write in
+// <think> tags from the rest of the text
+func splitThinkTags(text string) (answer, reasoning string) {
+	var parts []string
+	for _, re := range []*regexp.Regexp{thinkBlockPattern, openThinkPattern} {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			parts = append(parts, strings.TrimSpace(m[1]))
 		}
+		text = re.ReplaceAllString(text, "")
 	}
-
-	return suggestions, nil
+	return strings.TrimSpace(text), joinReasoning(parts...)
 }
 
 // removeThinkTags removes <think> tags and their contents from text
 func removeThinkTags(text string) string {
-	// Remove <think>...</think> blocks (including multiline)
-	re := regexp.MustCompile(`(?s)<think>.*?</think>`)
-	cleaned := re.ReplaceAllString(text, "")
+	answer, _ := splitThinkTags(text)
+	return answer
+}
 
-	// Also remove standalone <think> tags without closing tags
-	re2 := regexp.MustCompile(`<think>.*`)
-	cleaned = re2.ReplaceAllString(cleaned, "")
+// joinReasoning joins the non-empty pieces of reasoning into paragraphs
+func joinReasoning(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "\n\n")
+}
 
-	// Clean up extra whitespace
-	cleaned = strings.TrimSpace(cleaned)
+// cleanTitle reduces a model response to a single title that fits Lychee's title column
+func cleanTitle(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.TrimPrefix(text, "Title:")
+	text = strings.Trim(strings.TrimSpace(text), "\"'`*“”‘’")
+	text = strings.TrimSuffix(strings.TrimSpace(text), ".")
 
-	return cleaned
+	// photos.title is varchar(100)
+	if runes := []rune(text); len(runes) > 100 {
+		text = strings.TrimSpace(string(runes[:100]))
+	}
+	return text
 }
 
 func formatTakenAt(takenAt sql.NullTime) string {
@@ -391,6 +516,21 @@ func formatTakenAt(takenAt sql.NullTime) string {
 	return takenAt.Time.Format("2006-01-02 15:04:05")
 }
 
+// photoDate returns the capture date of a photo, falling back to its upload date
+func photoDate(photo *database.Photo) string {
+	if photo.TakenAt.Valid {
+		return photo.TakenAt.Time.Format("2006-01-02")
+	}
+	return photo.CreatedAt.Format("2006-01-02")
+}
+
+func formatFloat(nf sql.NullFloat64, format string) string {
+	if !nf.Valid {
+		return "Unknown"
+	}
+	return fmt.Sprintf(format, nf.Float64)
+}
+
 func getStringValue(ns sql.NullString) string {
 	if ns.Valid {
 		return ns.String
@@ -453,88 +593,78 @@ func isMovieFile(photo *database.Photo, variant *database.SizeVariant) bool {
 	return false
 }
 
-// compactDescriptionsHierarchically applies recursive batch compression to reduce descriptions to manageable size
-func (c *Client) compactDescriptionsHierarchically(albumID string, descriptions []string) ([]string, error) {
-	if len(descriptions) <= maxDescriptionsBeforeCompaction {
+// compactDescriptionsHierarchically summarizes batches of descriptions until the list fits within
+// budget tokens. Batches are sized from the context window, see planBatches.
+func (c *Client) compactDescriptionsHierarchically(albumID string, descriptions []string, budget int, onPartial PartialFunc) ([]string, error) {
+	total := totalDescriptionTokens(descriptions)
+	if total <= budget {
 		return descriptions, nil
 	}
 
-	log.Printf("Starting hierarchical compaction for album %s with %d descriptions", albumID, len(descriptions))
+	// A single description can't be compacted any further, only shortened
+	if len(descriptions) == 1 {
+		return []string{truncateToTokens(descriptions[0], budget-listItemTokens)}, nil
+	}
 
-	// Create batches of descriptions
-	batches := make([][]string, 0)
-	for i := 0; i < len(descriptions); i += maxDescriptionsBeforeCompaction {
-		end := i + maxDescriptionsBeforeCompaction
-		if end > len(descriptions) {
-			end = len(descriptions)
-		}
-		batches = append(batches, descriptions[i:end])
+	batchBudget, err := c.compactionBudget()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render compaction prompt: %w", err)
 	}
 
-	log.Printf("Created %d batches of descriptions for album %s", len(batches), albumID)
+	batches := planBatches(descriptions, batchBudget)
+	log.Printf("Compacting album %s: %d descriptions (~%d tokens, budget %d) in %d batches", albumID, len(descriptions), total, budget, len(batches))
 
 	// Compress each batch
 	compressedBatches := make([]string, 0, len(batches))
 	for i, batch := range batches {
+		if len(batch) == 1 {
+			compressedBatches = append(compressedBatches, batch[0])
+			continue
+		}
+
 		log.Printf("Compressing batch %d/%d (%d descriptions) for album %s", i+1, len(batches), len(batch), albumID)
 
-		compressed, err := c.compressBatchDescriptions(albumID, batch, i+1)
+		compressed, err := c.compressBatchDescriptions(albumID, batch, i+1, onPartial)
 		if err != nil {
 			return nil, fmt.Errorf("failed to compress batch %d: %w", i+1, err)
 		}
 
 		compressedBatches = append(compressedBatches, compressed)
-		log.Printf("Successfully compressed batch %d for album %s (result length: %d chars)", i+1, albumID, len(compressed))
-	}
-
-	// If we still have too many compressed batches, recursively compress them
-	if len(compressedBatches) > maxDescriptionsBeforeCompaction {
-		log.Printf("Still have %d compressed batches for album %s, applying another level of compaction", len(compressedBatches), albumID)
-		return c.compactDescriptionsHierarchically(albumID, compressedBatches)
 	}
 
-	log.Printf("Hierarchical compaction complete for album %s: %d -> %d descriptions", albumID, len(descriptions), len(compressedBatches))
-	return compressedBatches, nil
+	// If the summaries still don't fit, compress them again
+	return c.compactDescriptionsHierarchically(albumID, compressedBatches, budget, onPartial)
 }
 
 // compressBatchDescriptions compresses a batch of descriptions into a single summary
-func (c *Client) compressBatchDescriptions(albumID string, descriptions []string, batchNumber int) (string, error) {
-	prompt := fmt.Sprintf(`Compress the following photo descriptions into a single, concise summary that captures the key themes, subjects, and characteristics across all photos:
-
-Photo descriptions:
-%s
-
-Create a unified summary that:
-- Identifies common subjects, themes, and visual elements
-- Captures the overall mood and style
-- Mentions key activities or events depicted
-- Notes any significant compositional or photographic patterns
-
-Keep the summary to 2-4 sentences maximum. Focus on what ties these photos together and their collective essence.
+func (c *Client) compressBatchDescriptions(albumID string, descriptions []string, batchNumber int, onPartial PartialFunc) (string, error) {
+	prompt, err := c.prompts.Render(PromptCompaction, CompactionPromptContext{Descriptions: descriptions})
+	if err != nil {
+		return "", fmt.Errorf("failed to render compaction prompt: %w", err)
+	}
 
-Provide only the summary, no additional text.`,
-		strings.Join(descriptions, "\n- "))
+	model := c.primaryModel(taskCompaction)
+	cacheKey := compactionCacheKey(model, prompt)
+	if cached, ok, err := c.db.GetCompactionSummary(cacheKey); err != nil {
+		log.Printf("Error reading compaction cache: %v", err)
+	} else if ok {
+		log.Printf("Using cached summary for batch %d of album %s", batchNumber, albumID)
+		return cached, nil
+	}
 
 	log.Printf("Compressing batch %d for album %s (prompt length: %d chars)", batchNumber, albumID, len(prompt))
 
-	// Build options for the request
-	options := c.buildOllamaOptions()
-
-	req := &api.GenerateRequest{
-		Model:   c.synthModel,
-		Prompt:  prompt,
-		Stream:  &[]bool{false}[0],
-		Options: options,
-	}
+	req := c.newChat(PromptCompaction, prompt, nil, nil)
 
-	ctx := context.Background()
-	compressed, err := c.generateWithRetry(ctx, req)
+	// Batch summaries are intermediate results of an album, so their reasoning isn't kept
+	compressed, err := c.generate(taskCompaction, subject{kind: PromptCompaction}, req, onPartial, requireText)
 	if err != nil {
 		return "", fmt.Errorf("failed to compress batch descriptions after retries: %w", err)
 	}
 
-	// Remove <think> tags and their contents
-	compressed = removeThinkTags(compressed)
+	if err := c.db.SaveCompactionSummary(cacheKey, model, compressed); err != nil {
+		log.Printf("Error saving compaction summary: %v", err)
+	}
 
 	log.Printf("Successfully compressed batch %d for album %s (%d chars)", batchNumber, albumID, len(compressed))
 	return compressed, nil
@@ -0,0 +1,78 @@
+package ollama
+
+import (
+	"fmt"
+	"log"
+
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/images"
+
+	"github.com/ollama/ollama/api"
+)
+
+func (c *Client) contactSheetEnabled() bool {
+	return c.albums != nil && !c.albums.ContactSheet.Disabled
+}
+
+// BuildContactSheet lays out representative photos of an album in a grid, leaving out movies
+func (c *Client) BuildContactSheet(photos []database.Photo) (*images.ContactSheet, error) {
+	byID := make(map[string]*database.Photo, len(photos))
+	var candidates []images.SheetPhoto
+	for i := range photos {
+		photo := &photos[i]
+		if isMovieFile(photo, nil) {
+			continue
+		}
+		byID[photo.ID] = photo
+
+		takenAt := photo.CreatedAt
+		if photo.TakenAt.Valid {
+			takenAt = photo.TakenAt.Time
+		}
+		candidates = append(candidates, images.SheetPhoto{ID: photo.ID, TakenAt: takenAt})
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("album has no photos to show")
+	}
+
+	return c.imageFetcher.BuildContactSheet(candidates, func(photoID string) (*database.SizeVariant, error) {
+		variant, err := c.db.GetPhotoSizeVariant(photoID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image variant: %w", err)
+		}
+		if isMovieFile(byID[photoID], variant) {
+			return nil, fmt.Errorf("skipping movie file (path: %s)", variant.ShortPath)
+		}
+		return variant, nil
+	}, &c.albums.ContactSheet)
+}
+
+// describeContactSheet asks the vision model to describe an album from its contact sheet and
+// the summary written from its photo descriptions
+func (c *Client) describeContactSheet(album *database.Album, photos []database.Photo, summary string, dates, places []string, onPartial PartialFunc) (string, error) {
+	sheet, err := c.BuildContactSheet(photos)
+	if err != nil {
+		return "", fmt.Errorf("failed to build contact sheet: %w", err)
+	}
+	log.Printf("Describing album %s from a contact sheet of %d photos", album.ID, len(sheet.PhotoIDs))
+
+	prompt, err := c.prompts.Render(PromptContactSheet, ContactSheetPromptContext{
+		Summary:   summary,
+		Photos:    len(sheet.PhotoIDs),
+		Columns:   sheet.Columns,
+		Rows:      sheet.Rows,
+		DateRange: DateRange{Start: getMinDate(dates), End: getMaxDate(dates)},
+		Places:    places,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render contact sheet prompt: %w", err)
+	}
+
+	req := c.newChat(PromptContactSheet, prompt, []api.ImageData{sheet.Image}, nil)
+
+	description, err := c.generate(taskContactSheet, subject{kind: PromptContactSheet, id: album.ID}, req, onPartial, requireText)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe contact sheet: %w", err)
+	}
+	return description, nil
+}
@@ -0,0 +1,104 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/ollama/ollama/api"
+)
+
+// errorClass tells how a failed request should be handled
+type errorClass int
+
+const (
+	// errorPermanent failures will fail the same way again, e.g. an unknown model or a bad request
+	errorPermanent errorClass = iota
+	// errorTransient failures may succeed on retry, e.g. a crashed runner or a timeout
+	errorTransient
+	// errorUnreachable means the endpoint could not be contacted at all
+	errorUnreachable
+)
+
+func (c errorClass) String() string {
+	switch c {
+	case errorTransient:
+		return "transient"
+	case errorUnreachable:
+		return "unreachable"
+	default:
+		return "permanent"
+	}
+}
+
+// transientMessages are fragments of errors Ollama reports in the response stream that are
+// worth retrying. Other stream errors, like "model not found", are treated as permanent.
+var transientMessages = []string{
+	"server busy",
+	"runner process has terminated",
+	"runner process no longer running",
+	"timed out",
+	"connection reset",
+	"unexpected eof",
+	"try again",
+}
+
+// classifyError decides whether a failed request is worth retrying
+func classifyError(err error) errorClass {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return errorPermanent
+	}
+
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests,
+			statusErr.StatusCode == http.StatusRequestTimeout,
+			statusErr.StatusCode >= http.StatusInternalServerError:
+			return errorTransient
+		default:
+			return errorPermanent
+		}
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return errorUnreachable
+	case errors.As(err, &dnsErr):
+		return errorUnreachable
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return errorUnreachable
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return errorTransient
+	case errors.As(err, &netErr):
+		return errorTransient
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range transientMessages {
+		if strings.Contains(msg, fragment) {
+			return errorTransient
+		}
+	}
+
+	return errorPermanent
+}
+
+// isThinkingUnsupported reports whether a request failed because it asked a model without
+// reasoning support to think
+func isThinkingUnsupported(err error) bool {
+	var statusErr api.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(statusErr.ErrorMessage, "does not support thinking")
+}
@@ -0,0 +1,72 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestClassifyError(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	read := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("use of closed network connection")}
+
+	tests := []struct {
+		name string
+		err  error
+		want errorClass
+	}{
+		{"nil", nil, errorPermanent},
+		{"bad request", api.StatusError{StatusCode: 400, ErrorMessage: "invalid options"}, errorPermanent},
+		{"model not found", api.StatusError{StatusCode: 404, ErrorMessage: `model "llava" not found, try pulling it first`}, errorPermanent},
+		{"request timeout", api.StatusError{StatusCode: 408}, errorTransient},
+		{"too many requests", api.StatusError{StatusCode: 429, ErrorMessage: "server busy"}, errorTransient},
+		{"server error", api.StatusError{StatusCode: 500, ErrorMessage: "llama runner process has terminated"}, errorTransient},
+		{"bad gateway", api.StatusError{StatusCode: 502}, errorTransient},
+		{"wrapped status", fmt.Errorf("chat: %w", api.StatusError{StatusCode: 503}), errorTransient},
+		{"dial", dial, errorUnreachable},
+		{"connection refused", fmt.Errorf("post: %w", syscall.ECONNREFUSED), errorUnreachable},
+		{"host unreachable", syscall.EHOSTUNREACH, errorUnreachable},
+		{"dns", &net.DNSError{Err: "no such host", Name: "ollama.invalid", IsNotFound: true}, errorUnreachable},
+		{"read on open connection", read, errorTransient},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), errorTransient},
+		{"unexpected eof", io.ErrUnexpectedEOF, errorTransient},
+		{"canceled", context.Canceled, errorPermanent},
+		{"wrapped canceled", fmt.Errorf("chat: %w", context.Canceled), errorPermanent},
+		{"deadline exceeded", context.DeadlineExceeded, errorTransient},
+		{"transient stream error", errors.New("an error was encountered while running the model: server busy, please try again"), errorTransient},
+		{"transient message case", errors.New("Runner Process No Longer Running"), errorTransient},
+		{"permanent stream error", errors.New("model requires more system memory than is available"), errorPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsThinkingUnsupported(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{api.StatusError{StatusCode: 400, ErrorMessage: `"llava:7b" does not support thinking`}, true},
+		{fmt.Errorf("chat: %w", api.StatusError{StatusCode: 400, ErrorMessage: "registry.ollama.ai/library/llava:7b does not support thinking"}), true},
+		{api.StatusError{StatusCode: 500, ErrorMessage: "does not support thinking"}, false},
+		{api.StatusError{StatusCode: 400, ErrorMessage: "invalid options"}, false},
+		{errors.New("does not support thinking"), false},
+	}
+	for _, tt := range tests {
+		if got := isThinkingUnsupported(tt.err); got != tt.want {
+			t.Errorf("isThinkingUnsupported(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+// maxFacetSubjects is the most subjects kept for an album
+const maxFacetSubjects = 5
+
+// albumPromptData returns what the album's owner wrote about it. Sub-album titles that can't be
+// read are left out.
+func (c *Client) albumPromptData(album *database.Album) AlbumPromptData {
+	data := AlbumPromptData{
+		Title:       album.Title,
+		Description: strings.TrimSpace(album.Description.String),
+		Copyright:   strings.TrimSpace(album.Copyright.String),
+		NSFW:        album.IsNsfw,
+	}
+	subAlbums, err := c.db.GetSubAlbumTitles(album.ID)
+	if err != nil {
+		log.Printf("Failed to get sub-albums of album %s: %v", album.ID, err)
+	}
+	data.SubAlbums = subAlbums
+	return data
+}
+
+// GenerateAlbumFacets breaks an album's generated description down into its theme and typical
+// subjects, and adds the places and dates of its photos
+func (c *Client) GenerateAlbumFacets(album *database.Album, photos []database.Photo, description string) (*database.AlbumFacets, error) {
+	_, dates, err := c.extractPhotoData(photos)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := c.buildAlbumFacetsPrompt(album, description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render album facets prompt: %w", err)
+	}
+
+	req := c.newChat(PromptAlbumFacets, prompt, nil, nil)
+	req.Format = jsonFormat
+
+	facets := &database.AlbumFacets{
+		AlbumID:   album.ID,
+		Places:    c.geocoder.Summarize(photos, maxPromptPlaces),
+		DateStart: getMinDate(dates),
+		DateEnd:   getMaxDate(dates),
+	}
+	_, err = c.generate(taskAlbumSynthesis, subject{kind: PromptAlbumFacets, id: album.ID}, req, nil, func(response string) (err error) {
+		facets.Theme, facets.Subjects, err = parseAlbumFacets(response)
+		if err != nil {
+			return c.validator.invalidJSON(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate album facets: %w", err)
+	}
+
+	return facets, nil
+}
+
+func (c *Client) buildAlbumFacetsPrompt(album *database.Album, description string) (string, error) {
+	return c.prompts.Render(PromptAlbumFacets, AlbumFacetsPromptContext{
+		Album:       c.albumPromptData(album),
+		Description: description,
+	})
+}
+
+// parseAlbumFacets reads the model's JSON answer, dropping empty and surplus subjects
+func parseAlbumFacets(responseText string) (theme string, subjects []string, err error) {
+	var facets struct {
+		Theme    string   `json:"theme"`
+		Subjects []string `json:"subjects"`
+	}
+	if err := json.Unmarshal([]byte(responseText), &facets); err != nil {
+		return "", nil, fmt.Errorf("failed to parse JSON response: %w, response was: %s", err, responseText)
+	}
+
+	theme = strings.Join(strings.Fields(facets.Theme), " ")
+	if theme == "" {
+		return "", nil, fmt.Errorf("album facets have no theme, response was: %s", responseText)
+	}
+	for _, s := range facets.Subjects {
+		if s = strings.Join(strings.Fields(s), " "); s != "" && len(subjects) < maxFacetSubjects {
+			subjects = append(subjects, s)
+		}
+	}
+
+	return theme, subjects, nil
+}
+
+// addAlbumFacets gives the albums listed in the suggestions prompt their compact form, if
+// enabled. Albums without facets keep their full description.
+func (c *Client) addAlbumFacets(albumItems []AlbumPromptItem) {
+	if !c.prompts.compactAlbums || len(albumItems) == 0 {
+		return
+	}
+	facets, err := c.db.GetAlbumFacets()
+	if err != nil {
+		log.Printf("Listing albums with their full descriptions: failed to get album facets: %v", err)
+		return
+	}
+	for i := range albumItems {
+		if f, ok := facets[albumItems[i].ID]; ok {
+			albumItems[i].Facets = &AlbumFacetsPromptData{
+				Theme:     f.Theme,
+				Subjects:  f.Subjects,
+				Places:    f.Places,
+				DateRange: DateRange{Start: f.DateStart, End: f.DateEnd},
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// ModelStatus describes one of the configured models as found on an Ollama endpoint
+type ModelStatus struct {
+	Endpoint  string `json:"endpoint"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`  // the tasks the model is used for
+	Image     bool   `json:"image"` // used for image analysis, so it needs vision support
+	Available bool   `json:"available"`
+	Vision    bool   `json:"vision"` // only meaningful for available models
+}
+
+// PullProgress reports the progress of a model download
+type PullProgress struct {
+	Endpoint  string `json:"endpoint"`
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	Completed int64  `json:"completed"`
+	Total     int64  `json:"total"`
+}
+
+// PullProgressFunc receives progress updates while models are pulled
+type PullProgressFunc func(PullProgress)
+
+// Endpoints returns the URLs of the Ollama endpoints the client talks to
+func (c *Client) Endpoints() []string {
+	urls := make([]string, len(c.pool.endpoints))
+	for i, e := range c.pool.endpoints {
+		urls[i] = e.url
+	}
+	return urls
+}
+
+// ServerVersion returns the version reported by an Ollama endpoint
+func (c *Client) ServerVersion(ctx context.Context, endpointURL string) (string, error) {
+	e, err := c.endpoint(endpointURL)
+	if err != nil {
+		return "", err
+	}
+	return e.client.Version(ctx)
+}
+
+// CheckModels reports whether the configured models are available on every Ollama endpoint,
+// and whether they can process images
+func (c *Client) CheckModels(ctx context.Context) ([]ModelStatus, error) {
+	var statuses []ModelStatus
+	for _, e := range c.pool.endpoints {
+		endpointStatuses, err := c.checkEndpointModels(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, endpointStatuses...)
+	}
+	return statuses, nil
+}
+
+func (c *Client) checkEndpointModels(ctx context.Context, e *endpoint) ([]ModelStatus, error) {
+	list, err := e.client.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models on %s: %w", e.url, err)
+	}
+
+	installed := make(map[string]bool)
+	for _, m := range list.Models {
+		installed[normalizeModelName(m.Name)] = true
+		installed[normalizeModelName(m.Model)] = true
+	}
+
+	models, tasksByModel := c.taskModels()
+	var statuses []ModelStatus
+	for _, model := range models {
+		tasks := tasksByModel[model]
+		statuses = append(statuses, ModelStatus{
+			Endpoint: e.url,
+			Name:     model,
+			Role:     roleName(tasks),
+			Image:    containsString(tasks, taskPhotoDescription) || containsString(tasks, taskContactSheet),
+		})
+	}
+
+	for i := range statuses {
+		statuses[i].Available = installed[normalizeModelName(statuses[i].Name)]
+		if !statuses[i].Available {
+			continue
+		}
+
+		vision, err := c.supportsVision(ctx, e, statuses[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		statuses[i].Vision = vision
+	}
+
+	return statuses, nil
+}
+
+// VerifyModels checks the configured models at startup. Missing models are returned for
+// pulling if autoPull is set, and cause an error otherwise. An image analysis model without
+// vision support only logs a warning, since detection depends on the model's metadata.
+func (c *Client) VerifyModels(ctx context.Context, autoPull bool) (missing []ModelStatus, err error) {
+	statuses, err := c.CheckModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, status := range statuses {
+		if !status.Available {
+			missing = append(missing, status)
+			names = append(names, status.Name+" on "+status.Endpoint)
+			continue
+		}
+		if status.Image && !status.Vision {
+			log.Printf("Warning: image analysis model %s does not appear to support images; photo descriptions will likely fail", status.Name)
+		}
+		log.Printf("Found model %s (%s) on %s", status.Name, status.Role, status.Endpoint)
+	}
+
+	if len(missing) > 0 && !autoPull {
+		return nil, fmt.Errorf("model(s) not available: %s; run `ollama pull` for each or set ollama.auto_pull",
+			strings.Join(names, ", "))
+	}
+
+	return missing, nil
+}
+
+// PullModels downloads the given models, reporting progress through onProgress, and warns if
+// the image analysis model turns out not to support images
+func (c *Client) PullModels(ctx context.Context, models []ModelStatus, onProgress PullProgressFunc) error {
+	for _, m := range models {
+		model := m.Name
+		e, err := c.endpoint(m.Endpoint)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Pulling model %s on %s", model, e.url)
+
+		lastStatus := ""
+		err = e.client.Pull(ctx, &api.PullRequest{Model: model}, func(resp api.ProgressResponse) error {
+			if resp.Status != lastStatus {
+				log.Printf("Pulling %s: %s", model, resp.Status)
+				lastStatus = resp.Status
+			}
+			if onProgress != nil {
+				onProgress(PullProgress{
+					Endpoint:  e.url,
+					Model:     model,
+					Status:    resp.Status,
+					Completed: resp.Completed,
+					Total:     resp.Total,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to pull model %s: %w", model, err)
+		}
+		c.forgetDigest(e, model)
+
+		if m.Image {
+			vision, err := c.supportsVision(ctx, e, model)
+			if err != nil {
+				return err
+			}
+			if !vision {
+				log.Printf("Warning: image analysis model %s does not appear to support images; photo descriptions will likely fail", model)
+			}
+		}
+	}
+
+	return nil
+}
+
+// supportsVision reports whether a model can take images, judging from its projector, its
+// architecture metadata and its model families
+func (c *Client) supportsVision(ctx context.Context, e *endpoint, model string) (bool, error) {
+	info, err := e.client.Show(ctx, &api.ShowRequest{Model: model})
+	if err != nil {
+		return false, fmt.Errorf("failed to show model %s: %w", model, err)
+	}
+
+	if len(info.ProjectorInfo) > 0 {
+		return true, nil
+	}
+	for key := range info.ModelInfo {
+		if strings.Contains(key, ".vision.") {
+			return true, nil
+		}
+	}
+	for _, family := range info.Details.Families {
+		if family == "clip" || family == "mllama" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// endpoint looks up a pool endpoint by URL
+func (c *Client) endpoint(endpointURL string) (*endpoint, error) {
+	for _, e := range c.pool.endpoints {
+		if e.url == endpointURL {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown Ollama endpoint %s", endpointURL)
+}
+
+// normalizeModelName adds the implicit :latest tag, so that "llava" matches "llava:latest"
+func normalizeModelName(name string) string {
+	if name == "" {
+		return ""
+	}
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		return name + ":latest"
+	}
+	return name
+}
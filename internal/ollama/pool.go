@@ -0,0 +1,266 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"lychee-ai-organizer/internal/config"
+
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// drainAfterFailures is the number of consecutive transient failures after which an endpoint is drained
+	drainAfterFailures = 3
+	// drainDuration is how long an endpoint drained for failing requests waits before it is probed
+	drainDuration = 30 * time.Second
+	// probeInterval is how often drained endpoints are checked for recovery
+	probeInterval = 5 * time.Second
+	// probeTimeout bounds a single health check
+	probeTimeout = 5 * time.Second
+)
+
+// Status reports whether any Ollama endpoint is available. While none is, requests wait and
+// jobs are effectively paused.
+type Status struct {
+	Available bool   `json:"available"`
+	Message   string `json:"message"`
+}
+
+// StatusFunc is called whenever Ollama availability changes
+type StatusFunc func(Status)
+
+// endpoint is one Ollama server in the pool
+type endpoint struct {
+	url         string
+	client      *api.Client
+	weight      int
+	maxInFlight int
+
+	inFlight     int
+	failures     int // consecutive transient failures
+	drained      bool
+	drainedUntil time.Time // earliest time of the next health check
+}
+
+// pool routes requests across Ollama endpoints. Each request goes to the healthy endpoint with
+// the lowest load relative to its weight that has a free slot.
+//
+// An endpoint that can't be contacted is drained at once; one that fails repeatedly with
+// transient errors is drained for drainDuration. Drained endpoints get no requests. A background
+// prober checks them with Ollama's heartbeat endpoint and returns them to rotation once they
+// answer. When every endpoint is drained the pool is unavailable: the circuit is open, requests
+// wait in acquire, and the status handler is told so the UI can show that jobs are paused.
+type pool struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+	changed   chan struct{} // closed and replaced whenever a slot frees up or health changes
+	probing   bool
+	available bool
+	onStatus  StatusFunc
+}
+
+func newPool(cfgs []config.EndpointConfig) (*pool, error) {
+	p := &pool{changed: make(chan struct{}), available: true}
+
+	for _, cfg := range cfgs {
+		baseURL, err := url.Parse(cfg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Ollama endpoint URL: %w", err)
+		}
+
+		weight := cfg.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+
+		p.endpoints = append(p.endpoints, &endpoint{
+			url:         cfg.URL,
+			client:      api.NewClient(baseURL, &http.Client{}),
+			weight:      weight,
+			maxInFlight: cfg.MaxInFlight,
+		})
+	}
+
+	if len(p.endpoints) == 0 {
+		return nil, fmt.Errorf("no Ollama endpoints configured")
+	}
+
+	return p, nil
+}
+
+// acquire waits for a healthy endpoint with a free slot. The caller must release it.
+func (p *pool) acquire(ctx context.Context) (*endpoint, error) {
+	for {
+		p.mu.Lock()
+		e := p.pick()
+		if e != nil {
+			e.inFlight++
+			p.mu.Unlock()
+			return e, nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// pick chooses the least loaded healthy endpoint with a free slot. Must be called with p.mu held.
+func (p *pool) pick() *endpoint {
+	var best *endpoint
+	var bestLoad float64
+
+	for _, e := range p.endpoints {
+		if e.drained {
+			continue
+		}
+		if e.maxInFlight > 0 && e.inFlight >= e.maxInFlight {
+			continue
+		}
+
+		load := float64(e.inFlight+1) / float64(e.weight)
+		if best == nil || load < bestLoad {
+			best, bestLoad = e, load
+		}
+	}
+
+	return best
+}
+
+// release returns an endpoint's slot and records the outcome of the request made with it
+func (p *pool) release(e *endpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.inFlight--
+
+	switch classifyError(err) {
+	case errorUnreachable:
+		if !e.drained {
+			log.Printf("Draining Ollama endpoint %s: %v", e.url, err)
+			p.drain(e, time.Now())
+		}
+	case errorTransient:
+		e.failures++
+		if e.failures >= drainAfterFailures && !e.drained {
+			log.Printf("Draining Ollama endpoint %s after %d consecutive failures: %v", e.url, e.failures, err)
+			p.drain(e, time.Now().Add(drainDuration))
+		}
+	default:
+		// Success, or a problem with the request rather than the endpoint
+		e.failures = 0
+	}
+
+	p.notifyLocked()
+}
+
+// abandon returns an endpoint's slot without recording an outcome, for a request ended by its
+// caller's deadline or cancellation, which says nothing about the endpoint
+func (p *pool) abandon(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.inFlight--
+	p.notifyLocked()
+}
+
+// drain takes an endpoint out of rotation until a health check after probeAfter succeeds.
+// Must be called with p.mu held.
+func (p *pool) drain(e *endpoint, probeAfter time.Time) {
+	e.drained = true
+	e.drainedUntil = probeAfter
+
+	if !p.probing {
+		p.probing = true
+		go p.probe()
+	}
+}
+
+// probe health-checks drained endpoints until all of them have recovered
+func (p *pool) probe() {
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.mu.Lock()
+		var due []*endpoint
+		drained := 0
+		now := time.Now()
+		for _, e := range p.endpoints {
+			if !e.drained {
+				continue
+			}
+			drained++
+			if !now.Before(e.drainedUntil) {
+				due = append(due, e)
+			}
+		}
+		if drained == 0 {
+			p.probing = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		for _, e := range due {
+			ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+			err := e.client.Heartbeat(ctx)
+			cancel()
+			if err != nil {
+				continue
+			}
+
+			p.mu.Lock()
+			log.Printf("Ollama endpoint %s is healthy again", e.url)
+			e.drained = false
+			e.failures = 0
+			p.notifyLocked()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// notifyLocked wakes up waiting requests and reports availability changes. Must be called with p.mu held.
+func (p *pool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+
+	available := false
+	for _, e := range p.endpoints {
+		if !e.drained {
+			available = true
+			break
+		}
+	}
+	if available == p.available {
+		return
+	}
+	p.available = available
+
+	status := Status{Available: true, Message: "Ollama is reachable again; resuming"}
+	if !available {
+		status = Status{Available: false, Message: "Ollama is unreachable; jobs are paused until it comes back"}
+	}
+	log.Print(status.Message)
+
+	if p.onStatus != nil {
+		go p.onStatus(status)
+	}
+}
+
+// isAvailable reports whether any endpoint is in rotation
+func (p *pool) isAvailable() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.available
+}
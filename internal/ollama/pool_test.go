@@ -0,0 +1,75 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"lychee-ai-organizer/internal/config"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestTimeoutDoesNotDrainEndpoint(t *testing.T) {
+	slow := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-slow:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(slow)
+
+	p, err := newPool([]config.EndpointConfig{{URL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{pool: p, config: &config.OllamaConfig{Retry: config.RetryConfig{Attempts: 1}}}
+
+	for i := 0; i < drainAfterFailures+1; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := c.generateWithRetry(ctx, &api.ChatRequest{Model: "llava:7b"}, nil)
+		cancel()
+		if err == nil {
+			t.Fatal("expected the request to time out")
+		}
+	}
+
+	e := p.endpoints[0]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e.drained || e.failures != 0 || e.inFlight != 0 {
+		t.Errorf("after timeouts: drained = %v, failures = %d, in flight = %d; want a healthy idle endpoint", e.drained, e.failures, e.inFlight)
+	}
+}
+
+func TestServerErrorsDrainEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "llama runner process has terminated"})
+	}))
+	defer server.Close()
+
+	p, err := newPool([]config.EndpointConfig{{URL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{pool: p, config: &config.OllamaConfig{Retry: config.RetryConfig{Attempts: 1}}}
+
+	for i := 0; i < drainAfterFailures; i++ {
+		if _, err := c.generateWithRetry(context.Background(), &api.ChatRequest{Model: "llava:7b"}, nil); err == nil {
+			t.Fatal("expected the request to fail")
+		}
+	}
+
+	e := p.endpoints[0]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !e.drained {
+		t.Errorf("endpoint not drained after %d server errors", e.failures)
+	}
+}
@@ -0,0 +1,335 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"lychee-ai-organizer/internal/config"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Tasks the client runs, each with its own chain of model profiles
+const (
+	taskPhotoDescription = "photo_description"
+	taskAlbumSynthesis   = "album_synthesis"
+	taskCompaction       = "compaction"
+	taskSuggestion       = "suggestion"
+	taskTranslation      = "translation"
+	taskContactSheet     = "contact_sheet"
+)
+
+// errEmptyResponse rejects a response with nothing usable in it
+var errEmptyResponse = errors.New("model returned an empty response")
+
+// modelProfile is a model with the settings to request it with
+type modelProfile struct {
+	name          string // profile name from config, or "default"
+	model         string
+	options       map[string]interface{}
+	keepAlive     *api.Duration
+	timeout       time.Duration
+	think         *api.ThinkValue // nil leaves it to the model
+	keepReasoning bool
+}
+
+// subject identifies what a request produces, so that its answer can be validated and its
+// reasoning and validation failures stored. Without an id nothing is stored.
+type subject struct {
+	kind     string // the prompt name
+	id       string // the photo, album or cluster; "<id>/<language>" for translations
+	language string // the language the answer is written in; empty for the primary language
+}
+
+// newTaskProfiles resolves the profile chain of every task. Tasks without a chain use the image
+// analysis or description synthesis model with the global options.
+func (c *Client) newTaskProfiles() map[string][]modelProfile {
+	global := c.buildOllamaOptions()
+
+	chains := map[string][]string{
+		taskPhotoDescription: c.config.Tasks.PhotoDescription,
+		taskAlbumSynthesis:   c.config.Tasks.AlbumSynthesis,
+		taskCompaction:       c.config.Tasks.Compaction,
+		taskSuggestion:       c.config.Tasks.Suggestion,
+		taskTranslation:      c.config.Tasks.Translation,
+		taskContactSheet:     c.config.Tasks.ContactSheet,
+	}
+
+	tasks := make(map[string][]modelProfile, len(chains))
+	for task, chain := range chains {
+		if len(chain) == 0 {
+			model := c.config.DescriptionSynthesisModel
+			if task == taskPhotoDescription || task == taskContactSheet {
+				model = c.config.ImageAnalysisModel
+			}
+			tasks[task] = []modelProfile{{
+				name:          "default",
+				model:         model,
+				options:       global,
+				think:         thinkValue(c.config.Think),
+				keepReasoning: c.config.KeepReasoning,
+			}}
+			continue
+		}
+
+		for _, name := range chain {
+			cfg := c.config.Profiles[name]
+			profile := modelProfile{
+				name:    name,
+				model:   cfg.Model,
+				options: make(map[string]interface{}, len(global)+len(cfg.Options)),
+				timeout: time.Duration(cfg.TimeoutSeconds * float64(time.Second)),
+				think:   thinkValue(c.config.Think),
+				// Reasoning kept globally is kept for every profile
+				keepReasoning: cfg.KeepReasoning || c.config.KeepReasoning,
+			}
+			if cfg.Think != "" {
+				profile.think = thinkValue(cfg.Think)
+			}
+			for key, value := range global {
+				profile.options[key] = value
+			}
+			for key, value := range cfg.Options {
+				profile.options[key] = value
+			}
+			if cfg.KeepAlive != "" {
+				// Validated with the config
+				d, _ := config.ParseKeepAlive(cfg.KeepAlive)
+				profile.keepAlive = &api.Duration{Duration: d}
+			}
+			tasks[task] = append(tasks[task], profile)
+		}
+	}
+
+	return tasks
+}
+
+// thinkValue converts a think setting to the request field
+func thinkValue(think config.Think) *api.ThinkValue {
+	switch think {
+	case "":
+		return nil
+	case "true", "false":
+		return &api.ThinkValue{Value: think == "true"}
+	default:
+		return &api.ThinkValue{Value: string(think)}
+	}
+}
+
+// primaryModel is the model a task is normally run with
+func (c *Client) primaryModel(task string) string {
+	return c.tasks[task][0].model
+}
+
+// generate runs a request for a task with the task's profiles in turn. The request's model,
+// options, keep_alive and think setting are taken from the profile. A profile that fails, or
+// whose response can't be made valid or accept rejects, hands over to the next one; if all do,
+// the last error is returned. accept may be nil.
+//
+// The response is returned without the model's reasoning, whether the model reported it apart
+// or inline in <think> tags. If the profile keeps reasoning, it is stored for the subject.
+func (c *Client) generate(task string, about subject, req *api.ChatRequest, onPartial PartialFunc, accept func(response string) error) (string, error) {
+	chain := c.tasks[task]
+
+	var lastErr error
+	for i, profile := range chain {
+		response, err := c.generateWithProfile(profile, about, req, onPartial, accept)
+		if err == nil {
+			return response, nil
+		}
+
+		lastErr = fmt.Errorf("profile %s (%s): %w", profile.name, profile.model, err)
+		if i < len(chain)-1 {
+			log.Printf("Task %s failed with %v; falling back to profile %s (%s)", task, lastErr, chain[i+1].name, chain[i+1].model)
+		}
+	}
+
+	return "", lastErr
+}
+
+// generateWithProfile runs a request with one profile. An answer that fails validation, or
+// that accept rejects as invalid output, is sent back to the model together with the problem,
+// up to the configured number of repair attempts. If it still isn't valid, the problem is
+// recorded as the subject's validation failure.
+func (c *Client) generateWithProfile(profile modelProfile, about subject, req *api.ChatRequest, onPartial PartialFunc, accept func(response string) error) (string, error) {
+	r := *req
+	r.Model = profile.model
+	r.Options = profile.options
+	r.KeepAlive = profile.keepAlive
+	if !c.thinkingUnsupported(profile.model) {
+		r.Think = profile.think
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if profile.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, profile.timeout)
+	}
+	defer cancel()
+
+	start := time.Now()
+	for repairs := 0; ; repairs++ {
+		gen, err := c.generateWithRetry(ctx, &r, onPartial)
+		if r.Think != nil && isThinkingUnsupported(err) {
+			// Thinking was asked for but the model can't; ask again without it
+			log.Printf("Model %s does not support thinking; requesting it without", profile.model)
+			c.setThinkingUnsupported(profile.model)
+			r.Think = nil
+			gen, err = c.generateWithRetry(ctx, &r, onPartial)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		response, inline := splitThinkTags(gen.response)
+		err = c.validator.check(about.kind, c.outputLanguage(about), response)
+		if err == nil && accept != nil {
+			err = accept(response)
+		}
+		if err == nil {
+			if profile.keepReasoning && about.id != "" {
+				c.saveReasoning(about, profile.model, joinReasoning(gen.thinking, inline))
+			}
+			if about.id != "" {
+				c.saveProvenance(about, &r, gen.endpoint, time.Since(start))
+			}
+			c.clearValidationFailure(about)
+			return response, nil
+		}
+
+		var invalid *invalidOutputError
+		if !errors.As(err, &invalid) || !c.validator.enabled() {
+			return "", err
+		}
+		if repairs >= c.validator.repairs {
+			c.recordValidationFailure(about, profile.model, invalid, response)
+			return "", err
+		}
+
+		repair, rerr := c.prompts.Repair(about.kind, invalid.problem)
+		if rerr != nil {
+			log.Printf("Error rendering repair prompt: %v", rerr)
+			return "", err
+		}
+		log.Printf("Answer of %s to %s prompt %s; asking for a correction", profile.model, about.kind, invalid.problem)
+		// The full slice expression makes append copy, leaving the caller's request alone
+		r.Messages = append(r.Messages[:len(r.Messages):len(r.Messages)],
+			api.Message{Role: "assistant", Content: response},
+			api.Message{Role: "user", Content: repair})
+	}
+}
+
+// outputLanguage is the language the answer about a subject is asked for in
+func (c *Client) outputLanguage(about subject) string {
+	if about.language != "" {
+		return about.language
+	}
+	return c.prompts.Primary()
+}
+
+// requireText accepts any response with text in it
+func requireText(response string) error {
+	if response == "" {
+		return errEmptyResponse
+	}
+	return nil
+}
+
+// thinkingUnsupported reports whether a model has refused a request to think
+func (c *Client) thinkingUnsupported(model string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.noThinking[model]
+}
+
+func (c *Client) setThinkingUnsupported(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.noThinking[model] = true
+}
+
+// recordValidationFailure stores why the answer about a subject couldn't be repaired
+func (c *Client) recordValidationFailure(about subject, model string, invalid *invalidOutputError, response string) {
+	log.Printf("Answer of %s to %s prompt for %q could not be repaired: it %s", model, about.kind, about.id, invalid.problem)
+	if about.id == "" {
+		return
+	}
+	if err := c.db.SaveValidationFailure(about.kind, about.id, model, "answer "+invalid.problem, response); err != nil {
+		log.Printf("Error saving validation failure for %s %s: %v", about.kind, about.id, err)
+	}
+}
+
+// clearValidationFailure forgets an earlier validation failure once a valid answer is found
+func (c *Client) clearValidationFailure(about subject) {
+	if about.id == "" || !c.validator.enabled() {
+		return
+	}
+	if err := c.db.DeleteValidationFailure(about.kind, about.id); err != nil {
+		log.Printf("Error clearing validation failure for %s %s: %v", about.kind, about.id, err)
+	}
+}
+
+// saveReasoning stores the reasoning behind a response. Failing to store it doesn't fail the
+// response.
+func (c *Client) saveReasoning(about subject, model, reasoning string) {
+	if reasoning == "" {
+		return
+	}
+	if err := c.db.SaveReasoning(about.kind, about.id, model, reasoning); err != nil {
+		log.Printf("Error saving %s reasoning for %s: %v", about.kind, about.id, err)
+	}
+}
+
+// taskModels lists every model used by some task, with the tasks using it. Tasks that are
+// turned off are left out.
+func (c *Client) taskModels() (models []string, tasksByModel map[string][]string) {
+	tasksByModel = make(map[string][]string)
+	for task, chain := range c.tasks {
+		if !c.taskUsed(task) {
+			continue
+		}
+		for _, profile := range chain {
+			if _, ok := tasksByModel[profile.model]; !ok {
+				models = append(models, profile.model)
+			}
+			if !containsString(tasksByModel[profile.model], task) {
+				tasksByModel[profile.model] = append(tasksByModel[profile.model], task)
+			}
+		}
+	}
+	sort.Strings(models)
+	for _, tasks := range tasksByModel {
+		sort.Strings(tasks)
+	}
+	return models, tasksByModel
+}
+
+// taskUsed reports whether a task can run with the current config: translations need a second
+// output language, and contact sheets must not be disabled
+func (c *Client) taskUsed(task string) bool {
+	switch task {
+	case taskTranslation:
+		return c.prompts != nil && len(c.prompts.Languages()) > 1
+	case taskContactSheet:
+		return c.contactSheetEnabled()
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// roleName describes the tasks a model is used for
+func roleName(tasks []string) string {
+	return strings.ReplaceAll(strings.Join(tasks, ", "), "_", " ")
+}
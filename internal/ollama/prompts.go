@@ -0,0 +1,522 @@
+package ollama
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/language"
+)
+
+//go:embed prompts/*.tmpl
+var defaultPromptFiles embed.FS
+
+// Prompt names; each has a built-in template in prompts/<name>.tmpl which can be overridden from config
+const (
+	PromptPhotoDescription = "photo_description"
+	PromptPhotoTitle       = "photo_title"
+	PromptAlbumDescription = "album_description"
+	PromptCompaction       = "compaction"
+	PromptSuggestions      = "suggestions"
+	PromptAlbumProposal    = "album_proposal"
+	PromptTranslation      = "translation"
+	PromptContactSheet     = "contact_sheet"
+	PromptAlbumFacets      = "album_facets"
+
+	// promptSystem is the template of the system message sent with every prompt
+	promptSystem = "system"
+	// promptRepair is the template of the follow-up asking the model to correct an invalid answer
+	promptRepair = "repair"
+)
+
+// promptHashLength is the number of hex digits kept of a prompt's hash
+const promptHashLength = 16
+
+// maxPromptPlaces is the number of places listed when summarizing where a set of photos was taken
+const maxPromptPlaces = 5
+
+// PhotoPromptData describes a photo to prompt templates. Unknown and redacted values are
+// rendered as "Unknown".
+type PhotoPromptData struct {
+	ID          string
+	Title       string
+	TakenAt     string // 2006-01-02 15:04:05, or "Unknown"
+	Date        string // taken_at date, falling back to created_at
+	Make        string
+	Model       string
+	Location    string // Lychee's location, or the place geocoded from the coordinates
+	Description string // existing AI description, empty if none
+	EXIF        EXIFPromptData
+	Details     []PromptField // the configured photo fields that are known, in order
+}
+
+// EXIFPromptData holds the photo's exposure and position metadata
+type EXIFPromptData struct {
+	Lens      string
+	Focal     string
+	Aperture  string
+	Shutter   string
+	ISO       string
+	Altitude  string
+	Direction string
+	Latitude  string
+	Longitude string
+}
+
+// DateRange is the first and last capture date (YYYY-MM-DD) of a set of photos
+type DateRange struct {
+	Start string
+	End   string
+}
+
+// AlbumPromptItem describes an album in the suggestion prompt
+type AlbumPromptItem struct {
+	ID          string
+	Title       string
+	Description string
+	Facets      *AlbumFacetsPromptData // nil unless albums are listed in compact form and this one has facets
+}
+
+// AlbumFacetsPromptData is the compact form of an album's description
+type AlbumFacetsPromptData struct {
+	Theme     string
+	Subjects  []string
+	Places    []string // may be empty
+	DateRange DateRange
+}
+
+// AlbumPromptData is what the album's owner wrote about it in Lychee. Empty and redacted values
+// are left empty.
+type AlbumPromptData struct {
+	Title       string
+	Description string // the album's own description, not the AI one
+	Copyright   string
+	SubAlbums   []string // titles of the album's direct sub-albums
+	NSFW        bool     // marked as sensitive in Lychee
+}
+
+// PhotoPromptContext is the data passed to the photo_description and photo_title templates
+type PhotoPromptContext struct {
+	Photo PhotoPromptData
+}
+
+// AlbumPromptContext is the data passed to the album_description template
+type AlbumPromptContext struct {
+	Album        AlbumPromptData
+	Descriptions []string
+	DateRange    DateRange
+	Places       []string // where the photos were taken, most photos first; may be empty
+	SampleSize   int      // how many descriptions were sampled; 0 if all are included
+	PhotoCount   int      // how many photos of the album have a description
+}
+
+// CompactionPromptContext is the data passed to the compaction template
+type CompactionPromptContext struct {
+	Descriptions []string
+}
+
+// AlbumProposalPromptContext is the data passed to the album_proposal template
+type AlbumProposalPromptContext struct {
+	Descriptions []string
+	DateRange    DateRange
+	Places       []string // where the photos were taken, most photos first; may be empty
+}
+
+// SuggestionPromptContext is the data passed to the suggestions template
+type SuggestionPromptContext struct {
+	Photo  PhotoPromptData
+	Albums []AlbumPromptItem
+}
+
+// ContactSheetPromptContext is the data passed to the contact_sheet template, which is sent to
+// the vision model together with the contact sheet
+type ContactSheetPromptContext struct {
+	Summary   string // the album description written from the photo descriptions
+	Photos    int    // the number of photos on the sheet
+	Columns   int
+	Rows      int
+	DateRange DateRange
+	Places    []string // where the album's photos were taken, most photos first; may be empty
+}
+
+// AlbumFacetsPromptContext is the data passed to the album_facets template
+type AlbumFacetsPromptContext struct {
+	Album       AlbumPromptData
+	Description string // the album's generated description
+}
+
+// TranslationPromptContext is the data passed to the translation template
+type TranslationPromptContext struct {
+	Item     string // what the text describes: "photo" or "album"
+	Language string // the English name of the language to translate into, e.g. "German"
+	Text     string
+}
+
+// SystemPromptContext is the data passed to the system template
+type SystemPromptContext struct {
+	Prompt   string // the name of the prompt the system message is sent with
+	Language string // the English name of the language to write in, e.g. "English"
+}
+
+// RepairPromptContext is the data passed to the repair template
+type RepairPromptContext struct {
+	Prompt  string // the name of the prompt that was answered
+	Problem string // what is wrong, completing "Your previous answer ...", e.g. "is empty"
+}
+
+// Prompts holds the parsed prompt templates and the policy for the data rendered into them
+type Prompts struct {
+	templates   map[string]*template.Template
+	system      map[string]map[string]string // rendered system message by prompt name and language
+	hashes      map[string]string            // identifies the template and system template by prompt name
+	languages   []string                     // output languages, the primary one first
+	photoFields []string
+	redaction   redaction
+	examples    *config.ExamplesConfig
+	// compactAlbums lists albums in the suggestions prompt by their facets
+	compactAlbums bool
+}
+
+// LoadPrompts parses the built-in prompt templates, replacing any that are overridden in config,
+// and validates each one by rendering it against sample data. remote tells whether prompts may
+// be sent to a remote endpoint, which puts the redaction policy into effect.
+func LoadPrompts(cfg *config.PromptsConfig, remote bool) (*Prompts, error) {
+	if err := validatePhotoFields(cfg.PhotoFields); err != nil {
+		return nil, err
+	}
+
+	overrides := map[string]string{
+		PromptPhotoDescription: cfg.PhotoDescription,
+		PromptPhotoTitle:       cfg.PhotoTitle,
+		PromptAlbumDescription: cfg.AlbumDescription,
+		PromptCompaction:       cfg.Compaction,
+		PromptSuggestions:      cfg.Suggestions,
+		PromptAlbumProposal:    cfg.AlbumProposal,
+		promptSystem:           cfg.System,
+		promptRepair:           cfg.Repair,
+		PromptTranslation:      cfg.Translation,
+		PromptContactSheet:     cfg.ContactSheet,
+		PromptAlbumFacets:      cfg.AlbumFacets,
+	}
+
+	p := &Prompts{
+		templates:     make(map[string]*template.Template),
+		system:        make(map[string]map[string]string),
+		hashes:        make(map[string]string),
+		languages:     cfg.Languages,
+		photoFields:   cfg.PhotoFields,
+		redaction:     newRedaction(&cfg.Redaction, remote),
+		examples:      &cfg.Examples,
+		compactAlbums: cfg.CompactAlbums,
+	}
+
+	sources := make(map[string][]byte, len(overrides))
+	for name, path := range overrides {
+		var source []byte
+		var err error
+		if path != "" {
+			source, err = os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s prompt template: %w", name, err)
+			}
+		} else {
+			source, err = defaultPromptFiles.ReadFile("prompts/" + name + ".tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("missing built-in %s prompt template: %w", name, err)
+			}
+		}
+
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(string(source))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s prompt template: %w", name, err)
+		}
+
+		p.templates[name] = tmpl
+		sources[name] = source
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	for _, code := range p.languages {
+		if !language.Known(code) {
+			log.Printf("Unknown output language %q; prompts name it by its code and validation only rejects answers in another script", code)
+		}
+	}
+
+	// The system message doesn't depend on the photo or album, so it is rendered once for every
+	// language
+	for name := range overrides {
+		if name == promptSystem || name == promptRepair {
+			continue
+		}
+		hash := sha256.New()
+		hash.Write(sources[name])
+		hash.Write([]byte{0})
+		hash.Write(sources[promptSystem])
+		p.hashes[name] = hex.EncodeToString(hash.Sum(nil))[:promptHashLength]
+
+		p.system[name] = make(map[string]string, len(p.languages))
+		for _, code := range p.languages {
+			var buf bytes.Buffer
+			if err := p.templates[promptSystem].Execute(&buf, SystemPromptContext{Prompt: name, Language: language.Name(code)}); err != nil {
+				return nil, fmt.Errorf("invalid system prompt template: %w", err)
+			}
+			p.system[name][code] = strings.TrimSpace(buf.String())
+		}
+	}
+
+	return p, nil
+}
+
+// Repair renders the follow-up asking the model to correct its invalid answer to the named prompt
+func (p *Prompts) Repair(name, problem string) (string, error) {
+	return p.Render(promptRepair, RepairPromptContext{Prompt: name, Problem: problem})
+}
+
+// System returns the system message sent with the named prompt when writing in a language, or
+// in the primary language if language is empty; empty for none
+func (p *Prompts) System(name, language string) string {
+	if language == "" {
+		language = p.Primary()
+	}
+	return p.system[name][language]
+}
+
+// Hash identifies the version of the named prompt: it changes whenever its template or the
+// system template does
+func (p *Prompts) Hash(name string) string {
+	return p.hashes[name]
+}
+
+// Primary returns the language everything is generated in. Descriptions in the other languages
+// are translated from it.
+func (p *Prompts) Primary() string {
+	return p.languages[0]
+}
+
+// Languages returns the output languages, the primary one first
+func (p *Prompts) Languages() []string {
+	return p.languages
+}
+
+// validate renders every template against sample data, so that references to
+// nonexistent fields are reported at startup rather than during a job
+func (p *Prompts) validate() error {
+	for name, data := range samplePromptData() {
+		if _, err := p.Render(name, data); err != nil {
+			return fmt.Errorf("invalid %s prompt template: %w", name, err)
+		}
+		if _, err := p.Repair(name, "is empty"); err != nil {
+			return fmt.Errorf("invalid repair prompt template: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// samplePromptData returns sample data for every prompt template that is rendered with data
+func samplePromptData() map[string]interface{} {
+	photo := PhotoPromptData{
+		ID:          "sample",
+		Title:       "Sunset over the harbor",
+		TakenAt:     "2024-05-01 19:45:00",
+		Date:        "2024-05-01",
+		Make:        "Canon",
+		Model:       "EOS R6",
+		Location:    "Lisbon, Portugal",
+		Description: "Boats rest in a calm harbor at sunset.",
+		EXIF: EXIFPromptData{
+			Lens: "RF 24-105mm", Focal: "50 mm", Aperture: "f/8", Shutter: "1/250 s", ISO: "100",
+			Altitude: "12 m", Direction: "270°", Latitude: "38.7071", Longitude: "-9.1355",
+		},
+	}
+
+	album := AlbumPromptData{
+		Title:       "Lisbon 2024",
+		Description: "Our spring week in Lisbon",
+		Copyright:   "Jane Doe",
+		SubAlbums:   []string{"Belém", "Alfama"},
+		NSFW:        true,
+	}
+
+	return map[string]interface{}{
+		PromptPhotoDescription: PhotoPromptContext{Photo: photo},
+		PromptPhotoTitle:       PhotoPromptContext{Photo: photo},
+		PromptAlbumDescription: AlbumPromptContext{
+			Album:        album,
+			Descriptions: []string{photo.Description},
+			DateRange:    DateRange{Start: "2024-05-01", End: "2024-05-03"},
+			Places:       []string{"Lisbon, Portugal"},
+			SampleSize:   200,
+			PhotoCount:   1250,
+		},
+		PromptCompaction: CompactionPromptContext{Descriptions: []string{photo.Description}},
+		PromptAlbumProposal: AlbumProposalPromptContext{
+			Descriptions: []string{photo.Description},
+			DateRange:    DateRange{Start: "2024-05-01", End: "2024-05-03"},
+			Places:       []string{"Lisbon, Portugal"},
+		},
+		PromptSuggestions: SuggestionPromptContext{
+			Photo: photo,
+			Albums: []AlbumPromptItem{
+				{ID: "album1", Title: "Lisbon 2024", Description: "A spring trip to Lisbon."},
+				{ID: "album2", Title: "Harbors", Description: "Boats in harbors along the coast.", Facets: &AlbumFacetsPromptData{
+					Theme:     "Boats in coastal harbors",
+					Subjects:  []string{"boats", "harbor", "sunset"},
+					Places:    []string{"Lisbon, Portugal"},
+					DateRange: DateRange{Start: "2024-05-01", End: "2024-05-03"},
+				}},
+			},
+		},
+		PromptContactSheet: ContactSheetPromptContext{
+			Summary:   "A spring trip to Lisbon, with boats in the harbor and walks through the old town.",
+			Photos:    9,
+			Columns:   3,
+			Rows:      3,
+			DateRange: DateRange{Start: "2024-05-01", End: "2024-05-03"},
+			Places:    []string{"Lisbon, Portugal"},
+		},
+		PromptAlbumFacets: AlbumFacetsPromptContext{Album: album, Description: "A spring trip to Lisbon, with boats in the harbor."},
+		PromptTranslation: TranslationPromptContext{Item: "photo", Language: "German", Text: photo.Description},
+	}
+}
+
+// Render executes the named prompt template after applying the redaction policy to its data
+func (p *Prompts) Render(name string, data interface{}) (string, error) {
+	tmpl, ok := p.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown prompt template %q", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p.prepare(data)); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// photoPromptData converts a database photo into template data
+func (c *Client) photoPromptData(photo *database.Photo) PhotoPromptData {
+	description := ""
+	if photo.AIDescription.Valid {
+		description = photo.AIDescription.String
+	}
+
+	return PhotoPromptData{
+		ID:          photo.ID,
+		Title:       c.promptTitle(photo),
+		TakenAt:     formatTakenAt(photo.TakenAt),
+		Date:        photoDate(photo),
+		Make:        getStringValue(photo.Make),
+		Model:       getStringValue(photo.Model),
+		Location:    c.promptLocation(photo),
+		Description: description,
+		EXIF: EXIFPromptData{
+			Lens:      getStringValue(photo.Lens),
+			Focal:     getStringValue(photo.Focal),
+			Aperture:  getStringValue(photo.Aperture),
+			Shutter:   getStringValue(photo.Shutter),
+			ISO:       getStringValue(photo.ISO),
+			Altitude:  formatFloat(photo.Altitude, "%.0f m"),
+			Direction: formatFloat(photo.ImgDirection, "%.0f°"),
+			Latitude:  formatFloat(photo.Latitude, "%.4f"),
+			Longitude: formatFloat(photo.Longitude, "%.4f"),
+		},
+	}
+}
+
+// promptLocation names where a photo was taken, geocoding its coordinates if Lychee has no location
+func (c *Client) promptLocation(photo *database.Photo) string {
+	if location := c.geocoder.PhotoLocation(photo); location != "" {
+		return location
+	}
+	return "Unknown"
+}
+
+// PromptPreview is a rendered prompt with the system message and examples sent along with it,
+// or the error that prevented rendering it
+type PromptPreview struct {
+	Name     string    `json:"name"`
+	Model    string    `json:"model"`
+	Language string    `json:"language,omitempty"` // set for translations
+	System   string    `json:"system,omitempty"`
+	Hash     string    `json:"prompt_hash,omitempty"` // recorded in the provenance of texts made with the prompt
+	Examples []Example `json:"examples,omitempty"`
+	Prompt   string    `json:"prompt,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// PreviewPhotoPrompts renders the prompts that would be sent for a photo
+func (c *Client) PreviewPhotoPrompts(photo *database.Photo, albums []database.Album) []PromptPreview {
+	data := PhotoPromptContext{Photo: c.photoPromptData(photo)}
+	albumItems := c.suggestionAlbums(albums)
+
+	previews := []PromptPreview{
+		c.newPromptPreview(PromptPhotoDescription, c.primaryModel(taskPhotoDescription))(c.prompts.Render(PromptPhotoDescription, data)),
+		c.newPromptPreview(PromptPhotoTitle, c.primaryModel(taskPhotoDescription))(c.prompts.Render(PromptPhotoTitle, data)),
+		c.newPromptPreview(PromptSuggestions, c.primaryModel(taskSuggestion))(c.buildSuggestionPrompt(photo, albumItems)),
+	}
+	if previews[2].Error == "" {
+		previews[2].Examples = c.suggestionExamples(photo, albumItems)
+	}
+	return append(previews, c.previewTranslations(database.TranslationPhoto, photo.AIDescription)...)
+}
+
+// PreviewAlbumPrompts renders the prompts that would be sent for an album. For albums large enough
+// to need compaction, the final synthesis prompt depends on model output, so only the first
+// compaction batch is shown.
+func (c *Client) PreviewAlbumPrompts(album *database.Album, content *database.AlbumContent) []PromptPreview {
+	photos := content.Photos
+	_, dates, _ := c.extractPhotoData(photos)
+	descriptions, sample := c.albumDescriptions(content)
+	if len(descriptions) == 0 {
+		return []PromptPreview{{Name: PromptAlbumDescription, Model: c.primaryModel(taskAlbumSynthesis), Error: "no photo descriptions available for album synthesis"}}
+	}
+
+	places := c.geocoder.Summarize(photos, maxPromptPlaces)
+	albumData := c.albumPromptData(album)
+	budget, err := c.albumDescriptionBudget(albumData, dates, places, sample)
+	if err != nil {
+		return []PromptPreview{c.newPromptPreview(PromptAlbumDescription, c.primaryModel(taskAlbumSynthesis))("", err)}
+	}
+	if totalDescriptionTokens(descriptions) > budget && len(descriptions) > 1 {
+		batchBudget, err := c.compactionBudget()
+		if err != nil {
+			return []PromptPreview{c.newPromptPreview(PromptCompaction, c.primaryModel(taskCompaction))("", err)}
+		}
+		batch := planBatches(descriptions, batchBudget)[0]
+		return []PromptPreview{
+			c.newPromptPreview(PromptCompaction, c.primaryModel(taskCompaction))(c.prompts.Render(PromptCompaction, CompactionPromptContext{Descriptions: batch})),
+		}
+	}
+
+	previews := []PromptPreview{
+		c.newPromptPreview(PromptAlbumDescription, c.primaryModel(taskAlbumSynthesis))(c.buildAlbumDescriptionPrompt(albumData, descriptions, dates, places, sample)),
+	}
+	// The facets are taken from the description once it is written; the current one stands in
+	if album.AIDescription.Valid {
+		previews = append(previews, c.newPromptPreview(PromptAlbumFacets, c.primaryModel(taskAlbumSynthesis))(c.buildAlbumFacetsPrompt(album, album.AIDescription.String)))
+	}
+	return append(previews, c.previewTranslations(database.TranslationAlbum, album.AIDescription)...)
+}
+
+func (c *Client) newPromptPreview(name, model string) func(string, error) PromptPreview {
+	return func(prompt string, err error) PromptPreview {
+		preview := PromptPreview{Name: name, Model: model, System: c.prompts.System(name, ""), Hash: c.prompts.Hash(name), Prompt: prompt}
+		if err != nil {
+			preview.Error = err.Error()
+		}
+		return preview
+	}
+}
@@ -0,0 +1,82 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+// GenerateAlbumProposal proposes a title and description for a new album holding a cluster of
+// photos. clusterID identifies the proposal's reasoning and is used for logging and compaction.
+func (c *Client) GenerateAlbumProposal(clusterID string, photos []database.Photo) (title, description string, err error) {
+	log.Printf("Generating album proposal for cluster %s with %d photos", clusterID, len(photos))
+
+	descriptions, dates, err := c.extractPhotoData(photos)
+	if err != nil {
+		return "", "", err
+	}
+	if len(descriptions) == 0 {
+		return "", "", fmt.Errorf("no photo descriptions available for album proposal")
+	}
+	places := c.geocoder.Summarize(photos, maxPromptPlaces)
+
+	overhead, err := c.buildAlbumProposalPrompt(nil, dates, places)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render album proposal prompt: %w", err)
+	}
+	descriptions, err = c.compactDescriptionsHierarchically(clusterID, descriptions, c.descriptionBudget(c.promptTokens(PromptAlbumProposal, overhead)), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to compact descriptions: %w", err)
+	}
+
+	prompt, err := c.buildAlbumProposalPrompt(descriptions, dates, places)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render album proposal prompt: %w", err)
+	}
+
+	req := c.newChat(PromptAlbumProposal, prompt, nil, nil)
+	req.Format = jsonFormat
+
+	_, err = c.generate(taskAlbumSynthesis, subject{kind: PromptAlbumProposal, id: clusterID}, req, nil, func(response string) (err error) {
+		title, description, err = parseAlbumProposal(response)
+		if err != nil {
+			return c.validator.invalidJSON(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate album proposal: %w", err)
+	}
+
+	return title, description, nil
+}
+
+func (c *Client) buildAlbumProposalPrompt(descriptions []string, dates []string, places []string) (string, error) {
+	return c.prompts.Render(PromptAlbumProposal, AlbumProposalPromptContext{
+		Descriptions: descriptions,
+		DateRange:    DateRange{Start: getMinDate(dates), End: getMaxDate(dates)},
+		Places:       places,
+	})
+}
+
+// parseAlbumProposal reads the model's JSON answer, cleaning up the title so it fits Lychee
+func parseAlbumProposal(responseText string) (title, description string, err error) {
+	var proposal struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal([]byte(responseText), &proposal); err != nil {
+		return "", "", fmt.Errorf("failed to parse JSON response: %w, response was: %s", err, responseText)
+	}
+
+	// base_albums.title is varchar(100) like photos.title
+	title = cleanTitle(proposal.Title)
+	if title == "" {
+		return "", "", fmt.Errorf("album proposal has no title, response was: %s", responseText)
+	}
+
+	return title, strings.TrimSpace(proposal.Description), nil
+}
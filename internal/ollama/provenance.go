@@ -0,0 +1,129 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"lychee-ai-organizer/internal/database"
+
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// digestTimeout bounds the request listing an endpoint's models to find a digest
+	digestTimeout = 10 * time.Second
+	// digestTTL is how long a model's digest is cached
+	digestTTL = 10 * time.Minute
+	// missingDigestTTL is how long a model missing from an endpoint's list is cached as such
+	missingDigestTTL = time.Minute
+)
+
+// DescriptionKinds are the prompts whose provenance counts for the description of a photo or
+// album (database.TranslationPhoto or database.TranslationAlbum). An album's description is
+// written from text and then, with contact sheets, rewritten by the vision model.
+func DescriptionKinds(itemType string) []string {
+	if itemType == database.TranslationAlbum {
+		return []string{PromptAlbumDescription, PromptContactSheet}
+	}
+	return []string{PromptPhotoDescription}
+}
+
+// NormalizeModelName spells a model name the way provenance records it, with its tag
+func NormalizeModelName(name string) string {
+	return normalizeModelName(name)
+}
+
+// saveProvenance records the model, prompt and options an answer about a subject was made
+// with. Failing to store it doesn't fail the answer.
+func (c *Client) saveProvenance(about subject, req *api.ChatRequest, e *endpoint, duration time.Duration) {
+	options := make(map[string]interface{}, len(req.Options)+1)
+	for key, value := range req.Options {
+		options[key] = value
+	}
+	if req.Think != nil {
+		options["think"] = req.Think
+	}
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		log.Printf("Error encoding options of %s %s: %v", about.kind, about.id, err)
+	}
+
+	err = c.db.SaveProvenance(&database.Provenance{
+		Kind:       about.kind,
+		ItemID:     about.id,
+		Model:      normalizeModelName(req.Model),
+		Digest:     c.modelDigest(e, req.Model),
+		PromptHash: c.prompts.Hash(about.kind),
+		Options:    string(encoded),
+		DurationMs: duration.Milliseconds(),
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error saving provenance of %s %s: %v", about.kind, about.id, err)
+	}
+}
+
+// cachedDigest is a model digest and when the endpoint listed it; empty if the model wasn't listed
+type cachedDigest struct {
+	digest  string
+	fetched time.Time
+}
+
+// fresh reports whether the digest can still be trusted. Models can be pulled again outside
+// this application, and missing ones may be pulled any moment.
+func (d cachedDigest) fresh(now time.Time) bool {
+	ttl := digestTTL
+	if d.digest == "" {
+		ttl = missingDigestTTL
+	}
+	return now.Sub(d.fetched) < ttl
+}
+
+// modelDigest returns the digest of a model on an endpoint, listing the endpoint's models when
+// the cached digest is stale; empty if it can't be found
+func (c *Client) modelDigest(e *endpoint, model string) string {
+	if e == nil {
+		return ""
+	}
+	model = normalizeModelName(model)
+	key := e.url + " " + model
+
+	c.mu.Lock()
+	cached, ok := c.digests[key]
+	c.mu.Unlock()
+	if ok && cached.fresh(time.Now()) {
+		return cached.digest
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), digestTimeout)
+	defer cancel()
+	list, err := e.client.List(ctx)
+	if err != nil {
+		log.Printf("Failed to list models on %s for their digests: %v", e.url, err)
+		return ""
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	listed := false
+	for _, m := range list.Models {
+		name := e.url + " " + normalizeModelName(m.Name)
+		c.digests[name] = cachedDigest{digest: m.Digest, fetched: now}
+		listed = listed || name == key
+	}
+	// Remember models missing from the list too, so the endpoint isn't asked on every answer
+	if !listed {
+		c.digests[key] = cachedDigest{fetched: now}
+	}
+	return c.digests[key].digest
+}
+
+// forgetDigest drops the cached digest of a model on an endpoint, after it was pulled
+func (c *Client) forgetDigest(e *endpoint, model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.digests, e.url+" "+normalizeModelName(model))
+}
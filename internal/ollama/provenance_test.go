@@ -0,0 +1,136 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// fakeOllama lists the models it holds and counts how often it is asked to
+type fakeOllama struct {
+	mu      sync.Mutex
+	digests map[string]string // by model name
+	lists   int
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/api/tags":
+		f.lists++
+		var list api.ListResponse
+		for name, digest := range f.digests {
+			list.Models = append(list.Models, api.ListModelResponse{Name: name, Model: name, Digest: digest})
+		}
+		json.NewEncoder(w).Encode(list)
+	case "/api/pull":
+		json.NewEncoder(w).Encode(api.ProgressResponse{Status: "success"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeOllama) set(model, digest string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.digests[model] = digest
+}
+
+func (f *fakeOllama) listCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lists
+}
+
+func newDigestTestClient(t *testing.T, fake *fakeOllama) (*Client, *endpoint) {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	baseURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &endpoint{url: server.URL, client: api.NewClient(baseURL, server.Client())}
+	c := &Client{
+		pool:    &pool{endpoints: []*endpoint{e}},
+		digests: make(map[string]cachedDigest),
+	}
+	return c, e
+}
+
+// age makes the cached digest of a model look as if it was fetched that long ago
+func age(c *Client, e *endpoint, model string, by time.Duration) {
+	key := e.url + " " + normalizeModelName(model)
+	cached := c.digests[key]
+	cached.fetched = cached.fetched.Add(-by)
+	c.digests[key] = cached
+}
+
+func TestModelDigest(t *testing.T) {
+	fake := &fakeOllama{digests: map[string]string{"llava:7b": "aaa", "qwen3:8b": "bbb"}}
+	c, e := newDigestTestClient(t, fake)
+
+	if got := c.modelDigest(e, "llava:7b"); got != "aaa" {
+		t.Fatalf("digest = %q, want aaa", got)
+	}
+	// one listing serves every model on the endpoint
+	if got := c.modelDigest(e, "qwen3:8b"); got != "bbb" {
+		t.Errorf("digest = %q, want bbb", got)
+	}
+	if n := fake.listCount(); n != 1 {
+		t.Errorf("listed models %d times, want 1", n)
+	}
+
+	// a model pulled outside the application shows up once the cached digest expires
+	fake.set("llava:7b", "ccc")
+	if got := c.modelDigest(e, "llava:7b"); got != "aaa" {
+		t.Errorf("digest = %q before expiry, want the cached aaa", got)
+	}
+	age(c, e, "llava:7b", digestTTL)
+	if got := c.modelDigest(e, "llava:7b"); got != "ccc" {
+		t.Errorf("digest = %q after expiry, want ccc", got)
+	}
+}
+
+func TestModelDigestMissing(t *testing.T) {
+	fake := &fakeOllama{digests: map[string]string{}}
+	c, e := newDigestTestClient(t, fake)
+
+	if got := c.modelDigest(e, "llava"); got != "" {
+		t.Fatalf("digest = %q for a missing model", got)
+	}
+	c.modelDigest(e, "llava")
+	if n := fake.listCount(); n != 1 {
+		t.Errorf("listed models %d times, want 1 while the model is known to be missing", n)
+	}
+
+	fake.set("llava:latest", "aaa")
+	age(c, e, "llava", missingDigestTTL)
+	if got := c.modelDigest(e, "llava"); got != "aaa" {
+		t.Errorf("digest = %q once the model exists, want aaa", got)
+	}
+}
+
+func TestPullModelsForgetsDigest(t *testing.T) {
+	fake := &fakeOllama{digests: map[string]string{"llava:7b": "aaa"}}
+	c, e := newDigestTestClient(t, fake)
+
+	if got := c.modelDigest(e, "llava:7b"); got != "aaa" {
+		t.Fatalf("digest = %q, want aaa", got)
+	}
+	fake.set("llava:7b", "bbb")
+	if err := c.PullModels(context.Background(), []ModelStatus{{Endpoint: e.url, Name: "llava:7b"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.modelDigest(e, "llava:7b"); got != "bbb" {
+		t.Errorf("digest = %q after pulling, want bbb", got)
+	}
+}
@@ -0,0 +1,184 @@
+package ollama
+
+import (
+	"fmt"
+	"strings"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+// unknown is how missing and redacted values are rendered
+const unknown = "Unknown"
+
+// PromptField is one line of a photo's details in a prompt
+type PromptField struct {
+	Name  string
+	Value string
+}
+
+// photoFields are the photo details that can be listed in prompts, by config name
+var photoFields = map[string]struct {
+	name  string
+	value func(p *PhotoPromptData) string
+}{
+	"title":    {"Title", func(p *PhotoPromptData) string { return p.Title }},
+	"taken_at": {"Taken at", func(p *PhotoPromptData) string { return p.TakenAt }},
+	"camera": {"Camera", func(p *PhotoPromptData) string {
+		var parts []string
+		for _, part := range []string{p.Make, p.Model} {
+			if part != unknown && part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, " ")
+	}},
+	"lens":     {"Lens", func(p *PhotoPromptData) string { return p.EXIF.Lens }},
+	"focal":    {"Focal length", func(p *PhotoPromptData) string { return p.EXIF.Focal }},
+	"aperture": {"Aperture", func(p *PhotoPromptData) string { return p.EXIF.Aperture }},
+	"shutter":  {"Shutter speed", func(p *PhotoPromptData) string { return p.EXIF.Shutter }},
+	"iso":      {"ISO", func(p *PhotoPromptData) string { return p.EXIF.ISO }},
+	"location": {"Location", func(p *PhotoPromptData) string { return p.Location }},
+	"coordinates": {"Coordinates", func(p *PhotoPromptData) string {
+		if p.EXIF.Latitude == unknown || p.EXIF.Longitude == unknown {
+			return unknown
+		}
+		return p.EXIF.Latitude + ", " + p.EXIF.Longitude
+	}},
+	"altitude":  {"Altitude", func(p *PhotoPromptData) string { return p.EXIF.Altitude }},
+	"direction": {"Direction", func(p *PhotoPromptData) string { return p.EXIF.Direction }},
+}
+
+// redaction is the privacy policy applied to the data of every prompt before rendering
+type redaction struct {
+	gps    bool
+	titles bool
+	owner  bool
+}
+
+// newRedaction returns the policy in effect: the configured one if an endpoint is remote or the
+// policy applies always, and none otherwise
+func newRedaction(cfg *config.RedactionConfig, remote bool) redaction {
+	var r redaction
+	if !remote && !cfg.Always {
+		return r
+	}
+	for _, category := range cfg.Strip {
+		switch category {
+		case config.RedactGPS:
+			r.gps = true
+		case config.RedactTitles:
+			r.titles = true
+		case config.RedactOwner:
+			r.owner = true
+		}
+	}
+	return r
+}
+
+func validatePhotoFields(fields []string) error {
+	for _, field := range fields {
+		if _, ok := photoFields[field]; !ok {
+			return fmt.Errorf("unknown photo prompt field %q", field)
+		}
+	}
+	return nil
+}
+
+// RedactPlaces returns places, or none if the policy strips GPS data. Text that goes into
+// prompts outside of their data, like the places appended to album descriptions, must be built
+// with it too.
+func (p *Prompts) RedactPlaces(places []string) []string {
+	if p.redaction.gps {
+		return nil
+	}
+	return places
+}
+
+// RedactTitle returns a photo or album title, or "" if the policy strips titles. Text that goes
+// into prompts outside of their data, like sub-album descriptions, must be built with it too.
+func (p *Prompts) RedactTitle(title string) string {
+	if p.redaction.titles {
+		return ""
+	}
+	return title
+}
+
+// prepare applies the redaction policy to prompt data and lists the configured photo details.
+// Every prompt is rendered through it, so nothing the policy strips can reach a model.
+func (p *Prompts) prepare(data interface{}) interface{} {
+	switch d := data.(type) {
+	case PhotoPromptContext:
+		d.Photo = p.preparePhoto(d.Photo)
+		return d
+	case SuggestionPromptContext:
+		d.Photo = p.preparePhoto(d.Photo)
+		albums := make([]AlbumPromptItem, len(d.Albums))
+		for i, album := range d.Albums {
+			album.Title = p.RedactTitle(album.Title)
+			if album.Facets != nil {
+				facets := *album.Facets
+				facets.Places = p.RedactPlaces(facets.Places)
+				album.Facets = &facets
+			}
+			albums[i] = album
+		}
+		d.Albums = albums
+		return d
+	case AlbumPromptContext:
+		d.Album = p.prepareAlbum(d.Album)
+		d.Places = p.RedactPlaces(d.Places)
+		return d
+	case AlbumFacetsPromptContext:
+		d.Album = p.prepareAlbum(d.Album)
+		return d
+	case AlbumProposalPromptContext:
+		d.Places = p.RedactPlaces(d.Places)
+		return d
+	case ContactSheetPromptContext:
+		d.Places = p.RedactPlaces(d.Places)
+		return d
+	}
+	return data
+}
+
+func (p *Prompts) prepareAlbum(album AlbumPromptData) AlbumPromptData {
+	album.Title = p.RedactTitle(album.Title)
+	var subAlbums []string
+	for _, title := range album.SubAlbums {
+		if title = p.RedactTitle(title); title != "" {
+			subAlbums = append(subAlbums, title)
+		}
+	}
+	album.SubAlbums = subAlbums
+	if p.redaction.owner {
+		album.Copyright = ""
+	}
+	return album
+}
+
+func (p *Prompts) preparePhoto(photo PhotoPromptData) PhotoPromptData {
+	if p.redaction.gps {
+		photo.Location = unknown
+		photo.EXIF.Latitude = unknown
+		photo.EXIF.Longitude = unknown
+		photo.EXIF.Altitude = unknown
+		photo.EXIF.Direction = unknown
+	}
+	if p.RedactTitle(photo.Title) == "" {
+		photo.Title = unknown
+	}
+	if p.redaction.owner {
+		photo.Make = unknown
+		photo.Model = unknown
+		photo.EXIF.Lens = unknown
+	}
+
+	photo.Details = nil
+	for _, name := range p.photoFields {
+		field := photoFields[name]
+		if value := field.value(&photo); value != "" && value != unknown {
+			photo.Details = append(photo.Details, PromptField{Name: field.name, Value: value})
+		}
+	}
+	return photo
+}
@@ -0,0 +1,139 @@
+package ollama
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+// sensitive are values of the sample prompt data by the category that strips them
+var sensitive = map[string][]string{
+	config.RedactGPS:    {"Lisbon, Portugal", "38.7071", "-9.1355", "12 m", "270°"},
+	config.RedactTitles: {"Sunset over the harbor", "Lisbon 2024", "Belém", "Alfama", "Harbors"},
+	config.RedactOwner:  {"Canon", "EOS R6", "RF 24-105mm", "Jane Doe"},
+}
+
+func TestRedaction(t *testing.T) {
+	all := []string{config.RedactGPS, config.RedactTitles, config.RedactOwner}
+	tests := []struct {
+		name     string
+		strip    []string
+		always   bool
+		remote   bool
+		stripped []string
+	}{
+		{"no policy", nil, false, true, nil},
+		{"local endpoint", all, false, false, nil},
+		{"local endpoint, always", all, true, false, all},
+		{"gps", []string{config.RedactGPS}, false, true, []string{config.RedactGPS}},
+		{"titles", []string{config.RedactTitles}, false, true, []string{config.RedactTitles}},
+		{"owner", []string{config.RedactOwner}, false, true, []string{config.RedactOwner}},
+		{"everything", all, false, true, all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.PromptsConfig{
+				Languages:   []string{"en"},
+				PhotoFields: append(append([]string(nil), config.DefaultPhotoPromptFields...), "coordinates"),
+				Redaction:   config.RedactionConfig{Strip: tt.strip, Always: tt.always},
+			}
+			p, err := LoadPrompts(cfg, tt.remote)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var rendered strings.Builder
+			for name, data := range samplePromptData() {
+				prompt, err := p.Render(name, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rendered.WriteString(prompt + "\n")
+			}
+			for category, values := range sensitive {
+				stripped := false
+				for _, c := range tt.stripped {
+					stripped = stripped || c == category
+				}
+				for _, value := range values {
+					if found := strings.Contains(rendered.String(), value); found == stripped {
+						t.Errorf("%s value %q: in prompts = %v, want %v", category, value, found, !stripped)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRedactFreeText(t *testing.T) {
+	places := []string{"Lisbon, Portugal"}
+	open := &Prompts{}
+	if got := open.RedactPlaces(places); !reflect.DeepEqual(got, places) {
+		t.Errorf("RedactPlaces() = %v without a policy", got)
+	}
+	if got := open.RedactTitle("Lisbon 2024"); got != "Lisbon 2024" {
+		t.Errorf("RedactTitle() = %q without a policy", got)
+	}
+
+	strict := &Prompts{redaction: redaction{gps: true, titles: true}}
+	if got := strict.RedactPlaces(places); got != nil {
+		t.Errorf("RedactPlaces() = %v with gps stripped", got)
+	}
+	if got := strict.RedactTitle("Lisbon 2024"); got != "" {
+		t.Errorf("RedactTitle() = %q with titles stripped", got)
+	}
+}
+
+func TestPreparePhoto(t *testing.T) {
+	photo := samplePromptData()[PromptPhotoDescription].(PhotoPromptContext).Photo
+	photo.EXIF.ISO = unknown
+
+	tests := []struct {
+		name      string
+		fields    []string
+		redaction redaction
+		want      []PromptField
+	}{
+		{
+			name:   "configured order, unknown left out",
+			fields: []string{"location", "title", "iso", "camera"},
+			want: []PromptField{
+				{"Location", "Lisbon, Portugal"}, {"Title", "Sunset over the harbor"}, {"Camera", "Canon EOS R6"},
+			},
+		},
+		{
+			name:   "coordinates",
+			fields: []string{"coordinates", "altitude", "direction"},
+			want:   []PromptField{{"Coordinates", "38.7071, -9.1355"}, {"Altitude", "12 m"}, {"Direction", "270°"}},
+		},
+		{
+			name:      "gps stripped",
+			fields:    []string{"location", "coordinates", "altitude", "direction", "focal"},
+			redaction: redaction{gps: true},
+			want:      []PromptField{{"Focal length", "50 mm"}},
+		},
+		{
+			name:      "owner stripped",
+			fields:    []string{"camera", "lens", "aperture"},
+			redaction: redaction{owner: true},
+			want:      []PromptField{{"Aperture", "f/8"}},
+		},
+		{
+			name:      "titles stripped",
+			fields:    []string{"title", "taken_at"},
+			redaction: redaction{titles: true},
+			want:      []PromptField{{"Taken at", "2024-05-01 19:45:00"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Prompts{photoFields: tt.fields, redaction: tt.redaction}
+			got := p.preparePhoto(photo)
+			if !reflect.DeepEqual(got.Details, tt.want) {
+				t.Errorf("Details = %v, want %v", got.Details, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package ollama
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/words"
+)
+
+// stratum is the described photos of an album taken on one day at one place
+type stratum struct {
+	photos []*database.Photo // in time order
+	quota  int
+}
+
+// albumSample tells how many of an album's described photos a description is written from;
+// size is 0 when all of them are used
+type albumSample struct {
+	size int
+	of   int
+}
+
+// sampleDescriptions returns the photo descriptions an album description is written from. With
+// the sampled strategy, albums with more described photos than the threshold are cut down to a
+// sample stratified by day and place: every stratum is represented in proportion to its size,
+// and within a stratum the photos whose descriptions differ most are chosen. A sample is in time
+// order; otherwise the descriptions keep the order of photos.
+func (c *Client) sampleDescriptions(photos []database.Photo) ([]string, albumSample) {
+	var described []*database.Photo
+	for i := range photos {
+		if photos[i].AIDescription.Valid {
+			described = append(described, &photos[i])
+		}
+	}
+	if c.albums == nil || c.albums.Sampling.Strategy != config.SamplingSampled || len(described) <= c.albums.Sampling.Threshold {
+		descriptions := make([]string, len(described))
+		for i, photo := range described {
+			descriptions[i] = photo.AIDescription.String
+		}
+		return descriptions, albumSample{of: len(described)}
+	}
+	n := c.albums.Sampling.SampleSize
+
+	sort.SliceStable(described, func(i, j int) bool {
+		return photoTime(described[i]).Before(photoTime(described[j]))
+	})
+
+	// Group by day and place; strata are in the order of their first photo
+	var strata []*stratum
+	byKey := make(map[string]*stratum)
+	for _, photo := range described {
+		key := photoDate(photo) + "|" + c.geocoder.PhotoLocation(photo)
+		s, ok := byKey[key]
+		if !ok {
+			s = &stratum{}
+			byKey[key] = s
+			strata = append(strata, s)
+		}
+		s.photos = append(s.photos, photo)
+	}
+
+	allocateQuotas(strata, n, len(described))
+
+	var chosen []*database.Photo
+	for _, s := range strata {
+		chosen = append(chosen, mostVaried(s.photos, s.quota)...)
+	}
+	sort.SliceStable(chosen, func(i, j int) bool {
+		return photoTime(chosen[i]).Before(photoTime(chosen[j]))
+	})
+	descriptions := make([]string, len(chosen))
+	for i, photo := range chosen {
+		descriptions[i] = photo.AIDescription.String
+	}
+
+	log.Printf("Sampled %d of %d described photos from %d day and place strata", len(descriptions), len(described), len(strata))
+	return descriptions, albumSample{size: len(descriptions), of: len(described)}
+}
+
+// photoTime returns when a photo was taken, falling back to its upload time
+func photoTime(photo *database.Photo) time.Time {
+	if photo.TakenAt.Valid {
+		return photo.TakenAt.Time
+	}
+	return photo.CreatedAt
+}
+
+// allocateQuotas shares n sample places among strata holding total photos. If there are more
+// strata than places, strata evenly spread over time get one each. Otherwise every stratum gets
+// one, and the rest are shared in proportion to size by largest remainder.
+func allocateQuotas(strata []*stratum, n, total int) {
+	if len(strata) >= n {
+		for i := 0; i < n; i++ {
+			strata[(2*i+1)*len(strata)/(2*n)].quota = 1
+		}
+		return
+	}
+
+	remaining := n - len(strata)
+	rest := total - len(strata)
+	type remainder struct {
+		stratum  *stratum
+		fraction float64
+	}
+	remainders := make([]remainder, 0, len(strata))
+	given := 0
+	for _, s := range strata {
+		share := float64(remaining) * float64(len(s.photos)-1) / float64(rest)
+		s.quota = 1 + int(share)
+		given += int(share)
+		remainders = append(remainders, remainder{s, share - float64(int(share))})
+	}
+	sort.SliceStable(remainders, func(i, j int) bool {
+		return remainders[i].fraction > remainders[j].fraction
+	})
+	for i := 0; i < remaining-given; i++ {
+		remainders[i].stratum.quota++
+	}
+}
+
+// mostVaried picks n photos whose descriptions differ most, starting from the middle of the run
+// and then each time the photo least like any already picked. The result is in the given order.
+func mostVaried(photos []*database.Photo, n int) []*database.Photo {
+	if n == 0 {
+		return nil
+	}
+	if len(photos) <= n {
+		return photos
+	}
+
+	sets := make([]words.Set, len(photos))
+	for i, photo := range photos {
+		sets[i] = words.Of(photo.AIDescription.String)
+	}
+
+	picked := []int{len(photos) / 2}
+	nearest := make([]float64, len(photos))
+	for i := range photos {
+		nearest[i] = 1 - words.Similarity(sets[i], sets[picked[0]])
+	}
+	nearest[picked[0]] = -1
+	for len(picked) < n {
+		best := 0
+		for i := range photos {
+			if nearest[i] > nearest[best] {
+				best = i
+			}
+		}
+		picked = append(picked, best)
+		nearest[best] = -1
+		for i := range photos {
+			if nearest[i] >= 0 {
+				if d := 1 - words.Similarity(sets[i], sets[best]); d < nearest[i] {
+					nearest[i] = d
+				}
+			}
+		}
+	}
+
+	sort.Ints(picked)
+	chosen := make([]*database.Photo, len(picked))
+	for i, index := range picked {
+		chosen[i] = photos[index]
+	}
+	return chosen
+}
@@ -0,0 +1,103 @@
+package ollama
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+func TestAllocateQuotas(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes []int
+		n     int
+		want  []int
+	}{
+		{"one place each, spread over time", []int{3, 3, 3, 3, 3}, 2, []int{0, 1, 0, 1, 0}},
+		{"as many strata as places", []int{4, 1, 7}, 3, []int{1, 1, 1}},
+		{"proportional", []int{10, 10}, 6, []int{3, 3}},
+		// shares of the 3 remaining places: 2.08, 0.92 and 0; the largest remainder gets the last
+		{"largest remainder", []int{10, 5, 1}, 6, []int{3, 2, 1}},
+		{"single stratum", []int{40}, 8, []int{8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strata := make([]*stratum, len(tt.sizes))
+			total := 0
+			for i, size := range tt.sizes {
+				strata[i] = &stratum{photos: make([]*database.Photo, size)}
+				total += size
+			}
+			allocateQuotas(strata, tt.n, total)
+
+			got := make([]int, len(strata))
+			for i, s := range strata {
+				got[i] = s.quota
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quotas = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateQuotasSum(t *testing.T) {
+	for n := 1; n <= 30; n++ {
+		strata := make([]*stratum, 7)
+		total := 0
+		for i := range strata {
+			strata[i] = &stratum{photos: make([]*database.Photo, 1+i*i)}
+			total += 1 + i*i
+		}
+		allocateQuotas(strata, n, total)
+
+		sum := 0
+		for _, s := range strata {
+			if s.quota > len(s.photos) {
+				t.Errorf("n=%d: quota %d for a stratum of %d photos", n, s.quota, len(s.photos))
+			}
+			sum += s.quota
+		}
+		if sum != n {
+			t.Errorf("n=%d: quotas add up to %d", n, sum)
+		}
+	}
+}
+
+func TestMostVaried(t *testing.T) {
+	photos := make([]*database.Photo, 5)
+	for i, description := range []string{
+		"Harbour with fishing boats",
+		"Harbour with fishing boats along the quay",
+		"Mountain ridge under snow",
+		"Harbour with fishing boats and gulls",
+		"Forest trail covered in moss",
+	} {
+		photos[i] = &database.Photo{ID: string(rune('a' + i)), AIDescription: sql.NullString{String: description, Valid: true}}
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, ""},
+		// the middle photo first, then the first of those least like it
+		{1, "c"},
+		{2, "ac"},
+		{3, "ace"},
+		{4, "abce"},
+		{5, "abcde"},
+		{9, "abcde"},
+	}
+	for _, tt := range tests {
+		got := ""
+		for _, photo := range mostVaried(photos, tt.n) {
+			got += photo.ID
+		}
+		if got != tt.want {
+			t.Errorf("mostVaried(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ollama
+
+import (
+	"fmt"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+)
+
+// AlbumContent gathers what an album's description is written from: its photos and, following
+// the descendants config, those of its sub-albums or the descriptions of its described
+// sub-albums
+func (c *Client) AlbumContent(albumID string) (*database.AlbumContent, error) {
+	depth, useDescriptions := 0, false
+	if c.albums != nil {
+		depth = max(c.albums.Descendants.Depth, 0)
+		useDescriptions = c.albums.Descendants.Strategy == config.DescendantsDescriptions
+	}
+	return c.db.GetAlbumContent(albumID, depth, useDescriptions)
+}
+
+// albumDescriptions returns the descriptions an album's description is written from: one for
+// each described sub-album, followed by those of the photos no sub-album summarizes, sampled if
+// there are many
+func (c *Client) albumDescriptions(content *database.AlbumContent) ([]string, albumSample) {
+	var descriptions []string
+	for _, sub := range content.SubAlbums {
+		// The text goes into the prompt, so it follows the redaction policy too
+		if title := c.prompts.RedactTitle(sub.Title); title != "" {
+			descriptions = append(descriptions, fmt.Sprintf("Sub-album %q: %s", title, sub.AIDescription.String))
+		} else {
+			descriptions = append(descriptions, "Sub-album: "+sub.AIDescription.String)
+		}
+	}
+
+	photos := content.Photos
+	if len(content.Summarized) > 0 {
+		photos = nil
+		for _, photo := range content.Photos {
+			if !content.Summarized[photo.ID] {
+				photos = append(photos, photo)
+			}
+		}
+	}
+	photoDescriptions, sample := c.sampleDescriptions(photos)
+	return append(descriptions, photoDescriptions...), sample
+}
+
+// SubAlbumDescriptionDepth is how many levels of sub-albums are described before their parent,
+// so that their descriptions can stand in for their photos; 0 unless that strategy is configured
+func (c *Client) SubAlbumDescriptionDepth() int {
+	if c.albums == nil || c.albums.Descendants.Strategy != config.DescendantsDescriptions {
+		return 0
+	}
+	return max(c.albums.Descendants.Depth, 0)
+}
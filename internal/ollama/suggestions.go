@@ -0,0 +1,145 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+const (
+	// MaxSuggestions is the number of albums suggested per photo, as the built-in suggestions
+	// prompt asks for
+	MaxSuggestions = 3
+	// maxReasonLength is the maximum length, in characters, of a suggestion's reason
+	maxReasonLength = 160
+)
+
+// suggestionConfidence accepts confidences as JSON numbers or strings, e.g. 0.8, "0.8", 80 or "80%"
+type suggestionConfidence float64
+
+func (c *suggestionConfidence) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if text == "null" {
+		*c = 0
+		return nil
+	}
+
+	percent := false
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = strings.TrimSpace(unquoted)
+		if strings.HasSuffix(text, "%") {
+			percent = true
+			text = strings.TrimSpace(strings.TrimSuffix(text, "%"))
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid confidence %s", string(data))
+	}
+	if percent {
+		value /= 100
+	}
+
+	*c = suggestionConfidence(value)
+	return nil
+}
+
+// parseAlbumSuggestions parses the model's JSON answer, dropping unknown and duplicate albums
+// and normalizing confidences and reasons. Suggestions are returned best first.
+func parseAlbumSuggestions(responseText string, albums []database.Album) ([]database.AlbumSuggestion, error) {
+	var jsonResponse struct {
+		Suggestions []struct {
+			AlbumID    string               `json:"album_id"`
+			Confidence suggestionConfidence `json:"confidence"`
+			Reason     string               `json:"reason"`
+		} `json:"suggestions"`
+	}
+
+	if err := json.Unmarshal([]byte(responseText), &jsonResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w, response was: %s", err, responseText)
+	}
+
+	// Create a set of valid album IDs for validation
+	validAlbumIDs := make(map[string]bool)
+	for _, album := range albums {
+		validAlbumIDs[album.ID] = true
+	}
+
+	var suggestions []database.AlbumSuggestion
+	seen := make(map[string]bool)
+	for _, s := range jsonResponse.Suggestions {
+		albumID := strings.TrimSpace(s.AlbumID)
+		if !validAlbumIDs[albumID] || seen[albumID] {
+			continue
+		}
+		seen[albumID] = true
+
+		suggestions = append(suggestions, database.AlbumSuggestion{
+			AlbumID:    albumID,
+			Confidence: normalizeConfidence(float64(s.Confidence)),
+			Reason:     normalizeReason(s.Reason),
+		})
+	}
+
+	// The model is asked for best match first; confidences take precedence when they disagree
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Confidence > suggestions[j].Confidence
+	})
+
+	if len(suggestions) > MaxSuggestions {
+		suggestions = suggestions[:MaxSuggestions]
+	}
+	for i := range suggestions {
+		suggestions[i].Rank = i + 1
+	}
+
+	return suggestions, nil
+}
+
+// normalizeConfidence maps a model-reported confidence into [0, 1]. Values between 1 and 100
+// are taken to be percentages.
+func normalizeConfidence(value float64) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		return 0
+	}
+	if value > 1 && value <= 100 {
+		value /= 100
+	}
+	if value > 1 {
+		return 1
+	}
+	return math.Round(value*100) / 100
+}
+
+// normalizeReason reduces a model-provided reason to a single trimmed line of bounded length
+func normalizeReason(reason string) string {
+	reason = removeThinkTags(reason)
+	reason = strings.Join(strings.Fields(reason), " ")
+	reason = strings.Trim(reason, "\"'`*“”‘’")
+	reason = strings.TrimSpace(reason)
+
+	runes := []rune(reason)
+	if len(runes) > maxReasonLength {
+		cut := maxReasonLength
+		for cut > maxReasonLength/2 && !unicode.IsSpace(runes[cut]) {
+			cut--
+		}
+		reason = strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsPunct(r)
+		}) + "…"
+	}
+
+	if reason != "" {
+		first := []rune(reason)
+		first[0] = unicode.ToUpper(first[0])
+		reason = string(first)
+	}
+	return reason
+}
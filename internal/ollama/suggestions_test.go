@@ -0,0 +1,110 @@
+package ollama
+
+import (
+	"strings"
+	"testing"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+func TestNormalizeConfidence(t *testing.T) {
+	tests := []struct {
+		in, want float64
+	}{
+		{0, 0},
+		{0.834, 0.83},
+		{1, 1},
+		{1.5, 0.02}, // a percentage
+		{80, 0.8},
+		{100, 1},
+		{250, 1},
+		{-0.3, 0},
+	}
+	for _, tt := range tests {
+		if got := normalizeConfidence(tt.in); got != tt.want {
+			t.Errorf("normalizeConfidence(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAlbumSuggestions(t *testing.T) {
+	albums := []database.Album{{ID: "a1"}, {ID: "a2"}, {ID: "a3"}, {ID: "a4"}}
+
+	tests := []struct {
+		name     string
+		response string
+		want     []database.AlbumSuggestion // only album, rank and confidence are compared
+	}{
+		{
+			name:     "fractions",
+			response: `{"suggestions": [{"album_id": "a1", "confidence": 0.9, "reason": "beach"}, {"album_id": "a2", "confidence": 0.4}]}`,
+			want:     []database.AlbumSuggestion{{AlbumID: "a1", Rank: 1, Confidence: 0.9}, {AlbumID: "a2", Rank: 2, Confidence: 0.4}},
+		},
+		{
+			name:     "percentages and strings",
+			response: `{"suggestions": [{"album_id": "a1", "confidence": "85%"}, {"album_id": "a2", "confidence": 70}, {"album_id": "a3", "confidence": "0.6"}]}`,
+			want: []database.AlbumSuggestion{
+				{AlbumID: "a1", Rank: 1, Confidence: 0.85},
+				{AlbumID: "a2", Rank: 2, Confidence: 0.7},
+				{AlbumID: "a3", Rank: 3, Confidence: 0.6},
+			},
+		},
+		{
+			name:     "unknown and duplicate albums are dropped",
+			response: `{"suggestions": [{"album_id": "nope", "confidence": 0.99}, {"album_id": " a2 ", "confidence": 0.5}, {"album_id": "a2", "confidence": 0.8}]}`,
+			want:     []database.AlbumSuggestion{{AlbumID: "a2", Rank: 1, Confidence: 0.5}},
+		},
+		{
+			name:     "sorted by confidence and cut to three",
+			response: `{"suggestions": [{"album_id": "a1", "confidence": 0.2}, {"album_id": "a2", "confidence": 0.9}, {"album_id": "a3", "confidence": 0.5}, {"album_id": "a4", "confidence": 0.7}]}`,
+			want: []database.AlbumSuggestion{
+				{AlbumID: "a2", Rank: 1, Confidence: 0.9},
+				{AlbumID: "a4", Rank: 2, Confidence: 0.7},
+				{AlbumID: "a3", Rank: 3, Confidence: 0.5},
+			},
+		},
+		{
+			name:     "null confidence",
+			response: `{"suggestions": [{"album_id": "a1", "confidence": null}]}`,
+			want:     []database.AlbumSuggestion{{AlbumID: "a1", Rank: 1, Confidence: 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAlbumSuggestions(tt.response, albums)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d suggestions %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].AlbumID != want.AlbumID || got[i].Rank != want.Rank || got[i].Confidence != want.Confidence {
+					t.Errorf("suggestion %d = %s rank %d confidence %v, want %s rank %d confidence %v",
+						i, got[i].AlbumID, got[i].Rank, got[i].Confidence, want.AlbumID, want.Rank, want.Confidence)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAlbumSuggestionsErrors(t *testing.T) {
+	for _, response := range []string{
+		`not json`,
+		`{"suggestions": [{"album_id": "a1", "confidence": "high"}]}`,
+	} {
+		if _, err := parseAlbumSuggestions(response, []database.Album{{ID: "a1"}}); err == nil {
+			t.Errorf("parseAlbumSuggestions(%s) succeeded, want an error", response)
+		}
+	}
+}
+
+func TestNormalizeReason(t *testing.T) {
+	if got := normalizeReason("  \"same   beach\n as the album\"  "); got != "Same beach as the album" {
+		t.Errorf("normalizeReason = %q", got)
+	}
+	long := normalizeReason(strings.Repeat("word ", 60))
+	if n := len([]rune(long)); n > maxReasonLength+1 || !strings.HasSuffix(long, "…") {
+		t.Errorf("long reason was cut to %d characters: %q", n, long)
+	}
+}
@@ -0,0 +1,183 @@
+package ollama
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultContextWindow is Ollama's num_ctx when context_window is not configured
+	defaultContextWindow = 2048
+	// responseTokenReserve is the part of the context window kept free for the model's answer
+	responseTokenReserve = 512
+	// minDescriptionBudget keeps batching workable with very small context windows
+	minDescriptionBudget = 256
+	// listItemTokens is the cost of the "- " prefix and newline around each description in a prompt
+	listItemTokens = 3
+)
+
+// estimateTokens approximates the number of tokens in text. It assumes three characters per
+// token, which overestimates typical English (about four) so that prompts err on the short side.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 2) / 3
+}
+
+// promptTokens is the estimated cost of a prompt together with the system message sent with it
+func (c *Client) promptTokens(name, prompt string) int {
+	return estimateTokens(prompt) + estimateTokens(c.prompts.System(name, ""))
+}
+
+// descriptionTokens is the estimated cost of one description in a description list
+func descriptionTokens(description string) int {
+	return estimateTokens(description) + listItemTokens
+}
+
+// contextWindow returns the context window album prompts are sized for: the smallest num_ctx
+// among the album synthesis and compaction profiles, so that prompts fit every fallback too
+func (c *Client) contextWindow() int {
+	window := 0
+	for _, task := range []string{taskAlbumSynthesis, taskCompaction} {
+		for _, profile := range c.tasks[task] {
+			n := defaultContextWindow
+			switch v := profile.options["num_ctx"].(type) {
+			case int:
+				n = v
+			case float64:
+				n = int(v)
+			}
+			if window == 0 || n < window {
+				window = n
+			}
+		}
+	}
+	if window <= 0 {
+		return defaultContextWindow
+	}
+	return window
+}
+
+// descriptionBudget returns how many tokens of descriptions fit into a prompt whose fixed
+// parts (the system message and the rendered template without any descriptions) take
+// promptOverhead tokens. A tenth
+// of the window is kept as a margin for estimation error.
+func (c *Client) descriptionBudget(promptOverhead int) int {
+	budget := c.contextWindow()*9/10 - promptOverhead - responseTokenReserve
+	if budget < minDescriptionBudget {
+		return minDescriptionBudget
+	}
+	return budget
+}
+
+// albumDescriptionBudget returns the token budget for descriptions in the album description prompt
+func (c *Client) albumDescriptionBudget(album AlbumPromptData, dates []string, places []string, sample albumSample) (int, error) {
+	overhead, err := c.buildAlbumDescriptionPrompt(album, nil, dates, places, sample)
+	if err != nil {
+		return 0, err
+	}
+	return c.descriptionBudget(c.promptTokens(PromptAlbumDescription, overhead)), nil
+}
+
+// compactionBudget returns the token budget for descriptions in a compaction prompt
+func (c *Client) compactionBudget() (int, error) {
+	overhead, err := c.prompts.Render(PromptCompaction, CompactionPromptContext{})
+	if err != nil {
+		return 0, err
+	}
+	return c.descriptionBudget(c.promptTokens(PromptCompaction, overhead)), nil
+}
+
+// totalDescriptionTokens is the estimated cost of a description list
+func totalDescriptionTokens(descriptions []string) int {
+	total := 0
+	for _, d := range descriptions {
+		total += descriptionTokens(d)
+	}
+	return total
+}
+
+// truncateToTokens shortens text to roughly maxTokens, cutting at a word boundary
+func truncateToTokens(text string, maxTokens int) string {
+	if estimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := maxTokens * 3
+	if cut > len(runes) {
+		cut = len(runes)
+	}
+	truncated := string(runes[:cut])
+	if i := strings.LastIndexAny(truncated, " \n\t"); i > len(truncated)/2 {
+		truncated = truncated[:i]
+	}
+	return strings.TrimSpace(truncated) + "…"
+}
+
+// planBatches splits descriptions into compaction batches that each fit within budget tokens.
+//
+// Batch boundaries are content-defined: besides closing a batch when the next description would
+// overflow it, a batch also ends after any description whose hash hits a fixed pattern. Adding or
+// removing a photo therefore only changes the batches around it, and the summaries of the other
+// batches can be served from the compaction cache.
+//
+// Descriptions longer than half the budget are truncated, which guarantees every batch but the
+// last holds at least two descriptions, so each level of compaction shrinks the list.
+func planBatches(descriptions []string, budget int) [][]string {
+	if len(descriptions) == 0 {
+		return nil
+	}
+
+	items := make([]string, len(descriptions))
+	for i, d := range descriptions {
+		items[i] = truncateToTokens(d, budget/2-listItemTokens)
+	}
+
+	// Aim for content-defined boundaries about twice as often as the budget would force them
+	avg := totalDescriptionTokens(items) / len(items)
+	if avg < 1 {
+		avg = 1
+	}
+	modulus := uint32(budget / avg / 2)
+	if modulus < 2 {
+		modulus = 2
+	}
+
+	var batches [][]string
+	var current []string
+	used := 0
+	for _, item := range items {
+		tokens := descriptionTokens(item)
+		if len(current) > 0 && used+tokens > budget {
+			batches = append(batches, current)
+			current, used = nil, 0
+		}
+
+		current = append(current, item)
+		used += tokens
+
+		if len(current) >= 2 && contentHash(item)%modulus == 0 {
+			batches = append(batches, current)
+			current, used = nil, 0
+		}
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+func contentHash(text string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	return h.Sum32()
+}
+
+// compactionCacheKey identifies a compaction request by model and rendered prompt
+func compactionCacheKey(model, prompt string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+package ollama
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abc", 1},
+		{"abcd", 2},
+		{"äöü", 1}, // characters, not bytes
+		{strings.Repeat("x", 300), 100},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.text); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	short := "A quiet harbour at dawn."
+	if got := truncateToTokens(short, 100); got != short {
+		t.Errorf("text within the limit was changed to %q", got)
+	}
+
+	long := strings.Repeat("harbour boats ", 50)
+	got := truncateToTokens(long, 20)
+	if !strings.HasSuffix(got, "…") {
+		t.Errorf("truncated text %q has no ellipsis", got)
+	}
+	if n := utf8.RuneCountInString(got); n > 20*3+1 {
+		t.Errorf("truncated text has %d characters, want at most %d", n, 20*3+1)
+	}
+	if strings.Contains(got, "harbou…") || strings.Contains(got, "boat…") {
+		t.Errorf("truncated text %q was cut inside a word", got)
+	}
+}
+
+// testDescriptions returns n distinct descriptions of varying length
+func testDescriptions(n int) []string {
+	descriptions := make([]string, n)
+	for i := range descriptions {
+		descriptions[i] = fmt.Sprintf("Photo %d shows %s", i, strings.Repeat("a sunny street with cafés ", 1+i%5))
+	}
+	return descriptions
+}
+
+func TestPlanBatches(t *testing.T) {
+	if batches := planBatches(nil, 300); batches != nil {
+		t.Errorf("planBatches(nil) = %v, want nil", batches)
+	}
+
+	descriptions := testDescriptions(200)
+	descriptions[17] = strings.Repeat("a very long description ", 100)
+	const budget = 300
+
+	batches := planBatches(descriptions, budget)
+	var flattened []string
+	for i, batch := range batches {
+		if tokens := totalDescriptionTokens(batch); tokens > budget {
+			t.Errorf("batch %d takes %d tokens, over the budget of %d", i, tokens, budget)
+		}
+		if i < len(batches)-1 && len(batch) < 2 {
+			t.Errorf("batch %d holds %d description; only the last may hold fewer than two", i, len(batch))
+		}
+		flattened = append(flattened, batch...)
+	}
+
+	if len(flattened) != len(descriptions) {
+		t.Fatalf("batches hold %d descriptions, want %d", len(flattened), len(descriptions))
+	}
+	for i, d := range flattened {
+		if i == 17 {
+			if descriptionTokens(d) > budget/2 {
+				t.Errorf("the long description takes %d tokens, over half the budget", descriptionTokens(d))
+			}
+			continue
+		}
+		if d != descriptions[i] {
+			t.Errorf("description %d is %q, want %q", i, d, descriptions[i])
+		}
+	}
+}
+
+// Adding a photo must leave most batches as they were, so their summaries stay cached
+func TestPlanBatchesLocality(t *testing.T) {
+	const budget = 300
+	descriptions := testDescriptions(200)
+	before := planBatches(descriptions, budget)
+
+	changed := append(append(append([]string{}, descriptions[:100]...), "Photo new shows a harbour at night"), descriptions[100:]...)
+	after := planBatches(changed, budget)
+
+	unchanged := make(map[string]bool)
+	for _, batch := range before {
+		unchanged[strings.Join(batch, "\x00")] = true
+	}
+	kept := 0
+	for _, batch := range after {
+		if unchanged[strings.Join(batch, "\x00")] {
+			kept++
+		}
+	}
+	if kept < len(before)-3 {
+		t.Errorf("only %d of %d batches are unchanged after adding one description", kept, len(before))
+	}
+}
@@ -0,0 +1,59 @@
+package ollama
+
+import (
+	"database/sql"
+	"fmt"
+
+	"lychee-ai-organizer/internal/language"
+)
+
+// Languages returns the output languages, the primary one first. Everything is generated in the
+// primary language; photo and album descriptions are translated into the others.
+func (c *Client) Languages() []string {
+	return c.prompts.Languages()
+}
+
+// TranslateDescription translates the description of a photo or album from the primary language
+// into lang. itemType is database.TranslationPhoto or database.TranslationAlbum. onPartial, if not
+// nil, is called as the translation streams in.
+func (c *Client) TranslateDescription(itemType, itemID, description, lang string, onPartial PartialFunc) (string, error) {
+	prompt, err := c.buildTranslationPrompt(itemType, description, lang)
+	if err != nil {
+		return "", fmt.Errorf("failed to render translation prompt: %w", err)
+	}
+
+	req := c.newChatIn(lang, PromptTranslation, prompt, nil, nil)
+
+	about := subject{kind: PromptTranslation, id: itemID + "/" + lang, language: lang}
+	translation, err := c.generate(taskTranslation, about, req, onPartial, requireText)
+	if err != nil {
+		return "", fmt.Errorf("failed to translate description into %s: %w", language.Name(lang), err)
+	}
+
+	return translation, nil
+}
+
+func (c *Client) buildTranslationPrompt(itemType, description, lang string) (string, error) {
+	return c.prompts.Render(PromptTranslation, TranslationPromptContext{
+		Item:     itemType,
+		Language: language.Name(lang),
+		Text:     description,
+	})
+}
+
+// previewTranslations renders the prompts translating an existing description into each of the
+// other output languages
+func (c *Client) previewTranslations(itemType string, description sql.NullString) []PromptPreview {
+	if !description.Valid || description.String == "" {
+		return nil
+	}
+
+	var previews []PromptPreview
+	for _, lang := range c.Languages()[1:] {
+		preview := c.newPromptPreview(PromptTranslation, c.primaryModel(taskTranslation))(c.buildTranslationPrompt(itemType, description.String, lang))
+		preview.Language = lang
+		preview.System = c.prompts.System(PromptTranslation, lang)
+		previews = append(previews, preview)
+	}
+	return previews
+}
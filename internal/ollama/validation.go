@@ -0,0 +1,186 @@
+package ollama
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/language"
+)
+
+// maxTitleWords is the longest title accepted; the prompt asks for at most 6 words
+const maxTitleWords = 10
+
+// preamblePattern matches chatty openings that precede the answer instead of being it. "Here
+// is" only counts when it announces the answer, as a description may well start "Here is a
+// harbour ...".
+var preamblePattern = regexp.MustCompile(`(?i)^\W*((sure|certainly|of course|okay|ok|absolutely|i'd be happy|i would be happy|as requested)\b|(here is|here's|here are|below is)\b[^.\n]{0,40}?(:|\b(description|title|summary|translation|caption|answer|response)s?\b))`)
+
+// builtInBannedPhrases give away an answer about the request rather than to it
+var builtInBannedPhrases = []string{"as an ai", "language model", "i cannot", "i can't", "i'm sorry", "i am sorry", "i'm unable", "i am unable"}
+
+// sentenceEnd matches the end of a sentence, with any closing quotes or brackets; the
+// following space keeps numbers like 2.8 whole
+var sentenceEnd = regexp.MustCompile(`[.!?]+["'”’»)\]]*(\s+|$)`)
+
+// invalidOutputError is an answer that breaks the rules of its prompt. problem completes the
+// sentence "Your previous answer ..." and is told to the model so it can correct the answer.
+type invalidOutputError struct {
+	problem string
+	err     error // the underlying error, if any
+}
+
+func (e *invalidOutputError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("invalid answer: %s: %v", e.problem, e.err)
+	}
+	return "invalid answer: " + e.problem
+}
+
+func (e *invalidOutputError) Unwrap() error { return e.err }
+
+func invalidOutput(format string, args ...interface{}) error {
+	return &invalidOutputError{problem: fmt.Sprintf(format, args...)}
+}
+
+// validator checks generated text against the rules of its prompt
+type validator struct {
+	disabled     bool
+	repairs      int
+	banned       []string // lower case
+	minLength    int
+	maxLength    int
+	maxSentences map[string]int
+}
+
+// newValidator builds the checks from config; a nil config disables them
+func newValidator(cfg *config.ValidationConfig) *validator {
+	if cfg == nil || cfg.Disabled {
+		return &validator{disabled: true}
+	}
+
+	v := &validator{
+		repairs:      cfg.RepairAttempts,
+		banned:       append([]string(nil), builtInBannedPhrases...),
+		minLength:    cfg.MinLength,
+		maxLength:    cfg.MaxLength,
+		maxSentences: cfg.MaxSentences,
+	}
+	for _, phrase := range cfg.BannedPhrases {
+		if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+			v.banned = append(v.banned, phrase)
+		}
+	}
+	return v
+}
+
+func (v *validator) enabled() bool {
+	return !v.disabled
+}
+
+// check validates the answer to a prompt that asked for lang. JSON answers are checked as they
+// are parsed.
+func (v *validator) check(kind, lang, response string) error {
+	if v.disabled {
+		return nil
+	}
+	switch kind {
+	case PromptPhotoDescription, PromptAlbumDescription, PromptCompaction, PromptTranslation, PromptContactSheet:
+		return v.checkDescription(kind, lang, response)
+	case PromptPhotoTitle:
+		return v.checkTitle(response)
+	}
+	return nil
+}
+
+func (v *validator) checkDescription(kind, lang, text string) error {
+	if text == "" {
+		return invalidOutput("is empty")
+	}
+	if err := v.checkPhrases(text); err != nil {
+		return err
+	}
+
+	if n := utf8.RuneCountInString(text); n < v.minLength {
+		return invalidOutput("is only %d characters long", n)
+	} else if n > v.maxLength {
+		return invalidOutput("is %d characters long, more than the limit of %d", n, v.maxLength)
+	}
+
+	if max := v.maxSentences[kind]; max > 0 {
+		if n := countSentences(text); n > max {
+			return invalidOutput("has %d sentences, more than the limit of %d", n, max)
+		}
+	}
+
+	if code, ok := language.Detect(text); ok && code != lang {
+		// Only detected languages can be compared; an unknown expected language is only told
+		// apart from languages written in another script
+		if language.Known(lang) || !language.Latin(code) {
+			return invalidOutput("is written in %s instead of %s", language.Name(code), language.Name(lang))
+		}
+	}
+	return nil
+}
+
+func (v *validator) checkTitle(text string) error {
+	line := strings.TrimSpace(text)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if line == "" {
+		return invalidOutput("is empty")
+	}
+	if err := v.checkPhrases(line); err != nil {
+		return err
+	}
+	if n := len(strings.Fields(line)); n > maxTitleWords {
+		return invalidOutput("is %d words long, too long for a title", n)
+	}
+	return nil
+}
+
+// checkPhrases rejects preambles and banned phrases
+func (v *validator) checkPhrases(text string) error {
+	if m := preamblePattern.FindString(text); m != "" {
+		return invalidOutput("starts with %q instead of the answer itself", strings.TrimSpace(m))
+	}
+	lower := strings.ToLower(text)
+	for _, phrase := range v.banned {
+		if strings.Contains(lower, phrase) {
+			return invalidOutput("contains the phrase %q", phrase)
+		}
+	}
+	return nil
+}
+
+// invalidJSON turns a failure to parse a JSON answer into invalid output, so that the model is
+// asked to correct it
+func (v *validator) invalidJSON(err error) error {
+	if v.disabled {
+		return err
+	}
+	return &invalidOutputError{problem: "is not valid JSON in the requested format", err: err}
+}
+
+// countSentences counts the sentences in text, including a last one without a full stop. A
+// full stop followed by a lower case word ends an abbreviation like "p.m." rather than a sentence.
+func countSentences(text string) int {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0
+	}
+	n := 0
+	for _, m := range sentenceEnd.FindAllStringIndex(text, -1) {
+		if next, _ := utf8.DecodeRuneInString(text[m[1]:]); !unicode.IsLower(next) {
+			n++
+		}
+	}
+	if last := strings.TrimRight(text, `"'”’»)]`); last == "" || !strings.ContainsAny(last[len(last)-1:], ".!?") {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package ollama
+
+import (
+	"errors"
+	"testing"
+
+	"lychee-ai-organizer/internal/config"
+)
+
+func TestCountSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"A harbour at dawn", 1},
+		{"A harbour at dawn.", 1},
+		{"A harbour at dawn. Boats are moored along the quay!", 2},
+		{"Shot at f/2.8 with a 50mm lens.", 1},
+		{"Shot at f/2.8. The background is soft.", 2},
+		{"The sign reads 3.50 EUR per hour.", 1},
+		{"Is it raining? Yes... Heavily.", 3},
+		{"Taken at 5 p.m. near the harbour.", 1},
+		{`A sign reads "Closed." The door is locked.`, 2},
+		{`The sign reads "Closed."`, 1},
+		{"Gulls circle (three of them.) Boats wait", 2},
+		{"  Trailing whitespace.  \n", 1},
+	}
+	for _, tt := range tests {
+		if got := countSentences(tt.text); got != tt.want {
+			t.Errorf("countSentences(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPreamblePattern(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Sure! A harbour at dawn.", true},
+		{"Certainly, here is the description: A harbour.", true},
+		{"Of course. A harbour.", true},
+		{"OK, a harbour at dawn.", true},
+		{"**Here is the description:** A harbour.", true},
+		{"Here's a description of the photo: boats.", true},
+		{"Here is a short summary of the album.", true},
+		{"Here are the titles:\nHarbour", true},
+		{"Below is the translation.", true},
+		{"I'd be happy to help.", true},
+		{"Here is a harbour at dawn, with fishing boats along the quay.", false},
+		{"Here are three gulls above the water.", false},
+		{"Surely one of the finest harbours in the region.", false},
+		{"Okayama castle at night.", false},
+		{"A harbour at dawn. Here is the description: boats.", false},
+		{"Harbour at Dawn", false},
+	}
+	for _, tt := range tests {
+		if got := preamblePattern.MatchString(tt.text); got != tt.want {
+			t.Errorf("preamblePattern.MatchString(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestValidatorCheck(t *testing.T) {
+	v := newValidator(&config.ValidationConfig{
+		MinLength:     10,
+		MaxLength:     200,
+		MaxSentences:  map[string]int{"photo_description": 2},
+		BannedPhrases: []string{" Stunning "},
+	})
+
+	tests := []struct {
+		name    string
+		kind    string
+		lang    string
+		text    string
+		invalid bool
+	}{
+		{"valid", PromptPhotoDescription, "en", "A small harbour at dawn, with fishing boats moored along the quay.", false},
+		{"empty", PromptPhotoDescription, "en", "", true},
+		{"too short", PromptPhotoDescription, "en", "Boats.", true},
+		{"too many sentences", PromptPhotoDescription, "en", "A harbour. Some boats. A gull.", true},
+		{"preamble", PromptPhotoDescription, "en", "Sure, here is the description: a harbour with boats.", true},
+		{"banned phrase", PromptPhotoDescription, "en", "A stunning harbour at dawn with boats along the quay.", true},
+		{"built-in banned phrase", PromptPhotoDescription, "en", "As an AI, I see a harbour with boats.", true},
+		{"wrong language", PromptPhotoDescription, "en", "Ein kleiner Hafen im Morgengrauen, mit Fischerbooten an der Kaimauer.", true},
+		{"expected language", PromptPhotoDescription, "de", "Ein kleiner Hafen im Morgengrauen, mit Fischerbooten an der Kaimauer.", false},
+		{"unknown latin language", PromptPhotoDescription, "sv", "A small harbour at dawn, with fishing boats moored along the quay.", false},
+		{"unknown language in another script", PromptPhotoDescription, "sv", "Небольшая гавань на рассвете с рыбацкими лодками.", true},
+		{"title", PromptPhotoTitle, "en", "Harbour at Dawn", false},
+		{"long title", PromptPhotoTitle, "en", "A very long title that goes on and on about the harbour at dawn", true},
+		{"title preamble", PromptPhotoTitle, "en", "Sure! Harbour at Dawn", true},
+		{"unchecked prompt", PromptSuggestions, "en", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.check(tt.kind, tt.lang, tt.text)
+			var invalid *invalidOutputError
+			if got := errors.As(err, &invalid); got != tt.invalid {
+				t.Errorf("check() = %v, want invalid %v", err, tt.invalid)
+			}
+		})
+	}
+
+	if err := newValidator(nil).check(PromptPhotoDescription, "en", ""); err != nil {
+		t.Errorf("disabled validator returned %v", err)
+	}
+}
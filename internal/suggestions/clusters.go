@@ -0,0 +1,180 @@
+package suggestions
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"lychee-ai-organizer/internal/clusters"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/ollama"
+)
+
+// clusterCacheTTL bounds how long clusters of unsorted photos are reused, so that photos
+// added to Lychee in the meantime are picked up
+const clusterCacheTTL = 5 * time.Minute
+
+// ErrClusterNotFound is returned for a cluster ID that doesn't match the current clusters,
+// e.g. because some of its photos have been sorted since it was listed
+var ErrClusterNotFound = errors.New("cluster not found")
+
+// ClusterResult is an event of unsorted photos with suggestions for sorting it as a whole
+type ClusterResult struct {
+	Cluster     clusters.Cluster
+	Suggestions []database.AlbumSuggestion // aggregated over the photos with suggestions
+	Suggested   int                        // number of photos the suggestions are based on
+	Proposal    *database.AlbumProposal    // a pending new album proposal, if one was made
+}
+
+// Clusters groups the unsorted photos into events and aggregates their stored suggestions.
+// Only suggestions computed so far are used; events without any have their representative
+// photo queued, so that suggestions appear on the next request.
+func (s *Service) Clusters() ([]ClusterResult, map[string]database.Album, error) {
+	all, err := s.unsortedClusters()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	albums, err := s.db.GetTopLevelAlbums()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.db.GetCurrentAlbumSuggestions(s.version(albums))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]ClusterResult, len(all))
+	var missing []string
+	for i, c := range all {
+		results[i] = ClusterResult{Cluster: c}
+		results[i].Suggestions, results[i].Suggested = aggregateSuggestions(c.PhotoIDs(), stored)
+		if results[i].Suggested == 0 {
+			missing = append(missing, c.Representative().ID)
+		}
+
+		proposal, err := s.db.GetAlbumProposal(c.ID)
+		if err == nil && proposal.Status == database.AlbumProposalPending {
+			results[i].Proposal = proposal
+		}
+	}
+	s.enqueue(missing, true)
+
+	return results, albumMap(albums), nil
+}
+
+// MoveCluster moves the photos of an event into an album, except the excluded ones. Returns
+// the IDs of the moved photos.
+func (s *Service) MoveCluster(clusterID, albumID string, excluded []string) ([]string, error) {
+	all, err := s.unsortedClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	var cluster *clusters.Cluster
+	for i := range all {
+		if all[i].ID == clusterID {
+			cluster = &all[i]
+			break
+		}
+	}
+	if cluster == nil {
+		return nil, ErrClusterNotFound
+	}
+
+	isExcluded := make(map[string]bool, len(excluded))
+	for _, id := range excluded {
+		isExcluded[id] = true
+	}
+	var photoIDs []string
+	for _, id := range cluster.PhotoIDs() {
+		if !isExcluded[id] {
+			photoIDs = append(photoIDs, id)
+		}
+	}
+
+	if len(photoIDs) == 0 {
+		return nil, ErrNothingToMove
+	}
+
+	if err := s.db.MovePhotosToAlbum(photoIDs, albumID); err != nil {
+		return nil, err
+	}
+	log.Printf("Moved %d photos of cluster %s to album %s", len(photoIDs), clusterID, albumID)
+
+	s.Moved(photoIDs, albumID)
+
+	return photoIDs, nil
+}
+
+// aggregateSuggestions averages the stored suggestions of the given photos. Each album's
+// confidence is its mean over the photos that have suggestions, counting 0 where it wasn't
+// suggested, and its reason comes from the photo that suggested it most confidently.
+func aggregateSuggestions(photoIDs []string, stored map[string][]database.AlbumSuggestion) ([]database.AlbumSuggestion, int) {
+	byAlbum := make(map[string]*database.AlbumSuggestion)
+	best := make(map[string]float64)
+	suggested := 0
+	for _, photoID := range photoIDs {
+		photoSuggestions, ok := stored[photoID]
+		if !ok {
+			continue
+		}
+		suggested++
+		for _, ps := range photoSuggestions {
+			a, ok := byAlbum[ps.AlbumID]
+			if !ok {
+				a = &database.AlbumSuggestion{AlbumID: ps.AlbumID}
+				byAlbum[ps.AlbumID] = a
+			}
+			a.Confidence += ps.Confidence
+			if ps.Confidence > best[ps.AlbumID] {
+				best[ps.AlbumID] = ps.Confidence
+				a.Reason = ps.Reason
+			}
+		}
+	}
+
+	var suggestions []database.AlbumSuggestion
+	for _, a := range byAlbum {
+		a.Confidence = math.Round(a.Confidence/float64(suggested)*100) / 100
+		suggestions = append(suggestions, *a)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].AlbumID < suggestions[j].AlbumID
+	})
+	if len(suggestions) > ollama.MaxSuggestions {
+		suggestions = suggestions[:ollama.MaxSuggestions]
+	}
+	for i := range suggestions {
+		suggestions[i].Rank = i + 1
+	}
+
+	return suggestions, suggested
+}
+
+// unsortedClusters returns the unsorted photos grouped into events, clustering them if needed
+func (s *Service) unsortedClusters() ([]clusters.Cluster, error) {
+	s.metadataMu.Lock()
+	defer s.metadataMu.Unlock()
+
+	if s.clusters == nil || time.Since(s.clustersAt) > clusterCacheTTL {
+		photos, err := s.db.GetUnsortedPhotos()
+		if err != nil {
+			return nil, err
+		}
+		s.clusters = clusters.Build(photos, clusters.Options{
+			Gap:             time.Duration(s.clustersCfg.GapHours * float64(time.Hour)),
+			SplitDistanceKm: s.clustersCfg.SplitDistanceKm,
+		})
+		if s.clusters == nil {
+			s.clusters = []clusters.Cluster{}
+		}
+		s.clustersAt = time.Now()
+	}
+	return s.clusters, nil
+}
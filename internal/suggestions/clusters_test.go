@@ -0,0 +1,44 @@
+package suggestions
+
+import (
+	"testing"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+func TestAggregateSuggestions(t *testing.T) {
+	stored := map[string][]database.AlbumSuggestion{
+		"p1": {{AlbumID: "beach", Confidence: 0.9, Reason: "Sandy beach"}, {AlbumID: "city", Confidence: 0.3, Reason: "Houses"}},
+		"p2": {{AlbumID: "beach", Confidence: 0.7, Reason: "Waves"}},
+		"p3": {{AlbumID: "city", Confidence: 0.7, Reason: "Streets"}, {AlbumID: "food", Confidence: 0.2}, {AlbumID: "zoo", Confidence: 0.2}},
+		"p4": {}, // computed, but nothing fits
+	}
+
+	suggestions, suggested := aggregateSuggestions([]string{"p1", "p2", "p3", "p4", "p5"}, stored)
+	if suggested != 4 {
+		t.Errorf("suggested = %d, want 4; p5 has no stored suggestions", suggested)
+	}
+
+	want := []struct {
+		albumID    string
+		confidence float64
+		reason     string
+	}{
+		{"beach", 0.4, "Sandy beach"}, // (0.9 + 0.7) / 4
+		{"city", 0.25, "Streets"},     // (0.3 + 0.7) / 4, reason from the more confident photo
+		{"food", 0.05, ""},            // tied with zoo, which sorts after it
+	}
+	if len(suggestions) != len(want) {
+		t.Fatalf("got %d suggestions %+v, want %d", len(suggestions), suggestions, len(want))
+	}
+	for i, w := range want {
+		s := suggestions[i]
+		if s.AlbumID != w.albumID || s.Confidence != w.confidence || s.Reason != w.reason || s.Rank != i+1 {
+			t.Errorf("suggestion %d = %+v, want %s at %v (%q)", i, s, w.albumID, w.confidence, w.reason)
+		}
+	}
+
+	if suggestions, suggested := aggregateSuggestions([]string{"p5"}, stored); suggested != 0 || len(suggestions) != 0 {
+		t.Errorf("without stored suggestions: %+v from %d photos", suggestions, suggested)
+	}
+}
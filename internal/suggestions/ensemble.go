@@ -0,0 +1,185 @@
+package suggestions
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geocode"
+	"lychee-ai-organizer/internal/ollama"
+)
+
+// ensembleFormula changes whenever confidences are combined differently, so that suggestions
+// stored under an earlier formula aren't served
+const ensembleFormula = "2"
+
+// member is a suggester and its weight in the ensemble
+type member struct {
+	suggester Suggester
+	weight    float64
+}
+
+// ensemble merges the scores of several suggesters. An album's confidence is the weighted
+// mean of its scores over the suggesters that scored it. The mean is taken over at least the
+// weight of the heaviest suggester that voted, so the model's silence about an album still
+// counts against it, and a lightly weighted heuristic alone can't make an album look certain.
+// Suggesters that score no album at all sit out.
+type ensemble struct {
+	members []member
+}
+
+func newEnsemble(cfg *config.SuggestionsConfig, ollamaClient *ollama.Client, geocoder *geocode.Geocoder) (*ensemble, error) {
+	weights := cfg.Suggesters
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	e := &ensemble{}
+	for _, name := range names {
+		suggester, err := newSuggester(name, cfg, ollamaClient, geocoder)
+		if err != nil {
+			return nil, err
+		}
+		if weights[name] > 0 {
+			e.members = append(e.members, member{suggester: suggester, weight: weights[name]})
+		}
+	}
+	if len(e.members) == 0 {
+		return nil, fmt.Errorf("no suggesters enabled")
+	}
+
+	return e, nil
+}
+
+// key identifies the ensemble's configuration, so that suggestions stored under another one
+// aren't served
+func (e *ensemble) key() string {
+	key := "formula=" + ensembleFormula + ";"
+	for _, m := range e.members {
+		key += m.suggester.Name() + "=" + strconv.FormatFloat(m.weight, 'g', -1, 64)
+		if c, ok := m.suggester.(interface{ configKey() string }); ok {
+			key += "/" + c.configKey()
+		}
+		key += ";"
+	}
+	return key
+}
+
+// suggest runs every suggester and returns the best albums, best first, with each suggester's
+// contribution. complete is false if a suggester failed, in which case the suggestions are
+// based on the others and shouldn't be stored. If every suggester failed, the first error
+// is returned.
+func (e *ensemble) suggest(in *Input) (suggestions []database.AlbumSuggestion, complete bool, err error) {
+	type vote struct {
+		member member
+		scores []Score
+	}
+
+	var votes []vote
+	var firstErr error
+	heaviest := 0.0
+	for _, m := range e.members {
+		scores, err := m.suggester.Suggest(in)
+		if errors.Is(err, ErrAbstain) {
+			continue
+		} else if err != nil {
+			log.Printf("Suggester %s failed for photo %s: %v", m.suggester.Name(), in.Photo.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !hasScore(scores) {
+			continue
+		}
+		votes = append(votes, vote{member: m, scores: scores})
+		heaviest = math.Max(heaviest, m.weight)
+	}
+
+	if len(votes) == 0 {
+		return nil, firstErr == nil, firstErr
+	}
+
+	// Sum the weights of the suggesters scoring each album first, to share them out below
+	scorers := make(map[string]float64)
+	for _, v := range votes {
+		for _, score := range v.scores {
+			if clampScore(score.Confidence) > 0 {
+				scorers[score.AlbumID] += v.member.weight
+			}
+		}
+	}
+
+	byAlbum := make(map[string]*database.AlbumSuggestion)
+	best := make(map[string]float64) // largest weighted score behind each album's reason
+	for _, v := range votes {
+		for _, score := range v.scores {
+			confidence := clampScore(score.Confidence)
+			if confidence == 0 {
+				continue
+			}
+			share := v.member.weight / math.Max(scorers[score.AlbumID], heaviest)
+
+			s, ok := byAlbum[score.AlbumID]
+			if !ok {
+				s = &database.AlbumSuggestion{PhotoID: in.Photo.ID, AlbumID: score.AlbumID}
+				byAlbum[score.AlbumID] = s
+			}
+			s.Confidence += confidence * share
+			s.Contributions = append(s.Contributions, database.SuggestionContribution{
+				Suggester:  v.member.suggester.Name(),
+				Confidence: confidence,
+				Weight:     share,
+			})
+			if score.Reason != "" && confidence*share > best[score.AlbumID] {
+				best[score.AlbumID] = confidence * share
+				s.Reason = score.Reason
+			}
+		}
+	}
+
+	for _, s := range byAlbum {
+		s.Confidence = math.Round(s.Confidence*100) / 100
+		sort.SliceStable(s.Contributions, func(i, j int) bool {
+			return s.Contributions[i].Confidence*s.Contributions[i].Weight > s.Contributions[j].Confidence*s.Contributions[j].Weight
+		})
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].AlbumID < suggestions[j].AlbumID
+	})
+
+	if len(suggestions) > ollama.MaxSuggestions {
+		suggestions = suggestions[:ollama.MaxSuggestions]
+	}
+	for i := range suggestions {
+		suggestions[i].Rank = i + 1
+	}
+
+	return suggestions, firstErr == nil, nil
+}
+
+// clampScore limits a suggester's confidence to [0, 1]
+func clampScore(confidence float64) float64 {
+	return math.Max(0, math.Min(1, confidence))
+}
+
+// hasScore reports whether a suggester gave any album a score above 0
+func hasScore(scores []Score) bool {
+	for _, score := range scores {
+		if clampScore(score.Confidence) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package suggestions
+
+import (
+	"errors"
+	"testing"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+// fakeSuggester returns fixed scores, or abstains when it has none
+type fakeSuggester struct {
+	name   string
+	scores []Score
+	err    error
+}
+
+func (f fakeSuggester) Name() string { return f.name }
+
+func (f fakeSuggester) Suggest(in *Input) ([]Score, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.scores) == 0 {
+		return nil, ErrAbstain
+	}
+	return f.scores, nil
+}
+
+// defaultEnsemble weights fake suggesters like config.DefaultSuggesterWeights
+func defaultEnsemble(llm, neighbours, dateRange, location, camera []Score) *ensemble {
+	return &ensemble{members: []member{
+		{fakeSuggester{name: "camera", scores: camera}, 0.1},
+		{fakeSuggester{name: "date_range", scores: dateRange}, 0.5},
+		{fakeSuggester{name: "llm", scores: llm}, 1.0},
+		{fakeSuggester{name: "location", scores: location}, 0.5},
+		{fakeSuggester{name: "neighbours", scores: neighbours}, 1.0},
+	}}
+}
+
+func score(albumID string, confidence float64) []Score {
+	return []Score{{AlbumID: albumID, Confidence: confidence, Reason: "because " + albumID}}
+}
+
+func TestEnsembleConfidences(t *testing.T) {
+	type want struct {
+		albumID    string
+		confidence float64
+	}
+	tests := []struct {
+		name     string
+		ensemble *ensemble
+		want     []want
+	}{
+		{
+			name:     "model alone",
+			ensemble: defaultEnsemble(score("a", 0.9), nil, nil, nil, nil),
+			want:     []want{{"a", 0.9}},
+		},
+		{
+			name:     "metadata agreeing with the model",
+			ensemble: defaultEnsemble(score("a", 0.9), nil, score("a", 1), score("a", 0.8), score("a", 1)),
+			want:     []want{{"a", 0.9}}, // (0.9 + 0.5 + 0.4 + 0.1) / 2.1
+		},
+		{
+			name:     "metadata matching another album",
+			ensemble: defaultEnsemble(score("a", 0.9), nil, score("b", 1), nil, score("b", 1)),
+			want:     []want{{"a", 0.9}, {"b", 0.6}}, // b: (0.5 + 0.1) / 1.0, the model's weight
+		},
+		{
+			name:     "neighbours and model agreeing",
+			ensemble: defaultEnsemble(score("a", 0.8), score("a", 1), nil, nil, nil),
+			want:     []want{{"a", 0.9}},
+		},
+		{
+			name:     "neighbours disagreeing with the model",
+			ensemble: defaultEnsemble(score("a", 0.9), score("b", 0.7), nil, nil, nil),
+			want:     []want{{"a", 0.9}, {"b", 0.7}},
+		},
+		{
+			name:     "camera alone",
+			ensemble: defaultEnsemble(nil, nil, nil, nil, score("a", 1)),
+			want:     []want{{"a", 1}}, // the only voter, so its own weight is the heaviest
+		},
+		{
+			name:     "camera next to the date range",
+			ensemble: defaultEnsemble(nil, nil, score("a", 1), nil, score("b", 1)),
+			want:     []want{{"a", 1}, {"b", 0.2}}, // b: 0.1 / 0.5
+		},
+		{
+			name: "more than three albums",
+			ensemble: defaultEnsemble(
+				[]Score{{AlbumID: "a", Confidence: 0.9}, {AlbumID: "b", Confidence: 0.5}, {AlbumID: "c", Confidence: 0.3}},
+				nil, score("d", 0.2), nil, nil),
+			want: []want{{"a", 0.9}, {"b", 0.5}, {"c", 0.3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions, complete, err := tt.ensemble.suggest(&Input{Photo: &database.Photo{ID: "p"}})
+			if err != nil || !complete {
+				t.Fatalf("suggest: complete %v, error %v", complete, err)
+			}
+			if len(suggestions) != len(tt.want) {
+				t.Fatalf("got %d suggestions %+v, want %d", len(suggestions), suggestions, len(tt.want))
+			}
+			for i, w := range tt.want {
+				s := suggestions[i]
+				if s.AlbumID != w.albumID || s.Confidence != w.confidence || s.Rank != i+1 {
+					t.Errorf("suggestion %d = %s at %v (rank %d), want %s at %v", i, s.AlbumID, s.Confidence, s.Rank, w.albumID, w.confidence)
+				}
+				total := 0.0
+				for _, c := range s.Contributions {
+					total += c.Confidence * c.Weight
+				}
+				if diff := total - s.Confidence; diff > 0.005 || diff < -0.005 {
+					t.Errorf("contributions of %s add up to %v, not %v", s.AlbumID, total, s.Confidence)
+				}
+			}
+		})
+	}
+}
+
+func TestEnsembleFailures(t *testing.T) {
+	down := errors.New("ollama is down")
+	e := &ensemble{members: []member{
+		{fakeSuggester{name: "llm", err: down}, 1.0},
+		{fakeSuggester{name: "date_range", scores: score("a", 0.8)}, 0.5},
+	}}
+	suggestions, complete, err := e.suggest(&Input{Photo: &database.Photo{ID: "p"}})
+	if err != nil || complete {
+		t.Errorf("with one suggester failing: complete %v, error %v; want incomplete suggestions", complete, err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Confidence != 0.8 {
+		t.Errorf("suggestions = %+v, want a at 0.8 from the date range alone", suggestions)
+	}
+
+	e = &ensemble{members: []member{{fakeSuggester{name: "llm", err: down}, 1.0}}}
+	if _, _, err := e.suggest(&Input{Photo: &database.Photo{ID: "p"}}); !errors.Is(err, down) {
+		t.Errorf("with every suggester failing, error = %v, want %v", err, down)
+	}
+
+	e = &ensemble{members: []member{{fakeSuggester{name: "camera"}, 0.1}}}
+	suggestions, complete, err = e.suggest(&Input{Photo: &database.Photo{ID: "p"}})
+	if err != nil || !complete || len(suggestions) != 0 {
+		t.Errorf("with every suggester abstaining: %+v, complete %v, error %v; want none", suggestions, complete, err)
+	}
+}
@@ -0,0 +1,215 @@
+package suggestions
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geo"
+	"lychee-ai-organizer/internal/geocode"
+)
+
+const (
+	// eventSpan is the longest date range an album can cover and still count as a single event;
+	// photos inside wider ranges are weaker evidence
+	eventSpan = 31 * 24 * time.Hour
+	// dateHalfLife is how far outside an album's date range a photo's score halves
+	dateHalfLife = 7 * 24 * time.Hour
+	// locationHalfLifeKm is the distance from an album's nearest photo at which a photo's score halves
+	locationHalfLifeKm = 10.0
+	// sameCityScore is the least score for an album whose nearest photo was taken in the same city
+	sameCityScore = 0.5
+	// minHeuristicScore is the score below which a heuristic match is dropped as noise
+	minHeuristicScore = 0.05
+)
+
+// dateRangeSuggester favours albums whose photos were taken around the same time as the photo
+type dateRangeSuggester struct{}
+
+func (dateRangeSuggester) Name() string { return "date_range" }
+
+func (dateRangeSuggester) Suggest(in *Input) ([]Score, error) {
+	if !in.Photo.TakenAt.Valid {
+		return nil, ErrAbstain
+	}
+	taken := in.Photo.TakenAt.Time
+
+	var scores []Score
+	for albumID, photos := range in.AlbumPhotos {
+		var first, last time.Time
+		for _, p := range photos {
+			if !p.TakenAt.Valid {
+				continue
+			}
+			if first.IsZero() || p.TakenAt.Time.Before(first) {
+				first = p.TakenAt.Time
+			}
+			if last.IsZero() || p.TakenAt.Time.After(last) {
+				last = p.TakenAt.Time
+			}
+		}
+		if first.IsZero() {
+			continue
+		}
+
+		var score float64
+		var reason string
+		switch {
+		case taken.Before(first):
+			score = halve(first.Sub(taken).Hours(), dateHalfLife.Hours())
+			reason = fmt.Sprintf("Taken %s before the album's first photo", formatGap(first.Sub(taken)))
+		case taken.After(last):
+			score = halve(taken.Sub(last).Hours(), dateHalfLife.Hours())
+			reason = fmt.Sprintf("Taken %s after the album's last photo", formatGap(taken.Sub(last)))
+		default:
+			score = 1
+			if span := last.Sub(first); span > eventSpan {
+				score = math.Sqrt(float64(eventSpan) / float64(span))
+			}
+			reason = "Taken within the album's date range"
+		}
+
+		if score >= minHeuristicScore {
+			scores = append(scores, Score{AlbumID: albumID, Confidence: score, Reason: reason})
+		}
+	}
+
+	if len(scores) == 0 {
+		return nil, ErrAbstain
+	}
+	return scores, nil
+}
+
+// locationSuggester favours albums with photos taken near where the photo was taken. With a
+// geocoder, an album whose nearest photo lies in the same city scores at least sameCityScore,
+// so that a city trip spread over a large metropolitan area still matches.
+type locationSuggester struct {
+	geocoder *geocode.Geocoder
+}
+
+func (locationSuggester) Name() string { return "location" }
+
+func (s locationSuggester) Suggest(in *Input) ([]Score, error) {
+	if !in.Photo.Latitude.Valid || !in.Photo.Longitude.Valid {
+		return nil, ErrAbstain
+	}
+	lat, lon := in.Photo.Latitude.Float64, in.Photo.Longitude.Float64
+	place, placed := s.geocoder.Lookup(lat, lon)
+
+	var scores []Score
+	for albumID, photos := range in.AlbumPhotos {
+		nearest := math.Inf(1)
+		var nearestPhoto *database.AlbumPhotoMetadata
+		for i, p := range photos {
+			if p.Latitude.Valid && p.Longitude.Valid {
+				if d := geo.DistanceKm(lat, lon, p.Latitude.Float64, p.Longitude.Float64); d < nearest {
+					nearest, nearestPhoto = d, &photos[i]
+				}
+			}
+		}
+		if math.IsInf(nearest, 1) {
+			continue
+		}
+
+		score := halve(nearest, locationHalfLifeKm)
+		reason := fmt.Sprintf("Taken %.1f km from a photo in the album", nearest)
+		if placed {
+			reason = fmt.Sprintf("Taken in %s, %.1f km from a photo in the album", place.Describe(), nearest)
+			if score < sameCityScore {
+				other, ok := s.geocoder.Lookup(nearestPhoto.Latitude.Float64, nearestPhoto.Longitude.Float64)
+				if ok && other.String() == place.String() {
+					score = sameCityScore
+					reason = fmt.Sprintf("Taken in %s like photos in the album", place.String())
+				}
+			}
+		}
+
+		if score >= minHeuristicScore {
+			scores = append(scores, Score{AlbumID: albumID, Confidence: score, Reason: reason})
+		}
+	}
+
+	if len(scores) == 0 {
+		return nil, ErrAbstain
+	}
+	return scores, nil
+}
+
+// cameraSuggester favours albums shot mostly with the same camera as the photo
+type cameraSuggester struct{}
+
+func (cameraSuggester) Name() string { return "camera" }
+
+func (cameraSuggester) Suggest(in *Input) ([]Score, error) {
+	camera := cameraName(in.Photo.Make, in.Photo.Model)
+	if camera == "" {
+		return nil, ErrAbstain
+	}
+
+	var scores []Score
+	for albumID, photos := range in.AlbumPhotos {
+		known, same := 0, 0
+		for _, p := range photos {
+			c := cameraName(p.Make, p.Model)
+			if c == "" {
+				continue
+			}
+			known++
+			if c == camera {
+				same++
+			}
+		}
+		if same == 0 {
+			continue
+		}
+
+		share := float64(same) / float64(known)
+		if share >= minHeuristicScore {
+			scores = append(scores, Score{
+				AlbumID:    albumID,
+				Confidence: share,
+				Reason:     fmt.Sprintf("%.0f%% of the album was taken with the same camera", share*100),
+			})
+		}
+	}
+
+	if len(scores) == 0 {
+		return nil, ErrAbstain
+	}
+	return scores, nil
+}
+
+// halve returns a score of 1 at distance 0 that halves every halfLife
+func halve(distance, halfLife float64) float64 {
+	return math.Pow(0.5, distance/halfLife)
+}
+
+// cameraName combines make and model into a comparable name, or "" if neither is known
+func cameraName(maker, model sql.NullString) string {
+	var parts []string
+	for _, v := range []sql.NullString{maker, model} {
+		if v.Valid && strings.TrimSpace(v.String) != "" {
+			parts = append(parts, strings.ToLower(strings.TrimSpace(v.String)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatGap describes a time difference in days, or hours if less than a day
+func formatGap(d time.Duration) string {
+	if d < 24*time.Hour {
+		hours := int(d.Hours())
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+	days := int(d.Hours() / 24)
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
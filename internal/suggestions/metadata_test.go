@@ -0,0 +1,112 @@
+package suggestions
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"lychee-ai-organizer/internal/database"
+)
+
+func takenAt(t time.Time) sql.NullTime { return sql.NullTime{Time: t, Valid: true} }
+
+func coordinate(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
+
+func text(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+
+// scoreOf returns the confidence given to an album, or 0 if it wasn't scored
+func scoreOf(scores []Score, albumID string) float64 {
+	for _, s := range scores {
+		if s.AlbumID == albumID {
+			return s.Confidence
+		}
+	}
+	return 0
+}
+
+func TestDateRangeSuggester(t *testing.T) {
+	day := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	in := &Input{
+		Photo: &database.Photo{TakenAt: takenAt(day)},
+		AlbumPhotos: map[string][]database.AlbumPhotoMetadata{
+			"within": {{TakenAt: takenAt(day.AddDate(0, 0, -2))}, {TakenAt: takenAt(day.AddDate(0, 0, 2))}},
+			"week":   {{TakenAt: takenAt(day.AddDate(0, 0, -7))}},
+			"year":   {{TakenAt: takenAt(day.AddDate(-1, 0, 0))}},
+		},
+	}
+	scores, err := dateRangeSuggester{}.Suggest(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scoreOf(scores, "within"); got != 1 {
+		t.Errorf("album around the photo scored %v, want 1", got)
+	}
+	if got := scoreOf(scores, "week"); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("album a week before scored %v, want 0.5", got)
+	}
+	if got := scoreOf(scores, "year"); got != 0 {
+		t.Errorf("album a year before scored %v, want none", got)
+	}
+
+	delete(in.AlbumPhotos, "within")
+	delete(in.AlbumPhotos, "week")
+	if _, err := (dateRangeSuggester{}).Suggest(in); !errors.Is(err, ErrAbstain) {
+		t.Errorf("with no album near the date, error = %v, want ErrAbstain", err)
+	}
+	if _, err := (dateRangeSuggester{}).Suggest(&Input{Photo: &database.Photo{}}); !errors.Is(err, ErrAbstain) {
+		t.Errorf("without a date, error = %v, want ErrAbstain", err)
+	}
+}
+
+func TestLocationSuggester(t *testing.T) {
+	in := &Input{
+		Photo: &database.Photo{Latitude: coordinate(38.7223), Longitude: coordinate(-9.1393)}, // Lisbon
+		AlbumPhotos: map[string][]database.AlbumPhotoMetadata{
+			"lisbon": {{Latitude: coordinate(38.7223), Longitude: coordinate(-9.1393)}},
+			"porto":  {{Latitude: coordinate(41.1579), Longitude: coordinate(-8.6291)}},
+			"none":   {{}},
+		},
+	}
+	scores, err := locationSuggester{}.Suggest(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scoreOf(scores, "lisbon"); got != 1 {
+		t.Errorf("album at the same spot scored %v, want 1", got)
+	}
+	if got := scoreOf(scores, "porto"); got != 0 {
+		t.Errorf("album 270 km away scored %v, want none", got)
+	}
+
+	delete(in.AlbumPhotos, "lisbon")
+	if _, err := (locationSuggester{}).Suggest(in); !errors.Is(err, ErrAbstain) {
+		t.Errorf("with no album nearby, error = %v, want ErrAbstain", err)
+	}
+}
+
+func TestCameraSuggester(t *testing.T) {
+	in := &Input{
+		Photo: &database.Photo{Make: text("FUJIFILM"), Model: text("X100V")},
+		AlbumPhotos: map[string][]database.AlbumPhotoMetadata{
+			"mixed": {{Make: text("Fujifilm"), Model: text("X100V ")}, {Make: text("Apple"), Model: text("iPhone 15")}},
+			"other": {{Make: text("Apple"), Model: text("iPhone 15")}},
+		},
+	}
+	scores, err := cameraSuggester{}.Suggest(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scoreOf(scores, "mixed"); got != 0.5 {
+		t.Errorf("album half taken with the camera scored %v, want 0.5", got)
+	}
+	if got := scoreOf(scores, "other"); got != 0 {
+		t.Errorf("album taken with another camera scored %v, want none", got)
+	}
+
+	delete(in.AlbumPhotos, "mixed")
+	if _, err := (cameraSuggester{}).Suggest(in); !errors.Is(err, ErrAbstain) {
+		t.Errorf("with no album taken with the camera, error = %v, want ErrAbstain", err)
+	}
+}
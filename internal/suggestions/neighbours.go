@@ -0,0 +1,140 @@
+package suggestions
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// neighbourSuggester proposes the albums of sorted photos taken shortly before or after the
+// photo. A photo taken between two photos of the same album almost certainly belongs there.
+// It needs nothing but timestamps, so it works without any model.
+type neighbourSuggester struct {
+	window time.Duration
+}
+
+func (neighbourSuggester) Name() string { return "neighbours" }
+
+// configKey is part of the ensemble key, so that changing the window invalidates stored suggestions
+func (s neighbourSuggester) configKey() string { return s.window.String() }
+
+func (s neighbourSuggester) Suggest(in *Input) ([]Score, error) {
+	if !in.Photo.TakenAt.Valid || s.window <= 0 {
+		return nil, ErrAbstain
+	}
+	photo := newTimestamp(in.Photo.TakenAt, in.Photo.TakenAtOrigTz)
+
+	var scores []Score
+	for albumID, photos := range in.AlbumPhotos {
+		before, after := time.Duration(-1), time.Duration(-1)
+		for _, p := range photos {
+			if !p.TakenAt.Valid {
+				continue
+			}
+			d := photo.sub(newTimestamp(p.TakenAt, p.TakenAtOrigTz))
+			switch {
+			case d >= 0 && d <= s.window && (before < 0 || d < before):
+				before = d
+			case d < 0 && -d <= s.window && (after < 0 || -d < after):
+				after = -d
+			}
+		}
+		if before < 0 && after < 0 {
+			continue
+		}
+
+		nearest, reason := before, fmt.Sprintf("Taken %s after a photo in the album", formatShortGap(before))
+		if before < 0 || (after >= 0 && after < before) {
+			nearest, reason = after, fmt.Sprintf("Taken %s before a photo in the album", formatShortGap(after))
+		}
+
+		// Closeness falls linearly to 0 at the edge of the window; being surrounded by the
+		// album's photos halves the remaining doubt
+		score := 1 - float64(nearest)/float64(s.window)
+		if before >= 0 && after >= 0 {
+			score = 1 - (1-score)/2
+			reason = "Taken between two photos in the album"
+		}
+
+		if score >= minHeuristicScore {
+			scores = append(scores, Score{AlbumID: albumID, Confidence: score, Reason: reason})
+		}
+	}
+
+	if len(scores) == 0 {
+		return nil, ErrAbstain
+	}
+	return scores, nil
+}
+
+// timestamp is a capture time that knows whether it is an absolute instant. Lychee stores
+// taken_at converted to UTC when the original time zone is known; without one it holds the
+// camera's wall clock time as is.
+type timestamp struct {
+	t    time.Time
+	zone *time.Location // original time zone, nil if unknown
+}
+
+func newTimestamp(takenAt sql.NullTime, origTz sql.NullString) timestamp {
+	ts := timestamp{t: takenAt.Time}
+	if origTz.Valid {
+		ts.zone = parseZone(origTz.String)
+	}
+	return ts
+}
+
+// sub returns ts - other. Two instants are compared directly; if either time zone is unknown,
+// wall clock times are compared instead, which is right for photos from the same trip.
+func (ts timestamp) sub(other timestamp) time.Duration {
+	if ts.zone != nil && other.zone != nil {
+		return ts.t.Sub(other.t)
+	}
+	return ts.wallClock().Sub(other.wallClock())
+}
+
+// wallClock returns the local time at which the photo was taken, as if it were UTC
+func (ts timestamp) wallClock() time.Time {
+	t := ts.t
+	if ts.zone != nil {
+		t = t.In(ts.zone)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+var offsetPattern = regexp.MustCompile(`^(?:UTC|GMT)?([+-])(\d{1,2}):?(\d{2})?$`)
+
+// parseZone understands offsets like "+02:00" and IANA names like "Europe/Lisbon"
+func parseZone(name string) *time.Location {
+	if name == "" {
+		return nil
+	}
+	if m := offsetPattern.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset)
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return nil
+}
+
+// formatShortGap describes a time difference in minutes, or hours and days if longer
+func formatShortGap(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "less than a minute"
+	case d < 2*time.Minute:
+		return "1 minute"
+	case d < 2*time.Hour:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	default:
+		return formatGap(d)
+	}
+}
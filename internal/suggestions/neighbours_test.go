@@ -0,0 +1,147 @@
+package suggestions
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+	"time"
+	_ "time/tzdata" // for Europe/Lisbon and Asia/Tokyo on systems without zoneinfo
+
+	"lychee-ai-organizer/internal/database"
+)
+
+func TestNeighbourSuggester(t *testing.T) {
+	at := func(hour, minute int) sql.NullTime {
+		return takenAt(time.Date(2024, 5, 4, hour, minute, 0, 0, time.UTC))
+	}
+	zone := func(name string) sql.NullString { return text(name) }
+	noZone := sql.NullString{}
+
+	tests := []struct {
+		name       string
+		photo      database.Photo
+		album      []database.AlbumPhotoMetadata
+		want       float64 // 0 if the suggester should abstain
+		wantReason string
+	}{
+		{
+			name:       "between two photos",
+			photo:      database.Photo{TakenAt: at(14, 5)},
+			album:      []database.AlbumPhotoMetadata{{TakenAt: at(14, 0)}, {TakenAt: at(14, 10)}},
+			want:       1 - (5.0/60)/2,
+			wantReason: "Taken between two photos in the album",
+		},
+		{
+			name:       "after a photo",
+			photo:      database.Photo{TakenAt: at(14, 30)},
+			album:      []database.AlbumPhotoMetadata{{TakenAt: at(14, 0)}},
+			want:       0.5,
+			wantReason: "Taken 30 minutes after a photo in the album",
+		},
+		{
+			name:       "before a photo",
+			photo:      database.Photo{TakenAt: at(13, 45)},
+			album:      []database.AlbumPhotoMetadata{{TakenAt: at(14, 0)}, {TakenAt: at(16, 0)}},
+			want:       0.75,
+			wantReason: "Taken 15 minutes before a photo in the album",
+		},
+		{
+			name:  "outside the window",
+			photo: database.Photo{TakenAt: at(14, 5)},
+			album: []database.AlbumPhotoMetadata{{TakenAt: at(12, 0)}, {TakenAt: at(16, 10)}},
+		},
+		{
+			name:  "album photos without dates",
+			photo: database.Photo{TakenAt: at(14, 5)},
+			album: []database.AlbumPhotoMetadata{{}},
+		},
+		{
+			// Both know their zone, so UTC instants are compared
+			name:  "instants in different zones",
+			photo: database.Photo{TakenAt: at(14, 5), TakenAtOrigTz: zone("+01:00")},
+			album: []database.AlbumPhotoMetadata{
+				{TakenAt: at(14, 0), TakenAtOrigTz: zone("Europe/Lisbon")},
+				{TakenAt: at(14, 10), TakenAtOrigTz: zone("Asia/Tokyo")},
+			},
+			want: 1 - (5.0/60)/2,
+		},
+		{
+			// The photo holds the camera's wall clock, 15:05 in Lisbon's summer time; the album's
+			// photos are instants at 14:00 and 14:10 UTC, which is 15:00 and 15:10 in Lisbon
+			name:  "wall clock against instants",
+			photo: database.Photo{TakenAt: at(15, 5), TakenAtOrigTz: noZone},
+			album: []database.AlbumPhotoMetadata{
+				{TakenAt: at(14, 0), TakenAtOrigTz: zone("Europe/Lisbon")},
+				{TakenAt: at(14, 10), TakenAtOrigTz: zone("+01:00")},
+			},
+			want: 1 - (5.0/60)/2,
+		},
+		{
+			// Both show 14:05 on the camera's clock, but as instants they are an hour apart,
+			// at the edge of the window
+			name:  "same wall clock, an hour apart",
+			photo: database.Photo{TakenAt: at(14, 5), TakenAtOrigTz: zone("+00:00")},
+			album: []database.AlbumPhotoMetadata{{TakenAt: at(15, 5), TakenAtOrigTz: zone("-01:00")}},
+		},
+		{
+			// A zone that can't be read counts as unknown, so wall clocks are compared
+			name:  "unreadable zone",
+			photo: database.Photo{TakenAt: at(14, 5)},
+			album: []database.AlbumPhotoMetadata{{TakenAt: at(14, 5), TakenAtOrigTz: zone("not a zone")}},
+			want:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := neighbourSuggester{window: time.Hour}
+			in := &Input{
+				Photo:       &tt.photo,
+				AlbumPhotos: map[string][]database.AlbumPhotoMetadata{"lisbon": tt.album},
+			}
+			scores, err := s.Suggest(in)
+			if tt.want == 0 {
+				if !errors.Is(err, ErrAbstain) {
+					t.Errorf("got %+v, %v; want ErrAbstain", scores, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(scores) != 1 || math.Abs(scores[0].Confidence-tt.want) > 1e-9 {
+				t.Fatalf("scores = %+v, want lisbon at %v", scores, tt.want)
+			}
+			if tt.wantReason != "" && scores[0].Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", scores[0].Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestParseZone(t *testing.T) {
+	tests := []struct {
+		name   string
+		known  bool
+		offset int // seconds east of UTC in May 2024
+	}{
+		{"+02:00", true, 2 * 3600},
+		{"-0530", true, -(5*3600 + 30*60)},
+		{"UTC+1", true, 3600},
+		{"Europe/Lisbon", true, 3600}, // summer time
+		{"", false, 0},
+		{"Mars/Olympus", false, 0},
+	}
+	for _, tt := range tests {
+		zone := parseZone(tt.name)
+		if zone == nil || !tt.known {
+			if (zone != nil) != tt.known {
+				t.Errorf("parseZone(%q) = %v, want a zone: %v", tt.name, zone, tt.known)
+			}
+			continue
+		}
+		if _, offset := time.Date(2024, 5, 4, 12, 0, 0, 0, zone).Zone(); offset != tt.offset {
+			t.Errorf("parseZone(%q) has offset %d, want %d", tt.name, offset, tt.offset)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package suggestions
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"lychee-ai-organizer/internal/clusters"
+	"lychee-ai-organizer/internal/database"
+)
+
+var (
+	// ErrProposalReviewed is returned when approving or rejecting a proposal that was already reviewed
+	ErrProposalReviewed = database.ErrProposalReviewed
+	// ErrNothingToMove is returned when approving a proposal whose photos have all been sorted or excluded
+	ErrNothingToMove = errors.New("none of the proposal's photos are left to move")
+)
+
+// proposalFor returns a pending new album proposal for the event a photo belongs to, if the
+// photo fits no existing album well. A proposal is generated the first time it is needed;
+// nil is returned if that isn't possible right now.
+func (s *Service) proposalFor(photoID string, suggestions []database.AlbumSuggestion) *database.AlbumProposal {
+	cfg := s.cfg.NewAlbums
+	if cfg.Disabled {
+		return nil
+	}
+	if len(suggestions) > 0 && suggestions[0].Confidence >= s.cfg.LowConfidenceThreshold {
+		return nil
+	}
+
+	all, err := s.unsortedClusters()
+	if err != nil {
+		log.Printf("Error clustering unsorted photos: %v", err)
+		return nil
+	}
+	cluster, ok := clusters.Find(all, photoID)
+	if !ok || len(cluster.Photos) < cfg.MinPhotos {
+		return nil
+	}
+
+	// Photos of one cluster are often looked at in a row; generate its proposal only once
+	s.mu.Lock()
+	for {
+		wait, ok := s.proposing[cluster.ID]
+		if !ok {
+			break
+		}
+		s.mu.Unlock()
+		<-wait
+		s.mu.Lock()
+	}
+	done := make(chan struct{})
+	s.proposing[cluster.ID] = done
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.proposing, cluster.ID)
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	proposal, err := s.db.GetAlbumProposal(cluster.ID)
+	if err == nil {
+		if proposal.Status != database.AlbumProposalPending {
+			return nil
+		}
+		return proposal
+	} else if err != sql.ErrNoRows {
+		log.Printf("Error getting album proposal %s: %v", cluster.ID, err)
+		return nil
+	}
+
+	if !s.ollama.Available() {
+		return nil
+	}
+
+	title, description, err := s.ollama.GenerateAlbumProposal(cluster.ID, cluster.Photos)
+	if err != nil {
+		log.Printf("Error generating album proposal for cluster %s: %v", cluster.ID, err)
+		return nil
+	}
+
+	proposal = &database.AlbumProposal{
+		ID:          cluster.ID,
+		Title:       title,
+		Description: description,
+		Status:      database.AlbumProposalPending,
+		CreatedAt:   time.Now(),
+		PhotoIDs:    cluster.PhotoIDs(),
+	}
+	if err := s.db.SaveAlbumProposal(proposal); err != nil {
+		// An unsaved proposal couldn't be approved
+		log.Printf("Error saving album proposal %s: %v", cluster.ID, err)
+		return nil
+	}
+
+	log.Printf("Proposed new album %q for %d photos", title, len(proposal.PhotoIDs))
+	return proposal
+}
+
+// ApproveProposal creates the proposed album with the given title and description and moves
+// the proposal's photos into it, except the excluded ones and any sorted in the meantime.
+// Returns the new album's ID.
+func (s *Service) ApproveProposal(proposalID, title, description string, excluded []string) (string, error) {
+	proposal, err := s.db.GetAlbumProposal(proposalID)
+	if err != nil {
+		return "", err
+	}
+	if proposal.Status != database.AlbumProposalPending {
+		return "", ErrProposalReviewed
+	}
+
+	unsorted, err := s.db.GetUnsortedPhotoIDs()
+	if err != nil {
+		return "", err
+	}
+	isUnsorted := make(map[string]bool, len(unsorted))
+	for _, id := range unsorted {
+		isUnsorted[id] = true
+	}
+	isExcluded := make(map[string]bool, len(excluded))
+	for _, id := range excluded {
+		isExcluded[id] = true
+	}
+
+	var photoIDs []string
+	for _, id := range proposal.PhotoIDs {
+		if isUnsorted[id] && !isExcluded[id] {
+			photoIDs = append(photoIDs, id)
+		}
+	}
+	if len(photoIDs) == 0 {
+		return "", ErrNothingToMove
+	}
+
+	first, err := s.db.GetPhoto(photoIDs[0])
+	if err != nil {
+		return "", err
+	}
+
+	albumID, err := s.db.CreateAlbumFromProposal(proposalID, title, description, first.OwnerID, photoIDs)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Created album %s (%s) with %d photos from proposal %s", albumID, title, len(photoIDs), proposalID)
+
+	for _, id := range photoIDs {
+		s.Forget(id)
+	}
+	// The new album joins the album set, so every stored suggestion is now outdated
+	go s.Invalidate()
+
+	return albumID, nil
+}
@@ -0,0 +1,433 @@
+package suggestions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"lychee-ai-organizer/internal/clusters"
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geocode"
+	"lychee-ai-organizer/internal/ollama"
+)
+
+// ErrUnavailable is returned when suggestions need the model but Ollama is unreachable
+var ErrUnavailable = errors.New("ollama is unavailable")
+
+// Result holds the album suggestions for a photo
+type Result struct {
+	Suggestions []database.AlbumSuggestion
+	Albums      map[string]database.Album // all suggestible albums, by ID
+	Version     string                    // album set version the suggestions were computed against
+	Cached      bool                      // true if served from precomputed suggestions
+	Proposal    *database.AlbumProposal   // a new album for the photo's event, if nothing fits
+}
+
+// Service serves album suggestions from storage, computing missing ones on demand and
+// prefetching upcoming photos in the background. Suggestions come from an ensemble of
+// suggesters configured in cfg.Suggesters.
+type Service struct {
+	db          *database.DB
+	ollama      *ollama.Client
+	cfg         *config.SuggestionsConfig
+	clustersCfg *config.ClustersConfig
+	ensemble    *ensemble
+
+	metadataMu  sync.Mutex
+	albumPhotos []database.AlbumPhotoMetadata // loaded on first use, reset when albums change
+	clusters    []clusters.Cluster            // unsorted photos grouped into events
+	clustersAt  time.Time
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     []string
+	queued    map[string]bool
+	inflight  map[string]*call
+	proposing map[string]chan struct{} // album proposals being generated, by cluster ID
+}
+
+// call is a suggestion computation in progress, shared by everyone asking for the same photo
+type call struct {
+	done   chan struct{}
+	result *Result
+	err    error
+}
+
+func NewService(db *database.DB, ollamaClient *ollama.Client, cfg *config.SuggestionsConfig, clustersCfg *config.ClustersConfig, geocoder *geocode.Geocoder) (*Service, error) {
+	e, err := newEnsemble(cfg, ollamaClient, geocoder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid suggesters configuration: %w", err)
+	}
+
+	s := &Service{
+		db:          db,
+		ollama:      ollamaClient,
+		cfg:         cfg,
+		clustersCfg: clustersCfg,
+		ensemble:    e,
+		queued:      make(map[string]bool),
+		inflight:    make(map[string]*call),
+		proposing:   make(map[string]chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s, nil
+}
+
+// AlbumSetVersion identifies the albums offered as suggestions and their descriptions. Stored
+// suggestions are only served while the version they were computed against is current.
+func AlbumSetVersion(albums []database.Album) string {
+	sorted := make([]database.Album, 0, len(albums))
+	for _, album := range albums {
+		if album.AIDescription.Valid {
+			sorted = append(sorted, album)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	h := sha256.New()
+	for _, album := range sorted {
+		h.Write([]byte(album.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(album.Title))
+		h.Write([]byte{0})
+		h.Write([]byte(album.AIDescription.String))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// Run starts the background workers; they stop when ctx is cancelled
+func (s *Service) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}()
+
+	for i := 0; i < s.cfg.Workers; i++ {
+		go s.worker(ctx)
+	}
+
+	s.queueInitial()
+}
+
+// CurrentVersion returns the version of the current album set and suggester configuration
+func (s *Service) CurrentVersion() (string, error) {
+	albums, err := s.db.GetTopLevelAlbums()
+	if err != nil {
+		return "", err
+	}
+	return s.version(albums), nil
+}
+
+// version combines the album set version with the ensemble configuration, so that changing
+// suggester weights also invalidates stored suggestions
+func (s *Service) version(albums []database.Album) string {
+	h := sha256.New()
+	h.Write([]byte(AlbumSetVersion(albums)))
+	h.Write([]byte{0})
+	h.Write([]byte(s.ensemble.key()))
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// Get returns suggestions for a photo, computing them now if none are stored for the current albums
+func (s *Service) Get(photoID string) (*Result, error) {
+	albums, err := s.db.GetTopLevelAlbums()
+	if err != nil {
+		return nil, err
+	}
+	version := s.version(albums)
+
+	stored, ok, err := s.db.GetAlbumSuggestions(photoID, version)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Suggestions: stored, Albums: albumMap(albums), Version: version, Cached: true}
+	if !ok {
+		computed, err := s.compute(photoID, albums, version)
+		if err != nil {
+			return nil, err
+		}
+		// The computed result may be shared with concurrent callers
+		copied := *computed
+		result = &copied
+	}
+
+	result.Proposal = s.proposalFor(photoID, result.Suggestions)
+	return result, nil
+}
+
+// PrefetchAfter queues the photos following photoID in the filmstrip, nearest first
+func (s *Service) PrefetchAfter(photoID string) {
+	if s.cfg.PrefetchCount == 0 {
+		return
+	}
+
+	photoIDs, err := s.db.GetUnsortedPhotoIDs()
+	if err != nil {
+		log.Printf("Error getting unsorted photos for prefetch: %v", err)
+		return
+	}
+
+	for i, id := range photoIDs {
+		if id != photoID {
+			continue
+		}
+		end := i + 1 + s.cfg.PrefetchCount
+		if end > len(photoIDs) {
+			end = len(photoIDs)
+		}
+		s.enqueue(photoIDs[i+1:end], true)
+		return
+	}
+}
+
+// Invalidate drops suggestions computed against outdated album descriptions and queues
+// replacements. Call it whenever album descriptions change.
+func (s *Service) Invalidate() {
+	s.resetMetadata()
+
+	version, err := s.CurrentVersion()
+	if err != nil {
+		log.Printf("Error computing album set version: %v", err)
+		return
+	}
+
+	n, err := s.db.DeleteStaleAlbumSuggestions(version)
+	if err != nil {
+		log.Printf("Error deleting stale suggestions: %v", err)
+		return
+	}
+	log.Printf("Album set is now version %s; dropped stale suggestions for %d photos", version, n)
+
+	s.queueInitial()
+}
+
+// Forget drops stored suggestions for a photo that no longer needs them, e.g. because it was
+// moved into an album
+func (s *Service) Forget(photoID string) {
+	s.discard(photoID)
+
+	// The photo has probably joined an album, changing that album's metadata
+	s.resetMetadata()
+}
+
+// Redescribed drops stored suggestions for a photo whose description has changed, since they
+// were computed from the old one. The photo hasn't moved, so album metadata is kept; a job
+// describing many photos calls Invalidate once it is done.
+func (s *Service) Redescribed(photoID string) {
+	s.discard(photoID)
+}
+
+// discard removes a photo from the prefetch queue and deletes its stored suggestions
+func (s *Service) discard(photoID string) {
+	s.mu.Lock()
+	if s.queued[photoID] {
+		delete(s.queued, photoID)
+		for i, id := range s.queue {
+			if id == photoID {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.db.DeleteAlbumSuggestions(photoID); err != nil {
+		log.Printf("Error deleting suggestions for photo %s: %v", photoID, err)
+	}
+}
+
+// Moved records that photos were moved into an album and forgets their suggestions. Moves into
+// a suggested album count as accepted suggestions, which the model is shown as examples.
+func (s *Service) Moved(photoIDs []string, albumID string) {
+	for _, id := range photoIDs {
+		if err := s.db.RecordAcceptedSuggestion(id, albumID); err != nil {
+			log.Printf("Error recording accepted suggestion for photo %s: %v", id, err)
+		}
+		s.Forget(id)
+	}
+}
+
+// queueInitial queues the start of the filmstrip, or every unsorted photo with precompute_all
+func (s *Service) queueInitial() {
+	photoIDs, err := s.db.GetUnsortedPhotoIDs()
+	if err != nil {
+		log.Printf("Error getting unsorted photos for prefetch: %v", err)
+		return
+	}
+
+	if !s.cfg.PrecomputeAll && len(photoIDs) > s.cfg.PrefetchCount {
+		photoIDs = photoIDs[:s.cfg.PrefetchCount]
+	}
+	s.enqueue(photoIDs, false)
+}
+
+// compute generates and stores suggestions for a photo. Concurrent requests for the same
+// photo share a single model call.
+func (s *Service) compute(photoID string, albums []database.Album, version string) (*Result, error) {
+	s.mu.Lock()
+	if c, ok := s.inflight[photoID]; ok {
+		s.mu.Unlock()
+		<-c.done
+		return c.result, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	s.inflight[photoID] = c
+	s.mu.Unlock()
+
+	c.result, c.err = s.generate(photoID, albums, version)
+
+	s.mu.Lock()
+	delete(s.inflight, photoID)
+	s.mu.Unlock()
+	close(c.done)
+
+	return c.result, c.err
+}
+
+func (s *Service) generate(photoID string, albums []database.Album, version string) (*Result, error) {
+	photo, err := s.db.GetPhoto(photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	albumPhotos, err := s.metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	albumsByID := albumMap(albums)
+	in := &Input{Photo: photo, Albums: albums, AlbumPhotos: make(map[string][]database.AlbumPhotoMetadata)}
+	for _, p := range albumPhotos {
+		if _, ok := albumsByID[p.AlbumID]; ok && p.PhotoID != photoID {
+			in.AlbumPhotos[p.AlbumID] = append(in.AlbumPhotos[p.AlbumID], p)
+		}
+	}
+
+	log.Printf("Computing suggestions for photo %s against album set %s", photoID, version)
+	suggestions, complete, err := s.ensemble.suggest(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// Suggestions missing a failed suggester's vote are served, but computed again next time
+	if complete {
+		if err := s.db.SaveAlbumSuggestions(photoID, version, suggestions); err != nil {
+			log.Printf("Error saving suggestions for photo %s: %v", photoID, err)
+		}
+	}
+
+	return &Result{Suggestions: suggestions, Albums: albumsByID, Version: version}, nil
+}
+
+// metadata returns the metadata of every photo in an album, loading it if needed
+func (s *Service) metadata() ([]database.AlbumPhotoMetadata, error) {
+	s.metadataMu.Lock()
+	defer s.metadataMu.Unlock()
+
+	if s.albumPhotos == nil {
+		albumPhotos, err := s.db.GetAlbumPhotoMetadata()
+		if err != nil {
+			return nil, err
+		}
+		if albumPhotos == nil {
+			albumPhotos = []database.AlbumPhotoMetadata{}
+		}
+		s.albumPhotos = albumPhotos
+	}
+	return s.albumPhotos, nil
+}
+
+// resetMetadata makes the next suggestion reload album photo metadata and recluster unsorted photos
+func (s *Service) resetMetadata() {
+	s.metadataMu.Lock()
+	s.albumPhotos = nil
+	s.clusters = nil
+	s.metadataMu.Unlock()
+}
+
+func (s *Service) worker(ctx context.Context) {
+	for {
+		photoID, ok := s.next(ctx)
+		if !ok {
+			return
+		}
+
+		if _, err := s.Get(photoID); err != nil {
+			log.Printf("Error prefetching suggestions for photo %s: %v", photoID, err)
+		}
+	}
+}
+
+// enqueue adds photos to the prefetch queue; with front set they are processed before anything already queued
+func (s *Service) enqueue(photoIDs []string, front bool) {
+	if len(photoIDs) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if front {
+		promoted := make(map[string]bool, len(photoIDs))
+		for _, id := range photoIDs {
+			promoted[id] = true
+		}
+		rest := make([]string, 0, len(s.queue))
+		for _, id := range s.queue {
+			if !promoted[id] {
+				rest = append(rest, id)
+			}
+		}
+		s.queue = append(append([]string{}, photoIDs...), rest...)
+		for _, id := range photoIDs {
+			s.queued[id] = true
+		}
+	} else {
+		for _, id := range photoIDs {
+			if !s.queued[id] {
+				s.queue = append(s.queue, id)
+				s.queued[id] = true
+			}
+		}
+	}
+
+	s.cond.Broadcast()
+}
+
+func (s *Service) next(ctx context.Context) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.queue) == 0 {
+		if ctx.Err() != nil {
+			return "", false
+		}
+		s.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return "", false
+	}
+
+	photoID := s.queue[0]
+	s.queue = s.queue[1:]
+	delete(s.queued, photoID)
+	return photoID, true
+}
+
+func albumMap(albums []database.Album) map[string]database.Album {
+	m := make(map[string]database.Album, len(albums))
+	for _, album := range albums {
+		m[album.ID] = album
+	}
+	return m
+}
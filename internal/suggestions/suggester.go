@@ -0,0 +1,97 @@
+package suggestions
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"lychee-ai-organizer/internal/config"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/geocode"
+	"lychee-ai-organizer/internal/ollama"
+)
+
+// ErrAbstain is returned by a suggester that has no evidence for a photo, e.g. a location
+// scorer asked about a photo without GPS coordinates, or that matches no album. Abstaining
+// suggesters are left out of the ensemble for that photo rather than counting as a vote
+// against every album.
+var ErrAbstain = errors.New("suggester has no evidence for this photo")
+
+// Score is a suggester's confidence, from 0 to 1, that a photo belongs in an album
+type Score struct {
+	AlbumID    string
+	Confidence float64
+	Reason     string
+}
+
+// Input is what suggesters get to look at: the photo, the albums it could go into and the
+// metadata of the photos already in those albums
+type Input struct {
+	Photo       *database.Photo
+	Albums      []database.Album
+	AlbumPhotos map[string][]database.AlbumPhotoMetadata // by album ID
+}
+
+// Suggester scores albums for a photo from one kind of evidence. Albums a suggester finds no
+// match for may be left out of its scores; if it matches none, it returns ErrAbstain.
+type Suggester interface {
+	Name() string
+	Suggest(in *Input) ([]Score, error)
+}
+
+// newSuggester creates the suggester registered under name
+func newSuggester(name string, cfg *config.SuggestionsConfig, ollamaClient *ollama.Client, geocoder *geocode.Geocoder) (Suggester, error) {
+	switch name {
+	case "llm":
+		return &llmSuggester{ollama: ollamaClient}, nil
+	case "neighbours":
+		return neighbourSuggester{window: time.Duration(cfg.NeighbourWindowMinutes) * time.Minute}, nil
+	case "date_range":
+		return dateRangeSuggester{}, nil
+	case "location":
+		return locationSuggester{geocoder: geocoder}, nil
+	case "camera":
+		return cameraSuggester{}, nil
+	default:
+		return nil, fmt.Errorf("unknown suggester %q", name)
+	}
+}
+
+// llmSuggester asks the model to match the photo's description against the album descriptions
+type llmSuggester struct {
+	ollama *ollama.Client
+}
+
+func (s *llmSuggester) Name() string { return "llm" }
+
+func (s *llmSuggester) Suggest(in *Input) ([]Score, error) {
+	if !in.Photo.AIDescription.Valid {
+		return nil, ErrAbstain
+	}
+	described := false
+	for _, album := range in.Albums {
+		if album.AIDescription.Valid {
+			described = true
+			break
+		}
+	}
+	if !described {
+		return nil, ErrAbstain
+	}
+
+	// Don't leave the caller waiting for Ollama to come back
+	if !s.ollama.Available() {
+		return nil, ErrUnavailable
+	}
+
+	suggestions, err := s.ollama.GenerateAlbumSuggestions(in.Photo, in.Albums)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]Score, len(suggestions))
+	for i, suggestion := range suggestions {
+		scores[i] = Score{AlbumID: suggestion.AlbumID, Confidence: suggestion.Confidence, Reason: suggestion.Reason}
+	}
+	return scores, nil
+}
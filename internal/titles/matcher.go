@@ -0,0 +1,48 @@
+package titles
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// extensionPattern matches file extensions, but not the dotted time parts of names like "2024-05-01 14.05.33"
+var extensionPattern = regexp.MustCompile(`^\.[A-Za-z][A-Za-z0-9]{1,4}$`)
+
+// Matcher recognizes photo titles that are just camera-assigned filenames (IMG_4821, DSC01234, ...)
+type Matcher struct {
+	patterns []*regexp.Regexp
+}
+
+func NewMatcher(patterns []string) (*Matcher, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid camera filename pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	return &Matcher{patterns: compiled}, nil
+}
+
+// IsCameraFilename reports whether the title matches one of the configured camera filename patterns.
+// A trailing file extension (e.g. ".JPG") is ignored.
+func (m *Matcher) IsCameraFilename(title string) bool {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return false
+	}
+	if ext := filepath.Ext(title); extensionPattern.MatchString(ext) {
+		title = strings.TrimSuffix(title, ext)
+	}
+
+	for _, re := range m.patterns {
+		if re.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// progressTracker reports progress for a job whose items are processed concurrently. Current
+// is always the number of finished items, and updates are sent in order under a lock so the
+// count never goes backwards on the client.
+type progressTracker struct {
+	h     *Handler
+	conn  *websocket.Conn
+	total int
+
+	mu   sync.Mutex
+	done int
+}
+
+func (h *Handler) newProgressTracker(conn *websocket.Conn, total int) *progressTracker {
+	return &progressTracker{h: h, conn: conn, total: total}
+}
+
+// started reports that work on an item has begun
+func (p *progressTracker) started(stage, itemID, description string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.h.sendMessage(p.conn, "progress", ProgressUpdate{
+		Stage:       stage,
+		Current:     p.done,
+		Total:       p.total,
+		Description: description,
+		ItemID:      itemID,
+	})
+}
+
+// finished reports that an item is done, whether it succeeded or not
+func (p *progressTracker) finished(stage, itemID, description string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	p.h.sendMessage(p.conn, "progress", ProgressUpdate{
+		Stage:       stage,
+		Current:     p.done,
+		Total:       p.total,
+		Description: description,
+		ItemID:      itemID,
+		Finished:    true,
+	})
+}
+
+// forEach calls fn for each index in [0, n) on up to h.concurrency workers and waits for all of them
+func (h *Handler) forEach(n int, fn func(i int)) {
+	workers := h.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// errorList collects error messages from concurrent workers
+type errorList struct {
+	mu    sync.Mutex
+	items []string
+}
+
+func (l *errorList) add(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = append(l.items, msg)
+}
+
+// list returns the collected messages, never nil so it encodes as an empty JSON array
+func (l *errorList) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.items == nil {
+		return []string{}
+	}
+	return append([]string{}, l.items...)
+}
@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/gorilla/websocket"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/ollama"
+)
+
+// provenanceFilter reads the filter of a regenerate_descriptions message
+func provenanceFilter(payload interface{}) (database.ProvenanceFilter, error) {
+	var filter database.ProvenanceFilter
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return filter, err
+	}
+	if err := json.Unmarshal(encoded, &filter); err != nil {
+		return filter, err
+	}
+	if filter.Model != "" {
+		filter.Model = ollama.NormalizeModelName(filter.Model)
+	}
+	return filter, nil
+}
+
+// handleRegenerateDescriptions describes again the photos and albums whose description was made
+// as the filter says, such as everything written by one model
+func (h *Handler) handleRegenerateDescriptions(conn *websocket.Conn, payload interface{}) {
+	filter, err := provenanceFilter(payload)
+	if err != nil {
+		h.sendError(conn, "Invalid provenance filter: "+err.Error())
+		return
+	}
+	if filter.Empty() {
+		h.sendError(conn, "A model, digest, prompt hash or untracked is required")
+		return
+	}
+
+	photoIDs, err := h.db.GetDescribedByProvenance(database.TranslationPhoto, ollama.DescriptionKinds(database.TranslationPhoto), filter)
+	if err != nil {
+		h.sendError(conn, "Failed to get photos: "+err.Error())
+		return
+	}
+	albumIDs, err := h.db.GetDescribedByProvenance(database.TranslationAlbum, ollama.DescriptionKinds(database.TranslationAlbum), filter)
+	if err != nil {
+		h.sendError(conn, "Failed to get albums: "+err.Error())
+		return
+	}
+
+	photos := make([]database.Photo, 0, len(photoIDs))
+	for _, id := range photoIDs {
+		photo, err := h.db.GetPhoto(id)
+		if err != nil {
+			log.Printf("Error loading photo %s to regenerate: %v", id, err)
+			continue
+		}
+		photos = append(photos, *photo)
+	}
+	albums := make([]database.Album, 0, len(albumIDs))
+	for _, id := range albumIDs {
+		album, err := h.db.GetAlbum(id)
+		if err != nil {
+			log.Printf("Error loading album %s to regenerate: %v", id, err)
+			continue
+		}
+		albums = append(albums, *album)
+	}
+
+	if len(photos) == 0 && len(albums) == 0 {
+		h.sendMessage(conn, "complete", map[string]interface{}{
+			"message": "No descriptions match the filter",
+			"errors":  ErrorSummary{PhotoErrors: []string{}, AlbumErrors: []string{}, TotalErrors: 0},
+		})
+		return
+	}
+
+	progress := h.newProgressTracker(conn, len(photos)+len(albums))
+	photoErrors := h.processPhotos(progress, photos, "photos")
+
+	// Albums are described from their photos, so they go second. Only matching albums are
+	// rewritten; their sub-albums are left as they are.
+	var albumErrors errorList
+	h.forEach(len(albums), func(i int) {
+		album := albums[i]
+		progress.started("albums", album.ID, "Describing album: "+album.Title)
+		defer progress.finished("albums", album.ID, "Described album: "+album.Title)
+		h.describeAlbum(progress, &album, &albumErrors)
+	})
+	h.suggestions.Invalidate()
+
+	errorSummary := ErrorSummary{
+		PhotoErrors: photoErrors,
+		AlbumErrors: albumErrors.list(),
+	}
+	errorSummary.TotalErrors = len(errorSummary.PhotoErrors) + len(errorSummary.AlbumErrors)
+
+	h.sendMessage(conn, "complete", map[string]interface{}{
+		"message": fmt.Sprintf("Regenerated %d photo and %d album descriptions",
+			len(photos)-len(errorSummary.PhotoErrors), len(albums)-len(errorSummary.AlbumErrors)),
+		"errors": errorSummary,
+	})
+}
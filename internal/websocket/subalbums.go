@@ -0,0 +1,31 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+)
+
+// describeSubAlbums describes the sub-albums of an album that have no description yet, down to
+// depth levels below it and deepest first, so that each one's description is there when its
+// parent is described
+func (h *Handler) describeSubAlbums(progress *progressTracker, albumID string, depth int, albumErrors *errorList) {
+	if depth <= 0 {
+		return
+	}
+
+	children, err := h.db.GetChildAlbums(albumID)
+	if err != nil {
+		log.Printf("Error getting sub-albums of album %s: %v", albumID, err)
+		albumErrors.add(fmt.Sprintf("Album %s: Failed to get sub-albums: %v", albumID, err))
+		return
+	}
+
+	for i := range children {
+		child := &children[i]
+		if child.AIDescription.Valid {
+			continue
+		}
+		h.describeSubAlbums(progress, child.ID, depth-1, albumErrors)
+		h.describeAlbum(progress, child, albumErrors)
+	}
+}
@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gorilla/websocket"
+	"lychee-ai-organizer/internal/database"
+	"lychee-ai-organizer/internal/language"
+)
+
+// translateDescription translates a photo or album description into the other output languages
+// and stores the translations. It returns what went wrong; the description itself is kept either way.
+func (h *Handler) translateDescription(itemType, itemID, description string) []error {
+	var errs []error
+	for _, lang := range h.ollama.Languages()[1:] {
+		if err := h.translate(itemType, itemID, description, lang); err != nil {
+			log.Printf("Error translating %s description for %s: %v", itemType, itemID, err)
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (h *Handler) translate(itemType, itemID, description, lang string) error {
+	translation, err := h.ollama.TranslateDescription(itemType, itemID, description, lang, nil)
+	if err != nil {
+		return err
+	}
+	if err := h.db.SaveTranslation(itemType, itemID, lang, translation); err != nil {
+		return fmt.Errorf("failed to save %s translation: %w", language.Name(lang), err)
+	}
+	return nil
+}
+
+// handleTranslateDescriptions translates the descriptions that have no up-to-date translation,
+// such as those written before a language was added to the config
+func (h *Handler) handleTranslateDescriptions(conn *websocket.Conn) {
+	type work struct {
+		item database.Untranslated
+		lang string
+	}
+	var items []work
+	for _, lang := range h.ollama.Languages()[1:] {
+		untranslated, err := h.db.GetUntranslated(lang)
+		if err != nil {
+			h.sendError(conn, "Failed to get descriptions: "+err.Error())
+			return
+		}
+		for _, item := range untranslated {
+			items = append(items, work{item, lang})
+		}
+	}
+
+	if len(items) == 0 {
+		h.sendMessage(conn, "complete", map[string]interface{}{
+			"message": "No descriptions need translating",
+			"errors":  ErrorSummary{PhotoErrors: []string{}, AlbumErrors: []string{}, TotalErrors: 0},
+		})
+		return
+	}
+
+	var photoErrors, albumErrors errorList
+	progress := h.newProgressTracker(conn, len(items))
+	h.forEach(len(items), func(i int) {
+		item, lang := items[i].item, items[i].lang
+		what := fmt.Sprintf("%s %s into %s", item.ItemType, item.Title, language.Name(lang))
+		progress.started("translations", item.ItemID, "Translating "+what)
+		defer progress.finished("translations", item.ItemID, "Translated "+what)
+
+		if err := h.translate(item.ItemType, item.ItemID, item.Description, lang); err != nil {
+			log.Printf("Error translating %s description for %s: %v", item.ItemType, item.ItemID, err)
+			if item.ItemType == database.TranslationAlbum {
+				albumErrors.add(fmt.Sprintf("Album %s (%s): %v", item.ItemID, item.Title, err))
+			} else {
+				photoErrors.add(fmt.Sprintf("Photo %s (%s): %v", item.ItemID, item.Title, err))
+			}
+		}
+	})
+
+	errorSummary := ErrorSummary{
+		PhotoErrors: photoErrors.list(),
+		AlbumErrors: albumErrors.list(),
+	}
+	errorSummary.TotalErrors = len(errorSummary.PhotoErrors) + len(errorSummary.AlbumErrors)
+
+	h.sendMessage(conn, "complete", map[string]interface{}{
+		"message": fmt.Sprintf("Translated %d descriptions", len(items)-errorSummary.TotalErrors),
+		"errors":  errorSummary,
+	})
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/websocket"
 	"lychee-ai-organizer/internal/database"
 	"lychee-ai-organizer/internal/ollama"
+	"lychee-ai-organizer/internal/suggestions"
+	"lychee-ai-organizer/internal/titles"
 )
 
 var upgrader = websocket.Upgrader{
@@ -23,9 +26,18 @@ type Message struct {
 
 type ProgressUpdate struct {
 	Stage       string `json:"stage"`
-	Current     int    `json:"current"`
+	Current     int    `json:"current"` // number of finished items
 	Total       int    `json:"total"`
 	Description string `json:"description"`
+	ItemID      string `json:"item_id,omitempty"`  // the item that was started or finished
+	Finished    bool   `json:"finished,omitempty"` // true once ItemID is done, successfully or not
+}
+
+// PartialDescription carries the text generated so far for an item being described
+type PartialDescription struct {
+	ItemID   string `json:"item_id"`
+	ItemType string `json:"item_type"` // photo, album or title
+	Text     string `json:"text"`
 }
 
 type ErrorSummary struct {
@@ -35,14 +47,26 @@ type ErrorSummary struct {
 }
 
 type Handler struct {
-	db     *database.DB
-	ollama *ollama.Client
+	db           *database.DB
+	ollama       *ollama.Client
+	titleMatcher *titles.Matcher
+	suggestions  *suggestions.Service
+	concurrency  int
+
+	// writeLocks serializes writes per connection; jobs run in their own goroutines
+	// and gorilla/websocket allows only one concurrent writer
+	mu         sync.Mutex
+	writeLocks map[*websocket.Conn]*sync.Mutex
 }
 
-func NewHandler(db *database.DB, ollamaClient *ollama.Client) *Handler {
+func NewHandler(db *database.DB, ollamaClient *ollama.Client, titleMatcher *titles.Matcher, suggestionService *suggestions.Service, concurrency int) *Handler {
 	return &Handler{
-		db:     db,
-		ollama: ollamaClient,
+		db:           db,
+		ollama:       ollamaClient,
+		titleMatcher: titleMatcher,
+		suggestions:  suggestionService,
+		concurrency:  concurrency,
+		writeLocks:   make(map[*websocket.Conn]*sync.Mutex),
 	}
 }
 
@@ -54,6 +78,15 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
+	h.mu.Lock()
+	h.writeLocks[conn] = &sync.Mutex{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.writeLocks, conn)
+		h.mu.Unlock()
+	}()
+
 	for {
 		var msg Message
 		if err := conn.ReadJSON(&msg); err != nil {
@@ -70,6 +103,12 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			go h.handleDescribeAllAlbums(conn)
 		case "retry_album_failures":
 			go h.handleRetryAlbumFailures(conn)
+		case "suggest_titles":
+			go h.handleSuggestTitles(conn)
+		case "translate_descriptions":
+			go h.handleTranslateDescriptions(conn)
+		case "regenerate_descriptions":
+			go h.handleRegenerateDescriptions(conn, msg.Payload)
 		}
 	}
 }
@@ -95,151 +134,186 @@ func (h *Handler) handleRescan(conn *websocket.Conn) {
 		return
 	}
 
-	current := 0
-
-	// Process photos
-	for _, photo := range photos {
-		current++
-		h.sendProgress(conn, "photos", current, totalWork, "Processing photo: "+photo.Title)
-
-		description, err := h.ollama.GeneratePhotoDescription(&photo)
-		if err != nil {
-			log.Printf("Error generating photo description for %s: %v", photo.ID, err)
-			continue
-		}
-
-		if err := h.db.UpdatePhotoAIDescription(photo.ID, description); err != nil {
-			log.Printf("Error saving photo description for %s: %v", photo.ID, err)
-			continue
-		}
-	}
+	progress := h.newProgressTracker(conn, totalWork)
 
-	// Process albums (regenerate all album descriptions)
-	for _, album := range albums {
-		current++
-		h.sendProgress(conn, "albums", current, totalWork, "Regenerating album description: "+album.ID)
-
-		albumPhotos, err := h.db.GetPhotosInAlbum(album.ID)
-		if err != nil {
-			log.Printf("Error getting photos for album %s: %v", album.ID, err)
-			continue
-		}
-
-		if len(albumPhotos) == 0 {
-			continue
-		}
-
-		description, err := h.ollama.GenerateAlbumDescription(&album, albumPhotos)
-		if err != nil {
-			log.Printf("Error generating album description for %s: %v", album.ID, err)
-			continue
-		}
-
-		if err := h.db.UpdateAlbumAIDescription(album.ID, description); err != nil {
-			log.Printf("Error saving album description for %s: %v", album.ID, err)
-			continue
-		}
-	}
+	// Process photos, then regenerate all album descriptions; failures are only logged
+	h.processPhotos(progress, photos, "photos")
+	h.processAlbums(progress, albums, "albums")
 
 	h.sendMessage(conn, "complete", map[string]string{"message": "Rescan complete"})
 }
 
-func (h *Handler) sendProgress(conn *websocket.Conn, stage string, current, total int, description string) {
-	update := ProgressUpdate{
-		Stage:       stage,
-		Current:     current,
-		Total:       total,
-		Description: description,
-	}
-	h.sendMessage(conn, "progress", update)
-}
-
 func (h *Handler) sendMessage(conn *websocket.Conn, msgType string, payload interface{}) {
 	msg := Message{
 		Type:    msgType,
 		Payload: payload,
 	}
 
+	h.mu.Lock()
+	writeLock := h.writeLocks[conn]
+	h.mu.Unlock()
+	if writeLock == nil {
+		// Connection already closed; the job keeps running but nobody is listening
+		return
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
 	if err := conn.WriteJSON(msg); err != nil {
 		log.Printf("WebSocket write error: %v", err)
 	}
 }
 
+// Broadcast sends a message to every connected client
+func (h *Handler) Broadcast(msgType string, payload interface{}) {
+	h.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(h.writeLocks))
+	for conn := range h.writeLocks {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	for _, conn := range conns {
+		h.sendMessage(conn, msgType, payload)
+	}
+}
+
+// BroadcastPullProgress relays model download progress to every connected client
+func (h *Handler) BroadcastPullProgress(progress ollama.PullProgress) {
+	h.Broadcast("model_pull", progress)
+}
+
+// BroadcastOllamaStatus tells every connected client when jobs pause because Ollama is
+// unreachable, and when they resume
+func (h *Handler) BroadcastOllamaStatus(status ollama.Status) {
+	h.Broadcast("ollama_status", status)
+}
+
 func (h *Handler) sendError(conn *websocket.Conn, errorMsg string) {
 	h.sendMessage(conn, "error", map[string]string{"error": errorMsg})
 }
 
-// processPhotos is a helper function to reduce code duplication in photo processing
-func (h *Handler) processPhotos(conn *websocket.Conn, photos []database.Photo, stage string) []string {
-	var photoErrors []string
-	total := len(photos)
+// partialSender relays streamed text for an item to the client as partial_description messages
+func (h *Handler) partialSender(conn *websocket.Conn, itemID, itemType string) ollama.PartialFunc {
+	return func(text string) {
+		h.sendMessage(conn, "partial_description", PartialDescription{
+			ItemID:   itemID,
+			ItemType: itemType,
+			Text:     text,
+		})
+	}
+}
+
+// processPhotos describes photos on the job's worker pool and returns the errors
+func (h *Handler) processPhotos(progress *progressTracker, photos []database.Photo, stage string) []string {
+	var photoErrors errorList
 
-	for i, photo := range photos {
-		h.sendProgress(conn, stage, i+1, total, "Processing photo: "+photo.Title)
+	h.forEach(len(photos), func(i int) {
+		photo := photos[i]
+		progress.started(stage, photo.ID, "Processing photo: "+photo.Title)
+		defer progress.finished(stage, photo.ID, "Processed photo: "+photo.Title)
 
-		description, err := h.ollama.GeneratePhotoDescription(&photo)
+		description, err := h.ollama.GeneratePhotoDescription(&photo, h.partialSender(progress.conn, photo.ID, "photo"))
 		if err != nil {
 			errorMsg := fmt.Sprintf("Photo %s (%s): %v", photo.ID, photo.Title, err)
 			log.Printf("Error generating photo description for %s: %v", photo.ID, err)
-			photoErrors = append(photoErrors, errorMsg)
-			continue
+			photoErrors.add(errorMsg)
+			return
 		}
 
 		if err := h.db.UpdatePhotoAIDescription(photo.ID, description); err != nil {
 			errorMsg := fmt.Sprintf("Photo %s (%s): Failed to save description: %v", photo.ID, photo.Title, err)
 			log.Printf("Error saving photo description for %s: %v", photo.ID, err)
-			photoErrors = append(photoErrors, errorMsg)
-			continue
+			photoErrors.add(errorMsg)
+			return
 		}
-	}
+		// Stored suggestions were computed from the old description
+		h.suggestions.Redescribed(photo.ID)
+
+		for _, err := range h.translateDescription(database.TranslationPhoto, photo.ID, description) {
+			photoErrors.add(fmt.Sprintf("Photo %s (%s): %v", photo.ID, photo.Title, err))
+		}
+	})
+
+	// Newly described photos can now get suggestions
+	h.suggestions.Invalidate()
 
-	return photoErrors
+	return photoErrors.list()
 }
 
-// processAlbums is a helper function to reduce code duplication in album processing
-func (h *Handler) processAlbums(conn *websocket.Conn, albums []database.Album, stage string, startIndex int, total int) []string {
-	var albumErrors []string
+// processAlbums describes albums on the job's worker pool and returns the errors
+func (h *Handler) processAlbums(progress *progressTracker, albums []database.Album, stage string) []string {
+	var albumErrors errorList
 
 	log.Printf("Starting processAlbums with %d albums", len(albums))
-	for i, album := range albums {
-		currentIndex := startIndex + i + 1
-		h.sendProgress(conn, stage, currentIndex, total, "Describing album: "+album.Title)
+	h.forEach(len(albums), func(i int) {
+		album := albums[i]
+		progress.started(stage, album.ID, "Describing album: "+album.Title)
+		defer progress.finished(stage, album.ID, "Described album: "+album.Title)
+
+		// Where sub-album descriptions stand in for their photos, they are written first
+		h.describeSubAlbums(progress, album.ID, h.ollama.SubAlbumDescriptionDepth(), &albumErrors)
+		h.describeAlbum(progress, &album, &albumErrors)
+	})
 
-		albumPhotos, err := h.db.GetPhotosInAlbum(album.ID)
-		if err != nil {
-			errorMsg := fmt.Sprintf("Album %s (%s): Failed to get photos: %v", album.ID, album.Title, err)
-			log.Printf("Error getting photos for album %s: %v", album.ID, err)
-			albumErrors = append(albumErrors, errorMsg)
-			continue
-		}
+	failures := albumErrors.list()
+	log.Printf("Completed processAlbums: %d errors out of %d albums", len(failures), len(albums))
 
-		if len(albumPhotos) == 0 {
-			errorMsg := fmt.Sprintf("Album %s (%s): No photos found", album.ID, album.Title)
-			log.Printf("No photos found for album %s (%s)", album.ID, album.Title)
-			albumErrors = append(albumErrors, errorMsg)
-			continue
-		}
+	// Suggestions computed against the old album descriptions are stale now
+	h.suggestions.Invalidate()
 
-		description, err := h.ollama.GenerateAlbumDescription(&album, albumPhotos)
-		if err != nil {
-			errorMsg := fmt.Sprintf("Album %s (%s): %v", album.ID, album.Title, err)
-			log.Printf("Error generating album description for %s: %v", album.ID, err)
-			albumErrors = append(albumErrors, errorMsg)
-			continue
-		}
+	return failures
+}
 
-		if err := h.db.UpdateAlbumAIDescription(album.ID, description); err != nil {
-			errorMsg := fmt.Sprintf("Album %s (%s): Failed to save description: %v", album.ID, album.Title, err)
-			log.Printf("Error saving album description for %s: %v", album.ID, err)
-			albumErrors = append(albumErrors, errorMsg)
-			continue
-		}
-		
-		log.Printf("Successfully processed album %s (%s)", album.ID, album.Title)
+// describeAlbum writes, saves and translates the description of an album, and its facets
+func (h *Handler) describeAlbum(progress *progressTracker, album *database.Album, albumErrors *errorList) {
+	// Photos in sub-albums count too, so albums that only hold sub-albums get described
+	content, err := h.ollama.AlbumContent(album.ID)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Album %s (%s): Failed to get photos: %v", album.ID, album.Title, err)
+		log.Printf("Error getting photos for album %s: %v", album.ID, err)
+		albumErrors.add(errorMsg)
+		return
+	}
+
+	albumPhotos := content.Photos
+	if len(albumPhotos) == 0 {
+		errorMsg := fmt.Sprintf("Album %s (%s): No photos found", album.ID, album.Title)
+		log.Printf("No photos found for album %s (%s)", album.ID, album.Title)
+		albumErrors.add(errorMsg)
+		return
+	}
+
+	description, err := h.ollama.GenerateAlbumDescription(album, content, h.partialSender(progress.conn, album.ID, "album"))
+	if err != nil {
+		errorMsg := fmt.Sprintf("Album %s (%s): %v", album.ID, album.Title, err)
+		log.Printf("Error generating album description for %s: %v", album.ID, err)
+		albumErrors.add(errorMsg)
+		return
+	}
+
+	if err := h.db.UpdateAlbumAIDescription(album.ID, description); err != nil {
+		errorMsg := fmt.Sprintf("Album %s (%s): Failed to save description: %v", album.ID, album.Title, err)
+		log.Printf("Error saving album description for %s: %v", album.ID, err)
+		albumErrors.add(errorMsg)
+		return
 	}
 
-	log.Printf("Completed processAlbums: %d errors out of %d albums", len(albumErrors), len(albums))
-	return albumErrors
+	for _, err := range h.translateDescription(database.TranslationAlbum, album.ID, description) {
+		albumErrors.add(fmt.Sprintf("Album %s (%s): %v", album.ID, album.Title, err))
+	}
+
+	// The compact form for suggestion prompts
+	facets, err := h.ollama.GenerateAlbumFacets(album, albumPhotos, description)
+	if err == nil {
+		err = h.db.SaveAlbumFacets(facets)
+	}
+	if err != nil {
+		log.Printf("Error saving album facets for %s: %v", album.ID, err)
+		albumErrors.add(fmt.Sprintf("Album %s (%s): Failed to save facets: %v", album.ID, album.Title, err))
+	}
+
+	log.Printf("Successfully processed album %s (%s)", album.ID, album.Title)
 }
 
 func (h *Handler) handleDescribePhotos(conn *websocket.Conn) {
@@ -258,7 +332,7 @@ func (h *Handler) handleDescribePhotos(conn *websocket.Conn) {
 		return
 	}
 
-	photoErrors := h.processPhotos(conn, photos, "photos")
+	photoErrors := h.processPhotos(h.newProgressTracker(conn, len(photos)), photos, "photos")
 
 	errorSummary := ErrorSummary{
 		PhotoErrors: photoErrors,
@@ -288,7 +362,7 @@ func (h *Handler) handleDescribeAllAlbums(conn *websocket.Conn) {
 		return
 	}
 
-	albumErrors := h.processAlbums(conn, albums, "albums", 0, len(albums))
+	albumErrors := h.processAlbums(h.newProgressTracker(conn, len(albums)), albums, "albums")
 
 	errorSummary := ErrorSummary{
 		PhotoErrors: []string{},
@@ -318,7 +392,7 @@ func (h *Handler) handleRetryAlbumFailures(conn *websocket.Conn) {
 		return
 	}
 
-	albumErrors := h.processAlbums(conn, albums, "albums", 0, len(albums))
+	albumErrors := h.processAlbums(h.newProgressTracker(conn, len(albums)), albums, "albums")
 
 	errorSummary := ErrorSummary{
 		PhotoErrors: []string{},
@@ -331,3 +405,62 @@ func (h *Handler) handleRetryAlbumFailures(conn *websocket.Conn) {
 		"errors":  errorSummary,
 	})
 }
+
+func (h *Handler) handleSuggestTitles(conn *websocket.Conn) {
+	candidates, err := h.db.GetPhotosWithoutTitleSuggestion()
+	if err != nil {
+		h.sendError(conn, "Failed to get photos: "+err.Error())
+		return
+	}
+
+	// Only photos still carrying a camera filename get a title suggestion
+	var photos []database.Photo
+	for _, photo := range candidates {
+		if h.titleMatcher.IsCameraFilename(photo.Title) {
+			photos = append(photos, photo)
+		}
+	}
+
+	if len(photos) == 0 {
+		h.sendMessage(conn, "complete", map[string]interface{}{
+			"message": "No photos need title suggestions",
+			"errors":  ErrorSummary{PhotoErrors: []string{}, AlbumErrors: []string{}, TotalErrors: 0},
+		})
+		return
+	}
+
+	var errs errorList
+	progress := h.newProgressTracker(conn, len(photos))
+	h.forEach(len(photos), func(i int) {
+		photo := photos[i]
+		progress.started("titles", photo.ID, "Suggesting title for: "+photo.Title)
+		defer progress.finished("titles", photo.ID, "Suggested title for: "+photo.Title)
+
+		title, err := h.ollama.GeneratePhotoTitle(&photo, h.partialSender(conn, photo.ID, "title"))
+		if err != nil {
+			errorMsg := fmt.Sprintf("Photo %s (%s): %v", photo.ID, photo.Title, err)
+			log.Printf("Error generating title for %s: %v", photo.ID, err)
+			errs.add(errorMsg)
+			return
+		}
+
+		if err := h.db.SaveTitleSuggestion(photo.ID, photo.Title, title); err != nil {
+			errorMsg := fmt.Sprintf("Photo %s (%s): Failed to save title suggestion: %v", photo.ID, photo.Title, err)
+			log.Printf("Error saving title suggestion for %s: %v", photo.ID, err)
+			errs.add(errorMsg)
+			return
+		}
+	})
+	photoErrors := errs.list()
+
+	errorSummary := ErrorSummary{
+		PhotoErrors: photoErrors,
+		AlbumErrors: []string{},
+		TotalErrors: len(photoErrors),
+	}
+
+	h.sendMessage(conn, "complete", map[string]interface{}{
+		"message": fmt.Sprintf("Suggested titles for %d photos; review them before they are applied", len(photos)-len(photoErrors)),
+		"errors":  errorSummary,
+	})
+}
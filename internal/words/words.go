@@ -0,0 +1,45 @@
+package words
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLength leaves short words out, which are mostly articles and prepositions
+const minLength = 4
+
+// Set is the longer words of a text, lower case
+type Set map[string]bool
+
+// Of returns the set of words in text
+func Of(text string) Set {
+	s := make(Set)
+	s.Add(text)
+	return s
+}
+
+// Add adds the words of text: runs of letters and digits at least minLength long
+func (s Set) Add(text string) {
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= minLength {
+			s[word] = true
+		}
+	}
+}
+
+// Similarity is the Jaccard index of two word sets: 1 for the same words, 0 for none in common.
+// A text without words is like no other, so it is 0 if either set is empty.
+func Similarity(a, b Set) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,43 @@
+package words
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	got := Of("A red Tram, on the 28 line, climbs Alfama's hills in 1920s style.")
+	want := Set{"tram": true, "line": true, "climbs": true, "alfama": true, "hills": true, "1920s": true, "style": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Of() = %v, want %v", got, want)
+	}
+
+	set := Of("Harbour boats")
+	set.Add("boats and gulls")
+	if want := (Set{"harbour": true, "boats": true, "gulls": true}); !reflect.DeepEqual(set, want) {
+		t.Errorf("Add() = %v, want %v", set, want)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"harbour boats", "harbour boats", 1},
+		{"harbour boats", "mountain snow", 0},
+		{"harbour boats quay", "harbour boats", 2.0 / 3},
+		{"harbour boats gulls", "harbour quay moss", 1.0 / 5},
+		{"", "harbour", 0},
+		{"", "", 0},
+		{"a the in", "a the in", 0},
+	}
+	for _, tt := range tests {
+		if got := Similarity(Of(tt.a), Of(tt.b)); got != tt.want {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Similarity(Of(tt.b), Of(tt.a)); got != tt.want {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
@@ -26,6 +26,15 @@ func main() {
 	}
 
 	app := NewApp(*configPath)
+
+	// "check" verifies the Ollama setup without starting the server
+	if flag.Arg(0) == "check" {
+		if err := app.Check(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := app.Run(); err != nil {
 		log.Fatal(err)
 	}